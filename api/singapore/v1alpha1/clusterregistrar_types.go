@@ -3,6 +3,7 @@
 package v1alpha1
 
 import (
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
@@ -16,13 +17,178 @@ type ClusterRegistrarSpec struct {
 	// Important: Run "make generate" to regenerate code after modifying this file
 
 	ComputeService ComputeService `json:"computeService"`
+
+	// DefaultDeletionPolicy is the deletion policy of the RegisteredClusters which don't set one.
+	// The adopted ManagedClusters are always detached unless their RegisteredCluster sets Delete.
+	// +optional
+	DefaultDeletionPolicy DeletionPolicy `json:"defaultDeletionPolicy,omitempty"`
+
+	// DeletionMode defines how the ClusterRegistrar is deleted while RegisteredClusters exist.
+	// If empty, the deletion is blocked until all the RegisteredClusters are deleted.
+	// +optional
+	DeletionMode ClusterRegistrarDeletionMode `json:"deletionMode,omitempty"`
+
+	// Manager configures the compute-operator-manager deployment.
+	// +optional
+	Manager ManagerSpec `json:"manager,omitempty"`
+
+	// Webhook configures the compute-operator-webhook-service deployment and its certificates.
+	// +optional
+	Webhook WebhookSpec `json:"webhook,omitempty"`
+
+	// Hubs are the managed hub clusters. The installer creates their HubConfig in its namespace
+	// and installs the prerequisites of the manager on each hub.
+	// The HubConfigs created manually are kept when the list is empty.
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	Hubs []HubSpec `json:"hubs,omitempty"`
+}
+
+// HubSpec declares a managed hub cluster
+type HubSpec struct {
+	// Name of the HubConfig
+	// +kubebuilder:validation:MinLength=1
+	// +required
+	Name string `json:"name"`
+
+	// KubeconfigSecretRef is the secret in the installer namespace with the hub kubeconfig in key kubeconfig.
+	// +required
+	KubeconfigSecretRef corev1.LocalObjectReference `json:"kubeconfigSecretRef"`
+
+	// QPS indicates the maximum QPS to the hub, 100.0 by default.
+	// +kubebuilder:validation:Pattern=`^[0-9]+(\.[0-9]+)?$`
+	// +optional
+	QPS string `json:"qps,omitempty"`
+
+	// Burst is the maximum burst for throttle, 200 by default.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	Burst int `json:"burst,omitempty"`
+
+	// MaxManagedCluster is the capacity of the hub,
+	// <= zero means it will not accept managedCluster.
+	MaxManagedCluster int `json:"maxManagedCluster"`
 }
 
+// ManagerSpec configures the manager
+type ManagerSpec struct {
+	OperandSpec `json:",inline"`
+
+	// Shards is the number of shards the workspaces are spread in, 8 by default.
+	// With more than one shard, the replicas share the shards instead of electing a leader.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	Shards *int32 `json:"shards,omitempty"`
+}
+
+// WebhookSpec configures the webhook
+type WebhookSpec struct {
+	OperandSpec `json:",inline"`
+
+	// Enabled deploys the webhook, true by default. The webhook objects are deleted when it is disabled.
+	// +optional
+	Enabled *bool `json:"enabled,omitempty"`
+
+	// FailurePolicy of the ValidatingWebhookConfiguration, Fail by default.
+	// +kubebuilder:validation:Enum=Fail;Ignore
+	// +optional
+	FailurePolicy *admissionregistrationv1.FailurePolicyType `json:"failurePolicy,omitempty"`
+
+	// TimeoutSeconds of the webhook calls, 10 by default.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=30
+	// +optional
+	TimeoutSeconds *int32 `json:"timeoutSeconds,omitempty"`
+
+	// CertificateManagement defines who provides the serving certificate of the webhook and the CA bundle of its APIService.
+	// If empty, they are provided by the OpenShift service-ca operator or cert-manager.
+	// +optional
+	CertificateManagement CertificateManagement `json:"certificateManagement,omitempty"`
+}
+
+// CertificateManagement defines who provides the serving certificate of the webhook
+// +kubebuilder:validation:Enum=External;Installer
+type CertificateManagement string
+
+const (
+	// CertificateManagementExternal relies on the OpenShift service-ca operator or cert-manager.
+	CertificateManagementExternal CertificateManagement = "External"
+	// CertificateManagementInstaller generates a CA and the serving certificate, injects the CA bundle
+	// and rotates them before their expiration.
+	CertificateManagementInstaller CertificateManagement = "Installer"
+)
+
+// OperandSpec configures a deployment installed by the installer, the default of a field is used when it is not set.
+type OperandSpec struct {
+	// Replicas is the number of pods, 2 by default.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	Replicas *int32 `json:"replicas,omitempty"`
+
+	// Resources are the compute resources of the container.
+	// By default the manager requests 50m CPU and 50Mi memory within limits of 100m CPU and 256Mi memory,
+	// the webhook sets none.
+	// +optional
+	Resources *corev1.ResourceRequirements `json:"resources,omitempty"`
+
+	// LogVerbosity is the log verbosity of the container, 6 for the manager and 0 for the webhook by default.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=10
+	// +optional
+	LogVerbosity *int32 `json:"logVerbosity,omitempty"`
+
+	// NodeSelector of the pods.
+	// +optional
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+
+	// Tolerations of the pods, they replace the default tolerations of the infra and dedicated nodes taints.
+	// +optional
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+
+	// Affinity of the pods, it replaces the default anti-affinity spreading the pods across zones and nodes.
+	// +optional
+	Affinity *corev1.Affinity `json:"affinity,omitempty"`
+
+	// Env are the environment variables added to the container.
+	// +optional
+	Env []corev1.EnvVar `json:"env,omitempty"`
+}
+
+// ClusterRegistrarDeletionMode defines how the ClusterRegistrar is deleted while RegisteredClusters exist
+// +kubebuilder:validation:Enum=Protect;Cascade
+type ClusterRegistrarDeletionMode string
+
+const (
+	// ClusterRegistrarDeletionModeProtect blocks the deletion while RegisteredClusters exist.
+	ClusterRegistrarDeletionModeProtect ClusterRegistrarDeletionMode = "Protect"
+	// ClusterRegistrarDeletionModeCascade deletes the RegisteredClusters and waits for the manager
+	// to process their finalizers before uninstalling the operator.
+	ClusterRegistrarDeletionModeCascade ClusterRegistrarDeletionMode = "Cascade"
+)
+
 // ComputeService contains information about the compute service
 type ComputeService struct {
 	// The secret to access the compute service kubeconfig
 	// +required
 	ComputeKubeconfigSecretRef corev1.LocalObjectReference `json:"computeKubeconfigSecretRef"`
+
+	// Bootstrap applies the RegisteredCluster APIResourceSchema, the compute-apis APIExport and the service account
+	// of the compute operator in the workspace of the kubeconfig, and binds the APIExport in the listed workspaces.
+	// If not set, they are applied manually.
+	// +optional
+	Bootstrap *ComputeBootstrap `json:"bootstrap,omitempty"`
+}
+
+// ComputeBootstrap configures the objects applied in the compute service
+type ComputeBootstrap struct {
+	// ServiceAccountNamespace is the namespace of the compute-operator service account, default by default.
+	// +optional
+	ServiceAccountNamespace string `json:"serviceAccountNamespace,omitempty"`
+
+	// Workspaces are the workspaces where the compute-operator APIBinding of the compute-apis APIExport is applied.
+	// +optional
+	Workspaces []string `json:"workspaces,omitempty"`
 }
 
 // ClusterRegistrarStatus defines the observed state of ClusterRegistrar
@@ -33,13 +199,109 @@ type ClusterRegistrarStatus struct {
 	// Conditions contains the different condition statuses for this ClusterRegistrar.
 	// +optional
 	Conditions []metav1.Condition `json:"conditions"`
+
+	// InstalledVersion is the version of the operator whose CRDs and deployments are rolled out.
+	// +optional
+	InstalledVersion string `json:"installedVersion,omitempty"`
+
+	// RegisteredClusterCount is the number of RegisteredClusters in the compute service,
+	// the webhook denies the ClusterRegistrar deletion while it is not 0 unless the deletion mode is Cascade.
+	// +optional
+	RegisteredClusterCount int32 `json:"registeredClusterCount,omitempty"`
+
+	// InstalledObjects are the objects applied by the installer, the uninstall deletes them.
+	// +optional
+	InstalledObjects []InstalledObject `json:"installedObjects,omitempty"`
+
+	// Leftovers are the installed objects which still exist after their deletion during the uninstall,
+	// the finalizer is removed once there are none.
+	// +optional
+	Leftovers []InstalledObject `json:"leftovers,omitempty"`
 }
 
+// InstalledObject references an object installed by the installer
+type InstalledObject struct {
+	// APIVersion of the object
+	APIVersion string `json:"apiVersion"`
+	// Kind of the object
+	Kind string `json:"kind"`
+	// Namespace of the object, empty for a cluster scoped object
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+	// Name of the object
+	Name string `json:"name"`
+	// Hub is the name of the hub where the object is installed, empty for the objects of the installer cluster
+	// +optional
+	Hub string `json:"hub,omitempty"`
+	// Workspace is the compute service workspace where the object is installed,
+	// empty for the objects of the installer cluster
+	// +optional
+	Workspace string `json:"workspace,omitempty"`
+}
+
+// Conditions of the ClusterRegistrar, set by the installer.
+const (
+	// ClusterRegistrarConditionCRDsInstalled is true when the CRDs of the operator are established.
+	ClusterRegistrarConditionCRDsInstalled string = "CRDsInstalled"
+	// ClusterRegistrarConditionManagerAvailable is true when the manager deployment is available.
+	ClusterRegistrarConditionManagerAvailable string = "ManagerAvailable"
+	// ClusterRegistrarConditionWebhookAvailable is true when the webhook deployment is available.
+	ClusterRegistrarConditionWebhookAvailable string = "WebhookAvailable"
+	// ClusterRegistrarConditionAPIServiceAvailable is true when the APIService of the webhook is available.
+	ClusterRegistrarConditionAPIServiceAvailable string = "APIServiceAvailable"
+	// ClusterRegistrarConditionWebhookRegistered is true when the ValidatingWebhookConfiguration is registered.
+	ClusterRegistrarConditionWebhookRegistered string = "WebhookRegistered"
+	// ClusterRegistrarConditionComputeServiceReachable is true when the compute service is reachable
+	// with the kubeconfig of spec.computeService.
+	ClusterRegistrarConditionComputeServiceReachable string = "ComputeServiceReachable"
+	// ClusterRegistrarConditionComputeServiceBootstrapped is true when the objects of spec.computeService.bootstrap
+	// are applied in the compute service.
+	ClusterRegistrarConditionComputeServiceBootstrapped string = "ComputeServiceBootstrapped"
+	// ClusterRegistrarConditionHubsReachable is true when the hubs of spec.hubs are reachable
+	// and their prerequisites are installed.
+	ClusterRegistrarConditionHubsReachable string = "HubsReachable"
+	// ClusterRegistrarConditionUpgraded is true when the version of the installer is rolled out,
+	// false while upgrading or when the installer is older than the installed version.
+	ClusterRegistrarConditionUpgraded string = "Upgraded"
+	// ClusterRegistrarConditionUninstalled is false while the installed objects are deleted.
+	ClusterRegistrarConditionUninstalled string = "Uninstalled"
+	// ClusterRegistrarConditionReady is true when all the other conditions are true.
+	ClusterRegistrarConditionReady string = "Ready"
+)
+
+// Reasons of the ClusterRegistrar conditions
+const (
+	ReasonCRDsEstablished            string = "CRDsEstablished"
+	ReasonCRDNotEstablished          string = "CRDNotEstablished"
+	ReasonDeploymentAvailable        string = "DeploymentAvailable"
+	ReasonDeploymentNotAvailable     string = "DeploymentNotAvailable"
+	ReasonDeploymentNotFound         string = "DeploymentNotFound"
+	ReasonAPIServiceAvailable        string = "APIServiceAvailable"
+	ReasonAPIServiceNotAvailable     string = "APIServiceNotAvailable"
+	ReasonWebhookRegistered          string = "WebhookRegistered"
+	ReasonWebhookNotRegistered       string = "WebhookNotRegistered"
+	ReasonComputeServiceReachable    string = "ComputeServiceReachable"
+	ReasonComputeServiceUnreachable  string = "ComputeServiceUnreachable"
+	ReasonComputeServiceBootstrapped string = "ComputeServiceBootstrapped"
+	ReasonComputeBootstrapFailed     string = "ComputeBootstrapFailed"
+	ReasonHubsReachable              string = "HubsReachable"
+	ReasonHubUnreachable             string = "HubUnreachable"
+	ReasonUpgradeCompleted           string = "UpgradeCompleted"
+	ReasonUpgradeInProgress          string = "UpgradeInProgress"
+	ReasonDowngradeBlocked           string = "DowngradeBlocked"
+	ReasonUninstallInProgress        string = "UninstallInProgress"
+	ReasonRegisteredClustersExist    string = "RegisteredClustersExist"
+	ReasonDrainingRegisteredClusters string = "DrainingRegisteredClusters"
+)
+
 // +genclient
 // +genclient:nonNamespaced
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
 //+kubebuilder:resource:scope=Cluster
+// +kubebuilder:printcolumn:JSONPath=`.status.installedVersion`,name="Version",type=string
+// +kubebuilder:printcolumn:JSONPath=`.status.conditions[?(@.type=="Ready")].status`,name="Ready",type=string
+// +kubebuilder:printcolumn:JSONPath=`.metadata.creationTimestamp`,name="Age",type=date
 
 // ClusterRegistrar is the Schema for the clusterregistrars API. ClusterRegistrar is a cluster scoped resource.
 type ClusterRegistrar struct {
@@ -16,6 +16,99 @@ type RegisteredClusterSpec struct {
 	// kcp workspaces where SyncTarget will be created
 	// +kubebuilder:validation:Required
 	Location []string `json:"location,omitempty"`
+
+	// ExistingManagedClusterName is the name of a ManagedCluster already managed by the hub.
+	// When set, this ManagedCluster is adopted instead of creating a new one and no import is required.
+	// +optional
+	ExistingManagedClusterName string `json:"existingManagedClusterName,omitempty"`
+
+	// HubName is the name of the HubConfig of the hub where the ManagedCluster is created or adopted.
+	// If empty, a hub is selected by the operator.
+	// +optional
+	HubName string `json:"hubName,omitempty"`
+
+	// DeletionPolicy defines what happens to the ManagedCluster when the RegisteredCluster is deleted.
+	// If empty, the ClusterRegistrar defaultDeletionPolicy is used, otherwise an adopted ManagedCluster
+	// is detached and a ManagedCluster created for the RegisteredCluster is deleted.
+	// +optional
+	DeletionPolicy DeletionPolicy `json:"deletionPolicy,omitempty"`
+
+	// OnManagedClusterDeletion defines what happens when the ManagedCluster is deleted on the hub
+	// while the RegisteredCluster exists. If empty or Detach, the RegisteredCluster stays detached
+	// until the policy is changed to Recreate, which creates a new ManagedCluster and regenerates the import command.
+	// An adopted ManagedCluster is never recreated, it is adopted again once it exists on the hub.
+	// +optional
+	OnManagedClusterDeletion ManagedClusterDeletionAction `json:"onManagedClusterDeletion,omitempty"`
+
+	// SyncTarget contains the settings applied to the SyncTarget created in each location workspace.
+	// +optional
+	SyncTarget SyncTargetSettings `json:"syncTarget,omitempty"`
+
+	// LocationOverrides replaces the SyncTarget settings for specific location workspaces.
+	// +optional
+	LocationOverrides []LocationOverride `json:"locationOverrides,omitempty"`
+}
+
+// DeletionPolicy defines what happens to the ManagedCluster when the RegisteredCluster is deleted
+// +kubebuilder:validation:Enum=Delete;Detach
+type DeletionPolicy string
+
+const (
+	// DeletionPolicyDelete deletes the ManagedCluster, the klusterlet is removed from the registered cluster.
+	DeletionPolicyDelete DeletionPolicy = "Delete"
+	// DeletionPolicyDetach leaves the ManagedCluster on the hub, only the kcp artifacts are removed.
+	DeletionPolicyDetach DeletionPolicy = "Detach"
+)
+
+// ManagedClusterDeletionAction defines what happens when the ManagedCluster is deleted on the hub
+// +kubebuilder:validation:Enum=Detach;Recreate
+type ManagedClusterDeletionAction string
+
+const (
+	// ManagedClusterDeletionActionDetach leaves the RegisteredCluster detached from the hub.
+	ManagedClusterDeletionActionDetach ManagedClusterDeletionAction = "Detach"
+	// ManagedClusterDeletionActionRecreate creates a new ManagedCluster, the cluster must be imported again.
+	ManagedClusterDeletionActionRecreate ManagedClusterDeletionAction = "Recreate"
+)
+
+// SyncTargetSettings defines the SyncTarget spec fields managed through the RegisteredCluster
+type SyncTargetSettings struct {
+	// Unschedulable cordons the SyncTarget, kcp stops scheduling new workloads on it.
+	// +optional
+	Unschedulable bool `json:"unschedulable,omitempty"`
+
+	// EvictAfter is the time after which the workloads scheduled on the SyncTarget are unassigned from it.
+	// +optional
+	EvictAfter *metav1.Time `json:"evictAfter,omitempty"`
+
+	// SupportedAPIExports restricts the APIExports the SyncTarget supports.
+	// If empty, the supported APIExports of the SyncTarget are left to kcp.
+	// +optional
+	SupportedAPIExports []APIExportReference `json:"supportedAPIExports,omitempty"`
+}
+
+// APIExportReference references an APIExport in a kcp workspace
+type APIExportReference struct {
+	// Path is the absolute path of the workspace containing the APIExport.
+	// If empty, the location workspace is used.
+	// +optional
+	Path string `json:"path,omitempty"`
+
+	// ExportName is the name of the APIExport.
+	// +required
+	ExportName string `json:"exportName"`
+}
+
+// LocationOverride overrides the SyncTarget settings for a location workspace
+type LocationOverride struct {
+	// Location is the kcp location workspace, it must be listed in spec.location.
+	// +required
+	Location string `json:"location"`
+
+	// SyncTarget contains the settings applied to the SyncTarget of this location
+	// in place of spec.syncTarget.
+	// +required
+	SyncTarget SyncTargetSettings `json:"syncTarget"`
 }
 
 // RegisteredClusterStatus defines the observed state of RegisteredCluster
@@ -60,12 +153,149 @@ type RegisteredClusterStatus struct {
 	//ApiURL the URL of apiserver endpoint of the registered cluster.
 	// +optional
 	ApiURL string `json:"apiURL,omitempty"`
+
+	// DeletionPhase is the cleanup step in progress while the RegisteredCluster is being deleted.
+	// +optional
+	DeletionPhase DeletionPhase `json:"deletionPhase,omitempty"`
+
+	// Phase summarizes the operator conditions of the RegisteredCluster.
+	// +optional
+	Phase RegisteredClusterPhase `json:"phase,omitempty"`
+
+	// Drift lists the differences between the desired and the actual state found by the last drift check,
+	// they are repaired by the reconcile which found them.
+	// +optional
+	Drift []DriftRecord `json:"drift,omitempty"`
+
+	// LastDriftTime is the last time a drift was found.
+	// +optional
+	LastDriftTime *metav1.Time `json:"lastDriftTime,omitempty"`
+}
+
+// DriftRecord is a difference between the desired and the actual state of an object of the RegisteredCluster
+type DriftRecord struct {
+	// Kind of the drifted object.
+	Kind DriftKind `json:"kind"`
+
+	// Name of the drifted object.
+	// +optional
+	Name string `json:"name,omitempty"`
+
+	// Location is the location workspace of the drifted object.
+	// +optional
+	Location string `json:"location,omitempty"`
+
+	// Message describes the drift.
+	Message string `json:"message"`
 }
 
+// DriftKind is the kind of object checked for drift
+// +kubebuilder:validation:Enum=ManagedCluster;ManagedClusterSetMembership;SyncTarget;ServiceAccount;RBAC;ManifestWork
+type DriftKind string
+
+const (
+	DriftKindManagedCluster              DriftKind = "ManagedCluster"
+	DriftKindManagedClusterSetMembership DriftKind = "ManagedClusterSetMembership"
+	DriftKindSyncTarget                  DriftKind = "SyncTarget"
+	DriftKindServiceAccount              DriftKind = "ServiceAccount"
+	DriftKindRBAC                        DriftKind = "RBAC"
+	DriftKindManifestWork                DriftKind = "ManifestWork"
+)
+
+// RegisteredClusterPhase summarizes the registration progress
+// +kubebuilder:validation:Enum=Pending;Importing;Provisioning;Ready;Detached;Deleting
+type RegisteredClusterPhase string
+
+const (
+	// RegisteredClusterPhasePending waits for a hub and a ManagedCluster
+	RegisteredClusterPhasePending RegisteredClusterPhase = "Pending"
+	// RegisteredClusterPhaseImporting waits for the ManagedCluster to join the hub
+	RegisteredClusterPhaseImporting RegisteredClusterPhase = "Importing"
+	// RegisteredClusterPhaseProvisioning waits for the SyncTargets and the kcp-syncers of the locations
+	RegisteredClusterPhaseProvisioning RegisteredClusterPhase = "Provisioning"
+	// RegisteredClusterPhaseReady is reached when all operator conditions are true
+	RegisteredClusterPhaseReady RegisteredClusterPhase = "Ready"
+	// RegisteredClusterPhaseDetached is set when the ManagedCluster was deleted on the hub
+	RegisteredClusterPhaseDetached RegisteredClusterPhase = "Detached"
+	// RegisteredClusterPhaseDeleting is set while the RegisteredCluster is being deleted
+	RegisteredClusterPhaseDeleting RegisteredClusterPhase = "Deleting"
+)
+
+// DeletionPhase is a cleanup step of the RegisteredCluster deletion
+type DeletionPhase string
+
+const (
+	// DeletionPhaseSyncerManifestWork is the deletion of the kcp-syncer ManifestWorks on the hub
+	DeletionPhaseSyncerManifestWork DeletionPhase = "DeletingSyncerManifestWork"
+	// DeletionPhaseSyncerServiceAccount is the deletion of the kcp-syncer ServiceAccounts in the location workspaces
+	DeletionPhaseSyncerServiceAccount DeletionPhase = "DeletingSyncerServiceAccount"
+	// DeletionPhaseSyncTarget is the deletion of the SyncTargets in the location workspaces
+	DeletionPhaseSyncTarget DeletionPhase = "DeletingSyncTarget"
+	// DeletionPhaseManagedCluster is the deletion or the detach of the ManagedCluster on the hub
+	DeletionPhaseManagedCluster DeletionPhase = "DeletingManagedCluster"
+	// DeletionPhaseImportSecret is the deletion of the import command secret in the RegisteredCluster workspace
+	DeletionPhaseImportSecret DeletionPhase = "DeletingImportSecret"
+	// DeletionPhaseManagedClusterSet is the deletion of the ManagedClusterSet of the workspace on the hub
+	DeletionPhaseManagedClusterSet DeletionPhase = "DeletingManagedClusterSet"
+)
+
+// Conditions owned by the operator, the ManagedCluster conditions are mirrored beside them.
+const (
+	// RegisteredClusterConditionForceDeleted is set when the cleanup didn't complete before the deletion timeout
+	// and the RegisteredCluster finalizer was removed.
+	RegisteredClusterConditionForceDeleted string = "ForceDeleted"
+	// RegisteredClusterConditionHubAssigned is true when a hub is selected for the RegisteredCluster.
+	RegisteredClusterConditionHubAssigned string = "HubAssigned"
+	// RegisteredClusterConditionManagedClusterCreated is true when the ManagedCluster is created or adopted on the hub.
+	RegisteredClusterConditionManagedClusterCreated string = "ManagedClusterCreated"
+	// RegisteredClusterConditionImportCommandReady is true when the import command is available
+	// or not required by an adopted ManagedCluster.
+	RegisteredClusterConditionImportCommandReady string = "ImportCommandReady"
+	// RegisteredClusterConditionSyncTargetsReady is true when the SyncTargets of all locations are synced.
+	RegisteredClusterConditionSyncTargetsReady string = "SyncTargetsReady"
+	// RegisteredClusterConditionSyncersReady is true when the kcp-syncer ManifestWorks of all locations are applied.
+	RegisteredClusterConditionSyncersReady string = "SyncersReady"
+	// RegisteredClusterConditionReady is true when all the other operator conditions are true.
+	RegisteredClusterConditionReady string = "Ready"
+)
+
+// Reasons of the operator conditions
+const (
+	ReasonHubSelected             string = "HubSelected"
+	ReasonManagedClusterCreated   string = "ManagedClusterCreated"
+	ReasonManagedClusterAdopted   string = "ManagedClusterAdopted"
+	ReasonManagedClusterNotFound  string = "ManagedClusterNotFound"
+	ReasonManagedClusterDeleted   string = "ManagedClusterDeleted"
+	ReasonImportSecretAvailable   string = "ImportSecretAvailable"
+	ReasonImportSecretNotFound    string = "ImportSecretNotFound"
+	ReasonImportNotRequired       string = "ImportNotRequired"
+	ReasonManagedClusterNotJoined string = "ManagedClusterNotJoined"
+	ReasonNoLocation              string = "NoLocation"
+	ReasonSyncTargetsSynced       string = "SyncTargetsSynced"
+	ReasonSyncersApplied          string = "SyncersApplied"
+	ReasonSyncerNotApplied        string = "SyncerNotApplied"
+	ReasonReady                   string = "Ready"
+	ReasonNotReady                string = "NotReady"
+	ReasonDeleting                string = "Deleting"
+)
+
+// Reasons of the operator conditions set to false by a reconcile error, one per error class
+const (
+	// ReasonTransientError is an error retried with the exponential backoff of the workqueue
+	ReasonTransientError string = "TransientError"
+	// ReasonConfigurationError is an error which requires a change of the spec or of the hub configuration
+	ReasonConfigurationError string = "ConfigurationError"
+	// ReasonPermissionError is an error caused by missing permissions or invalid credentials
+	ReasonPermissionError string = "PermissionError"
+	// ReasonHubUnreachableError is an error caused by a hub which can not be reached
+	ReasonHubUnreachableError string = "HubUnreachableError"
+)
+
 // +genclient
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
 // +kubebuilder:printcolumn:JSONPath=`.status.apiURL`,name="Cluster URL",type=string
+// +kubebuilder:printcolumn:JSONPath=`.status.phase`,name="Phase",type=string
 // +kubebuilder:printcolumn:JSONPath=`.status.conditions[?(@.type=="ManagedClusterJoined")].status`,name="Joined",type=string
 // +kubebuilder:printcolumn:JSONPath=`.status.conditions[?(@.type=="ManagedClusterConditionAvailable")].status`,name="Available",type=string
 // +kubebuilder:printcolumn:JSONPath=`.metadata.creationTimestamp`,name="Age",type=date
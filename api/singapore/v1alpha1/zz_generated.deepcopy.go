@@ -8,17 +8,34 @@
 package v1alpha1
 
 import (
-	"k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	clusterv1 "open-cluster-management.io/api/cluster/v1"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *APIExportReference) DeepCopyInto(out *APIExportReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new APIExportReference.
+func (in *APIExportReference) DeepCopy() *APIExportReference {
+	if in == nil {
+		return nil
+	}
+	out := new(APIExportReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ClusterRegistrar) DeepCopyInto(out *ClusterRegistrar) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.Spec = in.Spec
+	in.Spec.DeepCopyInto(&out.Spec)
 	in.Status.DeepCopyInto(&out.Status)
 }
 
@@ -75,7 +92,14 @@ func (in *ClusterRegistrarList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ClusterRegistrarSpec) DeepCopyInto(out *ClusterRegistrarSpec) {
 	*out = *in
-	out.ComputeService = in.ComputeService
+	in.ComputeService.DeepCopyInto(&out.ComputeService)
+	in.Manager.DeepCopyInto(&out.Manager)
+	in.Webhook.DeepCopyInto(&out.Webhook)
+	if in.Hubs != nil {
+		in, out := &in.Hubs, &out.Hubs
+		*out = make([]HubSpec, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterRegistrarSpec.
@@ -93,11 +117,21 @@ func (in *ClusterRegistrarStatus) DeepCopyInto(out *ClusterRegistrarStatus) {
 	*out = *in
 	if in.Conditions != nil {
 		in, out := &in.Conditions, &out.Conditions
-		*out = make([]v1.Condition, len(*in))
+		*out = make([]metav1.Condition, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.InstalledObjects != nil {
+		in, out := &in.InstalledObjects, &out.InstalledObjects
+		*out = make([]InstalledObject, len(*in))
+		copy(*out, *in)
+	}
+	if in.Leftovers != nil {
+		in, out := &in.Leftovers, &out.Leftovers
+		*out = make([]InstalledObject, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterRegistrarStatus.
@@ -110,10 +144,35 @@ func (in *ClusterRegistrarStatus) DeepCopy() *ClusterRegistrarStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ComputeBootstrap) DeepCopyInto(out *ComputeBootstrap) {
+	*out = *in
+	if in.Workspaces != nil {
+		in, out := &in.Workspaces, &out.Workspaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ComputeBootstrap.
+func (in *ComputeBootstrap) DeepCopy() *ComputeBootstrap {
+	if in == nil {
+		return nil
+	}
+	out := new(ComputeBootstrap)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ComputeService) DeepCopyInto(out *ComputeService) {
 	*out = *in
 	out.ComputeKubeconfigSecretRef = in.ComputeKubeconfigSecretRef
+	if in.Bootstrap != nil {
+		in, out := &in.Bootstrap, &out.Bootstrap
+		*out = new(ComputeBootstrap)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ComputeService.
@@ -126,6 +185,21 @@ func (in *ComputeService) DeepCopy() *ComputeService {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DriftRecord) DeepCopyInto(out *DriftRecord) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DriftRecord.
+func (in *DriftRecord) DeepCopy() *DriftRecord {
+	if in == nil {
+		return nil
+	}
+	out := new(DriftRecord)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *HubConfig) DeepCopyInto(out *HubConfig) {
 	*out = *in
@@ -206,7 +280,7 @@ func (in *HubConfigStatus) DeepCopyInto(out *HubConfigStatus) {
 	*out = *in
 	if in.Conditions != nil {
 		in, out := &in.Conditions, &out.Conditions
-		*out = make([]v1.Condition, len(*in))
+		*out = make([]metav1.Condition, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
@@ -223,6 +297,130 @@ func (in *HubConfigStatus) DeepCopy() *HubConfigStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HubSpec) DeepCopyInto(out *HubSpec) {
+	*out = *in
+	out.KubeconfigSecretRef = in.KubeconfigSecretRef
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HubSpec.
+func (in *HubSpec) DeepCopy() *HubSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(HubSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InstalledObject) DeepCopyInto(out *InstalledObject) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InstalledObject.
+func (in *InstalledObject) DeepCopy() *InstalledObject {
+	if in == nil {
+		return nil
+	}
+	out := new(InstalledObject)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LocationOverride) DeepCopyInto(out *LocationOverride) {
+	*out = *in
+	in.SyncTarget.DeepCopyInto(&out.SyncTarget)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LocationOverride.
+func (in *LocationOverride) DeepCopy() *LocationOverride {
+	if in == nil {
+		return nil
+	}
+	out := new(LocationOverride)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ManagerSpec) DeepCopyInto(out *ManagerSpec) {
+	*out = *in
+	in.OperandSpec.DeepCopyInto(&out.OperandSpec)
+	if in.Shards != nil {
+		in, out := &in.Shards, &out.Shards
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ManagerSpec.
+func (in *ManagerSpec) DeepCopy() *ManagerSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ManagerSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OperandSpec) DeepCopyInto(out *OperandSpec) {
+	*out = *in
+	if in.Replicas != nil {
+		in, out := &in.Replicas, &out.Replicas
+		*out = new(int32)
+		**out = **in
+	}
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = new(corev1.ResourceRequirements)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.LogVerbosity != nil {
+		in, out := &in.LogVerbosity, &out.LogVerbosity
+		*out = new(int32)
+		**out = **in
+	}
+	if in.NodeSelector != nil {
+		in, out := &in.NodeSelector, &out.NodeSelector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Tolerations != nil {
+		in, out := &in.Tolerations, &out.Tolerations
+		*out = make([]corev1.Toleration, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Affinity != nil {
+		in, out := &in.Affinity, &out.Affinity
+		*out = new(corev1.Affinity)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Env != nil {
+		in, out := &in.Env, &out.Env
+		*out = make([]corev1.EnvVar, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OperandSpec.
+func (in *OperandSpec) DeepCopy() *OperandSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(OperandSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RegisteredCluster) DeepCopyInto(out *RegisteredCluster) {
 	*out = *in
@@ -290,6 +488,14 @@ func (in *RegisteredClusterSpec) DeepCopyInto(out *RegisteredClusterSpec) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	in.SyncTarget.DeepCopyInto(&out.SyncTarget)
+	if in.LocationOverrides != nil {
+		in, out := &in.LocationOverrides, &out.LocationOverrides
+		*out = make([]LocationOverride, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RegisteredClusterSpec.
@@ -309,7 +515,7 @@ func (in *RegisteredClusterStatus) DeepCopyInto(out *RegisteredClusterStatus) {
 	out.ClusterSecretRef = in.ClusterSecretRef
 	if in.Conditions != nil {
 		in, out := &in.Conditions, &out.Conditions
-		*out = make([]v1.Condition, len(*in))
+		*out = make([]metav1.Condition, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
@@ -334,6 +540,15 @@ func (in *RegisteredClusterStatus) DeepCopyInto(out *RegisteredClusterStatus) {
 		*out = make([]clusterv1.ManagedClusterClaim, len(*in))
 		copy(*out, *in)
 	}
+	if in.Drift != nil {
+		in, out := &in.Drift, &out.Drift
+		*out = make([]DriftRecord, len(*in))
+		copy(*out, *in)
+	}
+	if in.LastDriftTime != nil {
+		in, out := &in.LastDriftTime, &out.LastDriftTime
+		*out = (*in).DeepCopy()
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RegisteredClusterStatus.
@@ -345,3 +560,58 @@ func (in *RegisteredClusterStatus) DeepCopy() *RegisteredClusterStatus {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SyncTargetSettings) DeepCopyInto(out *SyncTargetSettings) {
+	*out = *in
+	if in.EvictAfter != nil {
+		in, out := &in.EvictAfter, &out.EvictAfter
+		*out = (*in).DeepCopy()
+	}
+	if in.SupportedAPIExports != nil {
+		in, out := &in.SupportedAPIExports, &out.SupportedAPIExports
+		*out = make([]APIExportReference, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SyncTargetSettings.
+func (in *SyncTargetSettings) DeepCopy() *SyncTargetSettings {
+	if in == nil {
+		return nil
+	}
+	out := new(SyncTargetSettings)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WebhookSpec) DeepCopyInto(out *WebhookSpec) {
+	*out = *in
+	in.OperandSpec.DeepCopyInto(&out.OperandSpec)
+	if in.Enabled != nil {
+		in, out := &in.Enabled, &out.Enabled
+		*out = new(bool)
+		**out = **in
+	}
+	if in.FailurePolicy != nil {
+		in, out := &in.FailurePolicy, &out.FailurePolicy
+		*out = new(v1.FailurePolicyType)
+		**out = **in
+	}
+	if in.TimeoutSeconds != nil {
+		in, out := &in.TimeoutSeconds, &out.TimeoutSeconds
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WebhookSpec.
+func (in *WebhookSpec) DeepCopy() *WebhookSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(WebhookSpec)
+	in.DeepCopyInto(out)
+	return out
+}
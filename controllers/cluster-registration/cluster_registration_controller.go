@@ -21,8 +21,10 @@ import (
 	apiextensionsclient "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
 	"k8s.io/apimachinery/pkg/api/equality"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
@@ -37,6 +39,7 @@ import (
 	"github.com/stolostron/compute-operator/resources"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	k8slabels "k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	clusterapiv1 "open-cluster-management.io/api/cluster/v1"
@@ -45,9 +48,11 @@ import (
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"sigs.k8s.io/controller-runtime/pkg/source"
@@ -55,7 +60,7 @@ import (
 	"github.com/kcp-dev/logicalcluster/v2"
 )
 
-// +kubebuilder:rbac:groups="",resources={secrets},verbs=get;list;watch;create;update;patch
+// +kubebuilder:rbac:groups="",resources={secrets},verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups="singapore.open-cluster-management.io",resources={hubconfigs},verbs=get;list;watch
 // +kubebuilder:rbac:groups="singapore.open-cluster-management.io",resources={registeredclusters},verbs=get;list;watch;create;update;delete
 
@@ -73,10 +78,49 @@ const (
 	ManagedClusterSetlabel          string = "cluster.open-cluster-management.io/clusterset"
 	HubNameLabel                    string = "registeredcluster.singapore.open-cluster-management.io/hub"
 	ManagedClusterSetClustername    string = "tenancy.kcp.dev/clustername"
+	AdoptedManagedClusterAnnotation string = "registeredcluster.singapore.open-cluster-management.io/adopted"
+	SkipCleanupAnnotation           string = "registeredcluster.singapore.open-cluster-management.io/skip-cleanup"
+	// OriginalClusterSetAnnotation keeps the ManagedClusterSet of an adopted ManagedCluster, empty if it had none
+	OriginalClusterSetAnnotation string = "registeredcluster.singapore.open-cluster-management.io/original-clusterset"
 )
 
+// Cleanup steps which can be listed, comma separated, in the SkipCleanupAnnotation
+const (
+	CleanupStepAll               string = "all"
+	CleanupStepManifestWork      string = "manifestwork"
+	CleanupStepServiceAccount    string = "serviceaccount"
+	CleanupStepSyncTarget        string = "synctarget"
+	CleanupStepManagedCluster    string = "managedcluster"
+	CleanupStepImportSecret      string = "importsecret"
+	CleanupStepManagedClusterSet string = "managedclusterset"
+)
+
+// Reasons of the events recorded on the RegisteredCluster
+const (
+	EventReasonHubAssigned           string = "HubAssigned"
+	EventReasonManagedClusterCreated string = "ManagedClusterCreated"
+	EventReasonManagedClusterAdopted string = "ManagedClusterAdopted"
+	EventReasonImportCommandReady    string = "ImportCommandReady"
+	EventReasonManagedClusterJoined  string = "ManagedClusterJoined"
+	EventReasonSyncTargetCreated     string = "SyncTargetCreated"
+	EventReasonSyncerDeploying       string = "SyncerDeploying"
+	EventReasonSyncerDeployed        string = "SyncerDeployed"
+)
+
+// deletionPhaseMessages are the messages of the events recorded when a deletion phase starts
+var deletionPhaseMessages = map[singaporev1alpha1.DeletionPhase]string{
+	singaporev1alpha1.DeletionPhaseSyncerManifestWork:   "Deleting the kcp-syncer ManifestWorks on the hub",
+	singaporev1alpha1.DeletionPhaseSyncerServiceAccount: "Deleting the kcp-syncer ServiceAccounts in the location workspaces",
+	singaporev1alpha1.DeletionPhaseSyncTarget:           "Deleting the SyncTargets in the location workspaces",
+	singaporev1alpha1.DeletionPhaseManagedCluster:       "Deleting or detaching the ManagedCluster on the hub",
+	singaporev1alpha1.DeletionPhaseImportSecret:         "Deleting the import command secret",
+	singaporev1alpha1.DeletionPhaseManagedClusterSet:    "Deleting the ManagedClusterSet of the workspace on the hub",
+}
+
 const defaultSyncerImage = "ghcr.io/kcp-dev/kcp/syncer:v0.7.6"
 
+const defaultManagedClusterSetName = "default"
+
 var syncTargetGVR = schema.GroupVersionResource{
 	Group:    "workload.kcp.dev",
 	Version:  "v1alpha1",
@@ -97,9 +141,67 @@ type RegisteredClusterReconciler struct {
 	Log         logr.Logger
 	Scheme      *runtime.Scheme
 	HubClusters []helpers.HubInstance
+	// DefaultDeletionPolicy is the ClusterRegistrar default deletion policy,
+	// the installer restarts the manager when it changes
+	DefaultDeletionPolicy singaporev1alpha1.DeletionPolicy
+	// DeletionTimeout is the duration after which the cleanup is forced, zero means no timeout
+	DeletionTimeout time.Duration
+	// OrphanScanInterval is the period of the scan for resources left by deleted RegisteredClusters, zero disables the scan
+	OrphanScanInterval time.Duration
+	EventRecorder      *helpers.EventRecorder
+	// ResyncPeriod is the period of the full reconcile of each RegisteredCluster, zero disables the resync
+	ResyncPeriod time.Duration
+	// MaxConcurrentReconciles is the number of workspaces reconciled in parallel
+	MaxConcurrentReconciles int
+	// workspaceLocks serializes the reconciles of the RegisteredClusters of a workspace
+	workspaceLocks *helpers.KeyedMutex
+	// ShardManager restricts the reconcile to the workspaces of the shards owned by the replica, nil reconciles all workspaces
+	ShardManager *helpers.ShardManager
+	// shardEvents enqueues the RegisteredClusters of the newly acquired shards
+	shardEvents chan event.GenericEvent
+	// waitBackoff spaces the requeues of the RegisteredClusters waiting for a resource
+	waitBackoff *waitBackoff
 }
 
 func (r *RegisteredClusterReconciler) Reconcile(computeContextOri context.Context, req ctrl.Request) (ctrl.Result, error) {
+	if r.ShardManager != nil && !r.ShardManager.Owns(req.ClusterName) {
+		r.Log.V(4).Info("workspace not in an owned shard", "clusterName", req.ClusterName, "namespace", req.Namespace, "name", req.Name)
+		return ctrl.Result{}, nil
+	}
+	// The RegisteredClusters of a workspace share the ManagedClusterSet and the hub
+	unlock := r.workspaceLocks.Lock(req.ClusterName)
+	defer unlock()
+	result, err := r.reconcile(computeContextOri, req)
+	key := registeredClusterKey{clusterName: req.ClusterName, namespace: req.Namespace, name: req.Name}
+	switch {
+	case err != nil:
+	case result.Requeue:
+		// the RegisteredCluster waits for a resource, increase the delay on each attempt
+		result.RequeueAfter = r.waitBackoff.next(key, result.RequeueAfter)
+	case result.RequeueAfter == 0:
+		r.waitBackoff.reset(key)
+		// Resync periodically to detect and repair the changes which are not watched
+		if r.ResyncPeriod > 0 && r.needsResync(computeContextOri, req) {
+			result.RequeueAfter = wait.Jitter(r.ResyncPeriod, 0.1)
+		}
+	}
+	return result, err
+}
+
+// needsResync returns true when the RegisteredCluster exists and is not being deleted,
+// a deleted RegisteredCluster is not requeued.
+func (r *RegisteredClusterReconciler) needsResync(computeContextOri context.Context, req ctrl.Request) bool {
+	regCluster := &singaporev1alpha1.RegisteredCluster{}
+	if err := r.Client.Get(
+		logicalcluster.WithCluster(computeContextOri, logicalcluster.New(req.ClusterName)),
+		types.NamespacedName{Namespace: req.Namespace, Name: req.Name},
+		regCluster); err != nil {
+		return false
+	}
+	return regCluster.DeletionTimestamp == nil
+}
+
+func (r *RegisteredClusterReconciler) reconcile(computeContextOri context.Context, req ctrl.Request) (result ctrl.Result, err error) {
 	_ = context.Background()
 	ctx := context.TODO()
 	// Return a copy of the conext and injects the cluster name in the copied context
@@ -117,16 +219,31 @@ func (r *RegisteredClusterReconciler) Reconcile(computeContextOri context.Contex
 			// Request object not found, could have been deleted after reconcile request.
 			// Owned objects are automatically garbage collected. For additional cleanup logic use finalizers.
 			// Return and don't requeue
+			registeredClusterMetrics.forget(registeredClusterKey{clusterName: req.ClusterName, namespace: req.Namespace, name: req.Name})
 			return reconcile.Result{}, nil
 		}
 		// Error reading the object - requeue the request.
 		return reconcile.Result{}, giterrors.WithStack(err)
 	}
 
+	if r.isDeletionTimedOut(regCluster) {
+		return r.forceRegclusterDeletion(computeContext, regCluster)
+	}
+
+	// The status changes of the reconcile are patched at once
+	originalStatus := regCluster.Status.DeepCopy()
+	defer func() {
+		if patchErr := r.patchStatus(computeContext, regCluster, originalStatus); patchErr != nil {
+			logger.Error(patchErr, "failed to patch the registeredcluster status")
+			if err == nil {
+				err = patchErr
+			}
+		}
+	}()
+
 	hubCluster, err := r.getHubCluster(ctx, regCluster, r.HubClusters, req.ClusterName)
 	if err != nil {
-		logger.Error(err, "failed to get HubCluster for RegisteredCluster workspace")
-		return ctrl.Result{}, err
+		return r.reportError(computeContext, regCluster, singaporev1alpha1.RegisteredClusterConditionHubAssigned, err)
 	}
 
 	controllerutil.AddFinalizer(regCluster, helpers.RegisteredClusterFinalizer)
@@ -137,90 +254,176 @@ func (r *RegisteredClusterReconciler) Reconcile(computeContextOri context.Contex
 	}
 
 	if err := r.syncManagedClusterSet(ctx, &hubCluster, regCluster); err != nil {
-		logger.Error(err, "failed to create ManagedClusterSet")
-		return ctrl.Result{}, err
+		return r.reportError(computeContext, regCluster, singaporev1alpha1.RegisteredClusterConditionHubAssigned,
+			helpers.NewHubError(hubCluster.HubConfig.Name, err))
+	}
+
+	// The SyncTargets are resolved once per reconcile and passed to the steps of the locations
+	syncTargets, err := r.getSyncTargets(computeContext, regCluster)
+	if err != nil {
+		return r.reportError(computeContext, regCluster, singaporev1alpha1.RegisteredClusterConditionSyncTargetsReady, err)
 	}
 
 	if regCluster.DeletionTimestamp == nil {
+		setConditions(regCluster,
+			newCondition(singaporev1alpha1.RegisteredClusterConditionHubAssigned, metav1.ConditionTrue,
+				singaporev1alpha1.ReasonHubSelected, fmt.Sprintf("assigned to the hub %s", hubCluster.HubConfig.Name)))
+		// A failed drift check doesn't prevent the reconcile from repairing the objects
+		if err := r.detectDrift(computeContext, ctx, regCluster, &hubCluster, syncTargets); err != nil {
+			logger.Error(err, "failed to detect drift")
+		}
+		if detached, err := r.syncManagedClusterDeletion(computeContext, ctx, regCluster, &hubCluster, syncTargets); err != nil {
+			return r.reportError(computeContext, regCluster, singaporev1alpha1.RegisteredClusterConditionManagedClusterCreated, err)
+		} else if detached {
+			logger.V(1).Info("registeredcluster detached, the managedcluster was deleted on the hub")
+			registeredClusterMetrics.setPhase(regCluster, hubCluster.HubConfig.Name)
+			return ctrl.Result{}, nil
+		}
 		// create managecluster on creation of registeredcluster CR
-		if r, err := r.createManagedCluster(ctx, regCluster, &hubCluster, req.ClusterName); err != nil || r.Requeue {
-			logger.Error(err, "failed to create ManagedCluster")
-			return r, err
+		if result, err := r.createManagedCluster(ctx, regCluster, &hubCluster, req.ClusterName); err != nil {
+			return r.reportError(computeContext, regCluster, singaporev1alpha1.RegisteredClusterConditionManagedClusterCreated,
+				helpers.NewHubError(hubCluster.HubConfig.Name, err))
+		} else if result.Requeue {
+			return result, nil
 		}
 
 	}
 	managedCluster, err := r.getManagedCluster(ctx, regCluster, &hubCluster, req.ClusterName)
 	if err != nil && !k8serrors.IsNotFound(err) {
-		logger.Error(err, "failed to get ManagedCluster")
-		return ctrl.Result{}, err
+		return r.reportError(computeContext, regCluster, singaporev1alpha1.RegisteredClusterConditionManagedClusterCreated,
+			helpers.NewHubError(hubCluster.HubConfig.Name, err))
 	}
 
 	//if deletetimestamp then process deletion
 	if regCluster.DeletionTimestamp != nil {
-		if r, err := r.processRegclusterDeletion(ctx, regCluster, &managedCluster, &hubCluster); err != nil || r.Requeue {
+		setConditions(regCluster)
+		registeredClusterMetrics.setPhase(regCluster, hubCluster.HubConfig.Name)
+		if r, err := r.processRegclusterDeletion(computeContext, ctx, regCluster, &managedCluster, &hubCluster, syncTargets); err != nil || r.Requeue {
 			return r, err
 		}
 		controllerutil.RemoveFinalizer(regCluster, helpers.RegisteredClusterFinalizer)
 		if err := r.Client.Update(computeContext, regCluster); err != nil {
 			return ctrl.Result{}, giterrors.WithStack(err)
 		}
+		observeDeletion(regCluster, deletionResultCompleted)
+		registeredClusterMetrics.forget(newRegisteredClusterKey(regCluster))
 		return reconcile.Result{}, nil
 	}
 
+	defer registeredClusterMetrics.setPhase(regCluster, hubCluster.HubConfig.Name)
+
+	managedClusterCreated := newCondition(singaporev1alpha1.RegisteredClusterConditionManagedClusterCreated, metav1.ConditionTrue,
+		singaporev1alpha1.ReasonManagedClusterCreated, fmt.Sprintf("ManagedCluster %s created", managedCluster.Name))
+	switch {
+	case err != nil:
+		managedClusterCreated = newCondition(singaporev1alpha1.RegisteredClusterConditionManagedClusterCreated, metav1.ConditionFalse,
+			singaporev1alpha1.ReasonManagedClusterNotFound, "waiting for the ManagedCluster")
+	case isAdoptedManagedCluster(&managedCluster):
+		managedClusterCreated = newCondition(singaporev1alpha1.RegisteredClusterConditionManagedClusterCreated, metav1.ConditionTrue,
+			singaporev1alpha1.ReasonManagedClusterAdopted, fmt.Sprintf("ManagedCluster %s adopted", managedCluster.Name))
+	}
+	setConditions(regCluster, managedClusterCreated)
+
 	// update status of registeredcluster - add import command
 	// TODO - skip creating the secret if cluster is already imported - and maybe delete it once cluster is imported?
-	if err := r.updateImportCommand(computeContext, ctx, regCluster, &managedCluster, &hubCluster); err != nil {
-		if k8serrors.IsNotFound(err) {
-			return reconcile.Result{Requeue: true, RequeueAfter: 1 * time.Second}, nil
+	// An adopted managedcluster which already joined the hub doesn't need to be imported
+	importCommandReady := newCondition(singaporev1alpha1.RegisteredClusterConditionImportCommandReady, metav1.ConditionTrue,
+		singaporev1alpha1.ReasonImportNotRequired, "the adopted ManagedCluster already joined the hub")
+	if !isAdoptedManagedCluster(&managedCluster) || !isManagedClusterJoined(&managedCluster) {
+		if err := r.updateImportCommand(computeContext, ctx, regCluster, &managedCluster, &hubCluster); err != nil {
+			if k8serrors.IsNotFound(err) {
+				setConditions(regCluster,
+					newCondition(singaporev1alpha1.RegisteredClusterConditionImportCommandReady, metav1.ConditionFalse,
+						singaporev1alpha1.ReasonImportSecretNotFound, "waiting for the hub to generate the import secret"))
+				return reconcile.Result{Requeue: true, RequeueAfter: 1 * time.Second}, nil
+			}
+			return r.reportError(computeContext, regCluster, singaporev1alpha1.RegisteredClusterConditionImportCommandReady,
+				helpers.NewHubError(hubCluster.HubConfig.Name, err))
 		}
-		logger.Error(err, "failed to update import command")
-		return ctrl.Result{}, err
+		importCommandReady = newCondition(singaporev1alpha1.RegisteredClusterConditionImportCommandReady, metav1.ConditionTrue,
+			singaporev1alpha1.ReasonImportSecretAvailable, fmt.Sprintf("import command available in the secret %s", regCluster.Status.ImportCommandRef.Name))
 	}
+	setConditions(regCluster, importCommandReady)
+
 	// update status of registeredcluster
-	if err := r.updateRegisteredClusterStatus(computeContext, regCluster, &managedCluster); err != nil {
-		logger.Error(err, "failed to update registered cluster status")
-		return ctrl.Result{}, err
+	wasJoined := meta.IsStatusConditionTrue(regCluster.Status.Conditions, clusterapiv1.ManagedClusterConditionJoined)
+	r.updateRegisteredClusterStatus(regCluster, &managedCluster)
+	if isManagedClusterJoined(&managedCluster) && !wasJoined {
+		registeredClusterMetrics.observeJoin(regCluster, &managedCluster, hubCluster.HubConfig.Name)
+		r.EventRecorder.Eventf(regCluster, corev1.EventTypeNormal, EventReasonManagedClusterJoined,
+			"ManagedCluster %s joined the hub %s", managedCluster.Name, hubCluster.HubConfig.Name)
+	}
+
+	if !isManagedClusterJoined(&managedCluster) {
+		setConditions(regCluster,
+			newCondition(singaporev1alpha1.RegisteredClusterConditionSyncTargetsReady, metav1.ConditionFalse,
+				singaporev1alpha1.ReasonManagedClusterNotJoined, "waiting for the ManagedCluster to join the hub"),
+			newCondition(singaporev1alpha1.RegisteredClusterConditionSyncersReady, metav1.ConditionFalse,
+				singaporev1alpha1.ReasonManagedClusterNotJoined, "waiting for the ManagedCluster to join the hub"))
+		return ctrl.Result{}, nil
 	}
 
-	if status, ok := helpers.GetConditionStatus(managedCluster.Status.Conditions, clusterapiv1.ManagedClusterConditionJoined); ok && status == metav1.ConditionTrue {
-		if len(regCluster.Spec.Location) > 0 {
-			for _, locationWorkspace := range regCluster.Spec.Location {
-				// sync SyncTarget
-				if err := r.syncSyncTarget(computeContext, regCluster, locationWorkspace, &managedCluster); err != nil {
-					logger.Error(err, "failed to sync SyncTarget in location workspace %s", locationWorkspace)
-					return ctrl.Result{}, giterrors.WithStack(err)
-				}
+	if len(regCluster.Spec.Location) == 0 {
+		setConditions(regCluster,
+			newCondition(singaporev1alpha1.RegisteredClusterConditionSyncTargetsReady, metav1.ConditionTrue,
+				singaporev1alpha1.ReasonNoLocation, "no location workspace"),
+			newCondition(singaporev1alpha1.RegisteredClusterConditionSyncersReady, metav1.ConditionTrue,
+				singaporev1alpha1.ReasonNoLocation, "no location workspace"))
+		return ctrl.Result{}, nil
+	}
 
-				// sync kcp-syncer service account
-				token := ""
-				sa, err := r.syncServiceAccount(computeContext, ctx, regCluster, locationWorkspace, &managedCluster, &hubCluster)
-				if err != nil {
-					logger.Error(err, "failed to sync ServiceAccount in the location workspace %s", locationWorkspace)
-					return ctrl.Result{}, err
-				} else {
-					token, err = r.getKcpSyncerSAToken(computeContext, regCluster, locationWorkspace, sa)
-					if err != nil {
-						logger.V(2).Info("secret not ready, requeue")
-						return reconcile.Result{Requeue: true, RequeueAfter: 1 * time.Second}, err
-					}
-				}
+	pendingSyncers := make([]string, 0)
+	for _, locationWorkspace := range regCluster.Spec.Location {
+		// sync SyncTarget
+		syncTarget, err := r.syncSyncTarget(computeContext, regCluster, locationWorkspace, &managedCluster, syncTargets[locationWorkspace])
+		if err != nil {
+			return r.reportError(computeContext, regCluster, singaporev1alpha1.RegisteredClusterConditionSyncTargetsReady,
+				giterrors.Wrapf(err, "location %s", locationWorkspace), "location", locationWorkspace)
+		}
 
-				// sync kcp-syncer deployment and supporting resources
-				if err := r.syncKcpSyncer(computeContext, ctx, regCluster, locationWorkspace, &managedCluster, &hubCluster, token); err != nil {
-					logger.Error(err, "failed to sync kcp-syncer in the location workspace %s", locationWorkspace)
-					return ctrl.Result{}, err
-				}
+		// sync kcp-syncer service account
+		token := ""
+		sa, err := r.syncServiceAccount(computeContext, ctx, regCluster, locationWorkspace, &managedCluster, &hubCluster, syncTarget)
+		if err != nil {
+			return r.reportError(computeContext, regCluster, singaporev1alpha1.RegisteredClusterConditionSyncersReady,
+				giterrors.Wrapf(err, "location %s", locationWorkspace), "location", locationWorkspace)
+		} else {
+			token, err = r.getKcpSyncerSAToken(computeContext, regCluster, locationWorkspace, syncTarget, sa)
+			if err != nil {
+				logger.V(2).Info("secret not ready, requeue", "location", locationWorkspace, "error", err.Error())
+				return reconcile.Result{Requeue: true, RequeueAfter: 1 * time.Second}, nil
 			}
 		}
+
+		// sync kcp-syncer deployment and supporting resources
+		applied, err := r.syncKcpSyncer(computeContext, ctx, regCluster, locationWorkspace, &managedCluster, &hubCluster, syncTarget, token)
+		if err != nil {
+			return r.reportError(computeContext, regCluster, singaporev1alpha1.RegisteredClusterConditionSyncersReady,
+				helpers.NewHubError(hubCluster.HubConfig.Name, giterrors.Wrapf(err, "location %s", locationWorkspace)),
+				"location", locationWorkspace)
+		}
+		if !applied {
+			pendingSyncers = append(pendingSyncers, locationWorkspace)
+		}
 	}
 
+	syncersReady := newCondition(singaporev1alpha1.RegisteredClusterConditionSyncersReady, metav1.ConditionTrue,
+		singaporev1alpha1.ReasonSyncersApplied, "kcp-syncer ManifestWorks applied for all locations")
+	if len(pendingSyncers) > 0 {
+		syncersReady = newCondition(singaporev1alpha1.RegisteredClusterConditionSyncersReady, metav1.ConditionFalse,
+			singaporev1alpha1.ReasonSyncerNotApplied, fmt.Sprintf("kcp-syncer ManifestWorks not applied for locations: %s", strings.Join(pendingSyncers, ", ")))
+	}
+	setConditions(regCluster,
+		newCondition(singaporev1alpha1.RegisteredClusterConditionSyncTargetsReady, metav1.ConditionTrue,
+			singaporev1alpha1.ReasonSyncTargetsSynced, "SyncTargets synced in all locations"),
+		syncersReady)
 	return ctrl.Result{}, nil
 }
 
 func (r *RegisteredClusterReconciler) getManagedClusterSetList(ctx context.Context, hubCluster *helpers.HubInstance, regCluster *singaporev1alpha1.RegisteredCluster) (*clusterapiv1beta1.ManagedClusterSetList, error) {
 	managedClusterSetList := &clusterapiv1beta1.ManagedClusterSetList{}
 
-	if err := hubCluster.Client.List(ctx, managedClusterSetList, client.MatchingLabels(map[string]string{ManagedClusterSetClustername: helpers.ComputeWorkspaceName(logicalcluster.From(regCluster).String())})); err != nil {
+	if err := hubCluster.Client.List(ctx, managedClusterSetList, client.MatchingFields{managedClusterSetWorkspaceIndex: helpers.ComputeWorkspaceName(logicalcluster.From(regCluster).String())}); err != nil {
 		// Error reading the object - requeue the request.
 		return managedClusterSetList, giterrors.WithStack(err)
 	}
@@ -255,6 +458,9 @@ func (r *RegisteredClusterReconciler) syncManagedClusterSet(ctx context.Context,
 				Labels: map[string]string{
 					ManagedClusterSetClustername: helpers.ComputeWorkspaceName(logicalcluster.From(regCluster).String()),
 				},
+				Annotations: map[string]string{
+					ClusterNameAnnotation: logicalcluster.From(regCluster).String(),
+				},
 			},
 		}
 
@@ -262,6 +468,28 @@ func (r *RegisteredClusterReconciler) syncManagedClusterSet(ctx context.Context,
 			return giterrors.WithStack(err)
 		}
 		logger.V(2).Info("managedclusterset is created")
+		r.EventRecorder.Eventf(regCluster, corev1.EventTypeNormal, EventReasonHubAssigned,
+			"Workspace assigned to the hub %s with the ManagedClusterSet %s", hubCluster.HubConfig.Name, managedClusterSet.Name)
+		return nil
+	}
+
+	// Annotate the managedclustersets created without the workspace annotation,
+	// the orphan scan uses it to find the workspace of the managedclusterset
+	for i := range managedClusterSetList.Items {
+		managedClusterSet := &managedClusterSetList.Items[i]
+		if _, ok := managedClusterSet.GetAnnotations()[ClusterNameAnnotation]; ok {
+			continue
+		}
+		patch := client.MergeFrom(managedClusterSet.DeepCopy())
+		annotations := managedClusterSet.GetAnnotations()
+		mergeMap(&annotations, map[string]string{
+			ClusterNameAnnotation: logicalcluster.From(regCluster).String(),
+		})
+		managedClusterSet.SetAnnotations(annotations)
+		if err := hubCluster.Client.Patch(ctx, managedClusterSet, patch); err != nil {
+			return giterrors.WithStack(err)
+		}
+		logger.V(2).Info("managedclusterset is annotated", "managedClusterSet", managedClusterSet.Name)
 	}
 
 	return nil
@@ -307,15 +535,31 @@ func (r *RegisteredClusterReconciler) getSyncTargetLabels(cluster clusterapiv1.M
 	return labels
 }
 
+// getSyncTargets returns the SyncTargets of the RegisteredCluster by location workspace,
+// they are resolved once per reconcile and the locations without SyncTarget are not in the map.
+func (r *RegisteredClusterReconciler) getSyncTargets(computeContext context.Context, regCluster *singaporev1alpha1.RegisteredCluster) (map[string]*unstructured.Unstructured, error) {
+	syncTargets := make(map[string]*unstructured.Unstructured, len(regCluster.Spec.Location))
+	for _, locationWorkspace := range regCluster.Spec.Location {
+		locationContext := logicalcluster.WithCluster(computeContext, logicalcluster.New(locationWorkspace))
+		syncTarget, err := r.getSyncTarget(locationContext, regCluster)
+		if err != nil {
+			return nil, giterrors.Wrapf(err, "location %s", locationWorkspace)
+		}
+		if syncTarget != nil {
+			syncTargets[locationWorkspace] = syncTarget
+		}
+	}
+	return syncTargets, nil
+}
+
+// getSyncTarget returns the SyncTarget of the RegisteredCluster in the location workspace from the cache,
+// nil if it doesn't exist.
 func (r *RegisteredClusterReconciler) getSyncTarget(locationContext context.Context, regCluster *singaporev1alpha1.RegisteredCluster) (*unstructured.Unstructured, error) {
 	logger := r.Log.WithName("getSyncTarget").WithValues("namespace", regCluster.Namespace, "name", regCluster.Name, "cluster", logicalcluster.From(regCluster).String())
 
-	labels := RegisteredClusterNamelabel + "=" + regCluster.Name + "," + RegisteredClusterNamespacelabel + "=" + regCluster.Namespace + "," + RegisteredClusterWorkspace + "=" + strings.ReplaceAll(logicalcluster.From(regCluster).String(), ":", "-") + "," + RegisteredClusterUidLabel + "=" + string(regCluster.UID)
-	syncTargetList, err := r.ComputeDynamicClient.Resource(syncTargetGVR).List(locationContext, metav1.ListOptions{
-		LabelSelector: labels,
-	})
-
-	if err != nil {
+	syncTargetList := &unstructured.UnstructuredList{}
+	syncTargetList.SetGroupVersionKind(syncTargetGVR.GroupVersion().WithKind("SyncTargetList"))
+	if err := r.Client.List(locationContext, syncTargetList, client.MatchingLabels(getSyncTargetSelector(regCluster))); err != nil {
 		r.Log.Error(err, "error getting SyncTarget list")
 		return nil, giterrors.WithStack(err)
 	}
@@ -327,34 +571,60 @@ func (r *RegisteredClusterReconciler) getSyncTarget(locationContext context.Cont
 
 	if len(syncTargetList.Items) == 0 {
 		return nil, nil
-		//k8serrors.NewNotFound(schema.GroupResource{Group: "workload.kcp.dev", Resource: "synctargets"}, "synctarget")
 	}
 	if len(syncTargetList.Items) > 1 {
-		logger.Error(err, "more than one synctarget found for registered cluster")
+		logger.Info("more than one synctarget found for registered cluster", "number", len(syncTargetList.Items))
 	}
 
 	return &syncTargetList.Items[0], nil
+}
 
+// getUncachedSyncTarget looks up in the location workspace a SyncTarget missing from the cache before creating it,
+// the SyncTarget created by the previous reconcile may not be in the cache yet.
+func (r *RegisteredClusterReconciler) getUncachedSyncTarget(locationContext context.Context, regCluster *singaporev1alpha1.RegisteredCluster) (*unstructured.Unstructured, error) {
+	syncTargetList, err := r.ComputeDynamicClient.Resource(syncTargetGVR).List(locationContext, metav1.ListOptions{
+		LabelSelector: k8slabels.SelectorFromSet(getSyncTargetSelector(regCluster)).String(),
+	})
+	if err != nil {
+		return nil, giterrors.WithStack(err)
+	}
+	if len(syncTargetList.Items) == 0 {
+		return nil, nil
+	}
+	syncTargetCacheMisses.Inc()
+	return &syncTargetList.Items[0], nil
 }
 
-func (r *RegisteredClusterReconciler) syncSyncTarget(computeContext context.Context, regCluster *singaporev1alpha1.RegisteredCluster, locationWorkspace string, managedCluster *clusterapiv1.ManagedCluster) error {
+// getSyncTargetSelector returns the labels identifying the SyncTargets of the RegisteredCluster
+func getSyncTargetSelector(regCluster *singaporev1alpha1.RegisteredCluster) map[string]string {
+	return map[string]string{
+		RegisteredClusterNamelabel:      regCluster.Name,
+		RegisteredClusterNamespacelabel: regCluster.Namespace,
+		RegisteredClusterWorkspace:      strings.ReplaceAll(logicalcluster.From(regCluster).String(), ":", "-"),
+		RegisteredClusterUidLabel:       string(regCluster.UID),
+	}
+}
+
+// syncSyncTarget creates or updates the SyncTarget of the location workspace and returns it
+func (r *RegisteredClusterReconciler) syncSyncTarget(computeContext context.Context,
+	regCluster *singaporev1alpha1.RegisteredCluster,
+	locationWorkspace string,
+	managedCluster *clusterapiv1.ManagedCluster,
+	syncTarget *unstructured.Unstructured) (*unstructured.Unstructured, error) {
 
 	logger := r.Log.WithName("syncSyncTarget").WithValues("namespace", regCluster.Namespace, "name", regCluster.Name, "managed cluster name", managedCluster.Name, "Location workspace", locationWorkspace)
 
 	locationContext := logicalcluster.WithCluster(computeContext, logicalcluster.New(locationWorkspace))
 
-	syncTarget, err := r.getSyncTarget(locationContext, regCluster)
-	if err != nil {
-		return giterrors.WithStack(err)
+	if syncTarget == nil {
+		var err error
+		if syncTarget, err = r.getUncachedSyncTarget(locationContext, regCluster); err != nil {
+			return nil, err
+		}
 	}
 
 	// Add labels to uniquely identify RegisteredCluster
-	labels := map[string]string{
-		RegisteredClusterNamelabel:      regCluster.Name,
-		RegisteredClusterNamespacelabel: regCluster.Namespace,
-		RegisteredClusterWorkspace:      strings.ReplaceAll(logicalcluster.From(regCluster).String(), ":", "-"),
-		RegisteredClusterUidLabel:       string(regCluster.UID),
-	}
+	labels := getSyncTargetSelector(regCluster)
 	// Copy the labels from the RegsiteredCluster
 	for k, v := range regCluster.Labels {
 		labels[k] = v
@@ -365,6 +635,8 @@ func (r *RegisteredClusterReconciler) syncSyncTarget(computeContext context.Cont
 		labels[k] = v
 	}
 
+	settings := getSyncTargetSettings(regCluster, locationWorkspace)
+
 	if syncTarget == nil {
 		syncTarget := &unstructured.Unstructured{
 			Object: map[string]interface{}{
@@ -373,34 +645,132 @@ func (r *RegisteredClusterReconciler) syncSyncTarget(computeContext context.Cont
 				"metadata": map[string]interface{}{
 					"generateName": regCluster.Name + "-",
 					"labels":       labels,
+					"annotations": map[string]interface{}{
+						ClusterNameAnnotation: logicalcluster.From(regCluster).String(),
+					},
 				},
 				"spec": map[string]interface{}{
 					"unschedulable": false,
 				},
 			},
 		}
+		if _, err := applySyncTargetSettings(syncTarget, settings, locationWorkspace); err != nil {
+			return nil, giterrors.WithStack(err)
+		}
 
-		if _, err := r.ComputeDynamicClient.Resource(syncTargetGVR).Create(locationContext, syncTarget, metav1.CreateOptions{}); err != nil {
-			return err
+		syncTarget, err := r.ComputeDynamicClient.Resource(syncTargetGVR).Create(locationContext, syncTarget, metav1.CreateOptions{})
+		if err != nil {
+			return nil, err
 		}
 		logger.V(2).Info("SyncTarget is created in the location workspace")
+		r.EventRecorder.Eventf(regCluster, corev1.EventTypeNormal, EventReasonSyncTargetCreated,
+			"SyncTarget %s created in the location workspace %s", syncTarget.GetName(), locationWorkspace)
+		return syncTarget, nil
 	} else {
+		syncTarget = syncTarget.DeepCopy()
 		// Update SyncTarget labels. Merge with existing labels found on SyncTarget since kcp adds some too
 		syncTargetLabels := syncTarget.GetLabels()
 		modified := mergeMap(&syncTargetLabels, labels)
-
 		if modified {
 			syncTarget.SetLabels(syncTargetLabels)
-			if _, err := r.ComputeDynamicClient.Resource(syncTargetGVR).Update(locationContext, syncTarget, metav1.UpdateOptions{}); err != nil {
-				return err
+		}
+		// The annotation maps the SyncTarget events back to the RegisteredCluster
+		syncTargetAnnotations := syncTarget.GetAnnotations()
+		if mergeMap(&syncTargetAnnotations, map[string]string{ClusterNameAnnotation: logicalcluster.From(regCluster).String()}) {
+			syncTarget.SetAnnotations(syncTargetAnnotations)
+			modified = true
+		}
+
+		specModified, err := applySyncTargetSettings(syncTarget, settings, locationWorkspace)
+		if err != nil {
+			return nil, giterrors.WithStack(err)
+		}
+
+		if modified || specModified {
+			if syncTarget, err = r.ComputeDynamicClient.Resource(syncTargetGVR).Update(locationContext, syncTarget, metav1.UpdateOptions{}); err != nil {
+				return nil, err
 			}
-			logger.V(2).Info("SyncTarget is updated in the location workspace")
+			logger.V(2).Info("SyncTarget is updated in the location workspace", "unschedulable", settings.Unschedulable)
 		} else {
 			r.Log.V(2).Info("no changes detected to SyncTarget", "labels", labels)
 		}
 	}
 
-	return nil
+	return syncTarget, nil
+}
+
+// getSyncTargetSettings returns the SyncTarget settings for the location workspace,
+// the location override takes precedence over spec.syncTarget
+func getSyncTargetSettings(regCluster *singaporev1alpha1.RegisteredCluster, locationWorkspace string) singaporev1alpha1.SyncTargetSettings {
+	for _, override := range regCluster.Spec.LocationOverrides {
+		if override.Location == locationWorkspace {
+			return override.SyncTarget
+		}
+	}
+	return regCluster.Spec.SyncTarget
+}
+
+// applySyncTargetSettings sets the SyncTarget spec according to the settings and returns true if the spec was modified
+func applySyncTargetSettings(syncTarget *unstructured.Unstructured, settings singaporev1alpha1.SyncTargetSettings, locationWorkspace string) (bool, error) {
+	modified := false
+
+	unschedulable, _, err := unstructured.NestedBool(syncTarget.Object, "spec", "unschedulable")
+	if err != nil {
+		return false, err
+	}
+	if unschedulable != settings.Unschedulable {
+		if err := unstructured.SetNestedField(syncTarget.Object, settings.Unschedulable, "spec", "unschedulable"); err != nil {
+			return false, err
+		}
+		modified = true
+	}
+
+	evictAfter, found, err := unstructured.NestedString(syncTarget.Object, "spec", "evictAfter")
+	if err != nil {
+		return false, err
+	}
+	switch {
+	case settings.EvictAfter == nil && found:
+		unstructured.RemoveNestedField(syncTarget.Object, "spec", "evictAfter")
+		modified = true
+	case settings.EvictAfter != nil:
+		desiredEvictAfter := settings.EvictAfter.UTC().Format(time.RFC3339)
+		if !found || evictAfter != desiredEvictAfter {
+			if err := unstructured.SetNestedField(syncTarget.Object, desiredEvictAfter, "spec", "evictAfter"); err != nil {
+				return false, err
+			}
+			modified = true
+		}
+	}
+
+	// The supported APIExports are left to kcp when not set on the RegisteredCluster
+	if len(settings.SupportedAPIExports) > 0 {
+		desiredExports := make([]interface{}, 0, len(settings.SupportedAPIExports))
+		for _, export := range settings.SupportedAPIExports {
+			path := export.Path
+			if len(path) == 0 {
+				path = locationWorkspace
+			}
+			desiredExports = append(desiredExports, map[string]interface{}{
+				"workspace": map[string]interface{}{
+					"path":       path,
+					"exportName": export.ExportName,
+				},
+			})
+		}
+		exports, _, err := unstructured.NestedSlice(syncTarget.Object, "spec", "supportedAPIExports")
+		if err != nil {
+			return false, err
+		}
+		if !equality.Semantic.DeepEqual(exports, desiredExports) {
+			if err := unstructured.SetNestedSlice(syncTarget.Object, desiredExports, "spec", "supportedAPIExports"); err != nil {
+				return false, err
+			}
+			modified = true
+		}
+	}
+
+	return modified, nil
 }
 
 // Adapted from openshift/library-go
@@ -430,11 +800,11 @@ func mergeMap(existing *map[string]string, required map[string]string) bool {
 	return modified
 }
 
-func (r *RegisteredClusterReconciler) updateRegisteredClusterStatus(computeContext context.Context, regCluster *singaporev1alpha1.RegisteredCluster, managedCluster *clusterapiv1.ManagedCluster) error {
+// updateRegisteredClusterStatus mirrors the ManagedCluster status in the RegisteredCluster status
+func (r *RegisteredClusterReconciler) updateRegisteredClusterStatus(regCluster *singaporev1alpha1.RegisteredCluster, managedCluster *clusterapiv1.ManagedCluster) {
 	r.Log.V(2).Info("updateRegisteredClusterStatus",
 		"regcluster", regCluster.Name,
 		"managedCluster", managedCluster.Name)
-	patch := client.MergeFrom(regCluster.DeepCopy())
 	if managedCluster.Status.Conditions != nil {
 		regCluster.Status.Conditions = helpers.MergeStatusConditions(regCluster.Status.Conditions, managedCluster.Status.Conditions...)
 	}
@@ -457,13 +827,7 @@ func (r *RegisteredClusterReconciler) updateRegisteredClusterStatus(computeConte
 		regCluster.Status.ClusterID = clusterID
 	}
 	r.Log.V(2).Info("updateRegisteredClusterStatus",
-		"patch", patch,
 		"regcluster", regCluster.Status)
-	if err := r.Client.Status().Patch(computeContext, regCluster, patch); err != nil {
-		return giterrors.WithStack(err)
-	}
-
-	return nil
 }
 
 func (r *RegisteredClusterReconciler) getHubCluster(ctx context.Context,
@@ -473,11 +837,35 @@ func (r *RegisteredClusterReconciler) getHubCluster(ctx context.Context,
 	// For now, we always assume there is only one hub cluster. //TODO Later we will replace this with a lookup.
 	log := ctrl.Log.WithName("GetHubCluster")
 	if len(hubInstances) == 0 {
-		return helpers.HubInstance{}, errors.New("hub cluster is not configured")
+		return helpers.HubInstance{}, helpers.NewConfigurationError(errors.New("hub cluster is not configured"))
+	}
+	// The hub is requested or must be the one managing the adopted managedcluster
+	if len(regCluster.Spec.HubName) != 0 || len(regCluster.Spec.ExistingManagedClusterName) != 0 {
+		hubInstance, err := r.getRequestedHubCluster(ctx, regCluster, hubInstances)
+		if err != nil {
+			return helpers.HubInstance{}, err
+		}
+		// A workspace is served by a single hub
+		for _, otherHubInstance := range hubInstances {
+			if otherHubInstance.HubConfig.Name == hubInstance.HubConfig.Name {
+				continue
+			}
+			if helpers.HasManagedClusterSetName(otherHubInstance, logicalcluster.From(regCluster).String()) {
+				return helpers.HubInstance{}, helpers.NewConfigurationError(fmt.Errorf("workspace %s is already assigned to hub %s",
+					logicalcluster.From(regCluster).String(),
+					otherHubInstance.HubConfig.Name))
+			}
+		}
+		log.V(2).Info("hub is selected for regCluster",
+			"namespace", regCluster.Namespace,
+			"name", regCluster.Name,
+			"hub", hubInstance.HubConfig.Name)
+		helpers.AddManagedClusterSetName(hubInstance, logicalcluster.From(regCluster).String())
+		return hubInstance, nil
 	}
 	// If ws already assigned to a hub
 	for _, hubInstance := range hubInstances {
-		if _, ok := hubInstance.ManagedClusterSetNames[helpers.ComputeWorkspaceName(logicalcluster.From(regCluster).String())]; ok {
+		if helpers.HasManagedClusterSetName(hubInstance, logicalcluster.From(regCluster).String()) {
 			log.V(2).Info("managedCluster already exists for regCluster",
 				"namespace", regCluster.Namespace,
 				"name", regCluster.Name,
@@ -486,6 +874,8 @@ func (r *RegisteredClusterReconciler) getHubCluster(ctx context.Context,
 		}
 	}
 	//Shuffle the hubInstances to not always select the first available one.
+	// The shuffle is done on a copy as the hubInstances are shared by the concurrent reconciles.
+	hubInstances = append([]helpers.HubInstance{}, hubInstances...)
 	rand.Seed(time.Now().UnixNano())
 	rand.Shuffle(len(hubInstances), func(i, j int) {
 		hubInstances[i], hubInstances[j] = hubInstances[j], hubInstances[i]
@@ -493,15 +883,15 @@ func (r *RegisteredClusterReconciler) getHubCluster(ctx context.Context,
 	for _, hubInstance := range hubInstances {
 		// Count the number of managedcluster to take the first hub which didn't maxout yet
 		// its number of managedcluster
-		managedClusterList := &clusterapiv1.ManagedClusterList{}
-		if err := hubInstance.Client.List(ctx, managedClusterList); err != nil {
+		managedClusters, err := helpers.CountManagedClusters(ctx, hubInstance)
+		if err != nil {
 			// Error reading the object - requeue the request.
-			return helpers.HubInstance{}, giterrors.WithStack(err)
+			return helpers.HubInstance{}, err
 		}
-		if len(managedClusterList.Items) < hubInstance.HubConfig.Spec.MaxManagedCluster {
+		if managedClusters < hubInstance.HubConfig.Spec.MaxManagedCluster {
 			log.V(2).Info(fmt.Sprintf("hub %s is selected as its number of managedclusters is %d/%d",
 				hubInstance.HubConfig.Name,
-				len(managedClusterList.Items),
+				managedClusters,
 				hubInstance.HubConfig.Spec.MaxManagedCluster))
 			helpers.AddManagedClusterSetName(hubInstance, logicalcluster.From(regCluster).String())
 			return hubInstance, nil
@@ -515,6 +905,33 @@ func (r *RegisteredClusterReconciler) getHubCluster(ctx context.Context,
 	return hubInstances[0], nil
 }
 
+// getRequestedHubCluster returns the hub named in the RegisteredCluster spec or, if no hub is named,
+// the hub managing the managedcluster to adopt.
+func (r *RegisteredClusterReconciler) getRequestedHubCluster(ctx context.Context,
+	regCluster *singaporev1alpha1.RegisteredCluster,
+	hubInstances []helpers.HubInstance) (helpers.HubInstance, error) {
+	for _, hubInstance := range hubInstances {
+		if len(regCluster.Spec.HubName) != 0 {
+			if hubInstance.HubConfig.Name == regCluster.Spec.HubName {
+				return hubInstance, nil
+			}
+			continue
+		}
+		managedCluster := &clusterapiv1.ManagedCluster{}
+		err := hubInstance.Client.Get(ctx, types.NamespacedName{Name: regCluster.Spec.ExistingManagedClusterName}, managedCluster)
+		switch {
+		case err == nil:
+			return hubInstance, nil
+		case !k8serrors.IsNotFound(err):
+			return helpers.HubInstance{}, giterrors.WithStack(err)
+		}
+	}
+	if len(regCluster.Spec.HubName) != 0 {
+		return helpers.HubInstance{}, helpers.NewConfigurationError(fmt.Errorf("hub %s is not configured", regCluster.Spec.HubName))
+	}
+	return helpers.HubInstance{}, helpers.NewConfigurationError(fmt.Errorf("managedcluster %s not found on any hub", regCluster.Spec.ExistingManagedClusterName))
+}
+
 func (r *RegisteredClusterReconciler) getManagedCluster(ctx context.Context, regCluster *singaporev1alpha1.RegisteredCluster, hubCluster *helpers.HubInstance, clusterName string) (clusterapiv1.ManagedCluster, error) {
 	managedCluster := clusterapiv1.ManagedCluster{}
 	managedClusterSetList, err := r.getManagedClusterSetList(ctx, hubCluster, regCluster)
@@ -533,7 +950,9 @@ func (r *RegisteredClusterReconciler) getManagedCluster(ctx context.Context, reg
 	mcsName := managedClusterSetList.Items[0].Name
 
 	managedClusterList := &clusterapiv1.ManagedClusterList{}
-	if err := hubCluster.Client.List(ctx, managedClusterList, client.MatchingLabels(getRegisteredClusterLabels(regCluster, mcsName))); err != nil {
+	if err := hubCluster.Client.List(ctx, managedClusterList,
+		client.MatchingFields{managedClusterRegisteredClusterIndex: string(regCluster.UID)},
+		client.MatchingLabels(getRegisteredClusterLabels(regCluster, mcsName))); err != nil {
 		// Error reading the object - requeue the request.
 		return managedCluster, giterrors.WithStack(err)
 	}
@@ -601,15 +1020,21 @@ func (r *RegisteredClusterReconciler) updateImportCommand(computeContext context
 	importCommand := "echo \"" + strings.TrimSpace(string(crdsv1Yaml)) + "\" | base64 --decode | kubectl apply -f - && sleep 2 && echo \"" + strings.TrimSpace(string(importYaml)) + "\" | base64 --decode | kubectl apply -f -"
 
 	values := struct {
-		Name          string
-		Namespace     string
-		ImportCommand string
-		ClusterName   string
+		Name                       string
+		Namespace                  string
+		ImportCommand              string
+		ClusterName                string
+		UID                        string
+		RegisteredClusterNameLabel string
+		RegisteredClusterUidLabel  string
 	}{
-		Name:          regCluster.Name,
-		Namespace:     regCluster.Namespace,
-		ImportCommand: importCommand,
-		ClusterName:   logicalcluster.From(regCluster).String(),
+		Name:                       regCluster.Name,
+		Namespace:                  regCluster.Namespace,
+		ImportCommand:              importCommand,
+		ClusterName:                logicalcluster.From(regCluster).String(),
+		UID:                        string(regCluster.UID),
+		RegisteredClusterNameLabel: RegisteredClusterNamelabel,
+		RegisteredClusterUidLabel:  RegisteredClusterUidLabel,
 	}
 
 	r.Log.V(2).Info("create secret on compute",
@@ -622,15 +1047,16 @@ func (r *RegisteredClusterReconciler) updateImportCommand(computeContext context
 		return giterrors.WithStack(err)
 	}
 
-	r.Log.V(2).Info("patch registeredCluster on compute with import secret",
+	r.Log.V(2).Info("set the import secret in the registeredCluster status",
 		"namespace", regCluster.Namespace,
 		"name", regCluster.Name)
-	patch := client.MergeFrom(regCluster.DeepCopy())
+	importCommandReady := len(regCluster.Status.ImportCommandRef.Name) == 0
 	regCluster.Status.ImportCommandRef = corev1.LocalObjectReference{
 		Name: regCluster.Name + "-import",
 	}
-	if err := r.Client.Status().Patch(computeContext, regCluster, patch); err != nil {
-		return giterrors.WithStack(err)
+	if importCommandReady {
+		r.EventRecorder.Eventf(regCluster, corev1.EventTypeNormal, EventReasonImportCommandReady,
+			"Import command available in the secret %s", regCluster.Status.ImportCommandRef.Name)
 	}
 
 	return nil
@@ -641,17 +1067,14 @@ func (r *RegisteredClusterReconciler) syncServiceAccount(computeContext context.
 	regCluster *singaporev1alpha1.RegisteredCluster,
 	locationWorkspace string,
 	managedCluster *clusterapiv1.ManagedCluster,
-	hubCluster *helpers.HubInstance) (*corev1.ServiceAccount, error) {
+	hubCluster *helpers.HubInstance,
+	syncTarget *unstructured.Unstructured) (*corev1.ServiceAccount, error) {
 
 	r.Log.V(2).Info("syncServiceAccount",
 		"registered cluster", regCluster.Name,
 		"location", regCluster.Spec.Location)
 
 	locationContext := logicalcluster.WithCluster(computeContext, logicalcluster.New(locationWorkspace))
-	syncTarget, err := r.getSyncTarget(locationContext, regCluster)
-	if err != nil {
-		return nil, err
-	}
 
 	// Create the ServiceAccount if it doesn't yet exist
 	if syncTarget == nil {
@@ -667,7 +1090,9 @@ func (r *RegisteredClusterReconciler) syncServiceAccount(computeContext context.
 
 		sa = &corev1.ServiceAccount{
 			ObjectMeta: metav1.ObjectMeta{
-				Name: saName,
+				Name:        saName,
+				Labels:      getServiceAccountLabels(regCluster),
+				Annotations: map[string]string{ClusterNameAnnotation: logicalcluster.From(regCluster).String()},
 				OwnerReferences: []metav1.OwnerReference{
 					{
 						APIVersion: syncTarget.GetAPIVersion(),
@@ -684,6 +1109,20 @@ func (r *RegisteredClusterReconciler) syncServiceAccount(computeContext context.
 		if err != nil {
 			return nil, err
 		}
+	} else {
+		// Label the ServiceAccounts created before they were watched
+		saLabels := sa.GetLabels()
+		modified := mergeMap(&saLabels, getServiceAccountLabels(regCluster))
+		saAnnotations := sa.GetAnnotations()
+		modified = mergeMap(&saAnnotations, map[string]string{ClusterNameAnnotation: logicalcluster.From(regCluster).String()}) || modified
+		if modified {
+			sa.SetLabels(saLabels)
+			sa.SetAnnotations(saAnnotations)
+			sa, err = r.ComputeKubeClient.CoreV1().ServiceAccounts("default").Update(locationContext, sa, metav1.UpdateOptions{})
+			if err != nil {
+				return nil, err
+			}
+		}
 	}
 
 	// Sync the ClusterRole and ClusterRoleBinding
@@ -725,16 +1164,12 @@ func (r *RegisteredClusterReconciler) syncServiceAccount(computeContext context.
 	return sa, nil
 }
 
-func (r *RegisteredClusterReconciler) getKcpSyncerSAToken(computeContext context.Context, regCluster *singaporev1alpha1.RegisteredCluster, locationWorkspace string, sa *corev1.ServiceAccount) (string, error) {
+func (r *RegisteredClusterReconciler) getKcpSyncerSAToken(computeContext context.Context, regCluster *singaporev1alpha1.RegisteredCluster, locationWorkspace string, syncTarget *unstructured.Unstructured, sa *corev1.ServiceAccount) (string, error) {
 
 	r.Log.V(2).Info("getKcpSyncerSAToken",
 		"service account", sa.Name)
 
 	locationContext := logicalcluster.WithCluster(computeContext, logicalcluster.New(locationWorkspace))
-	syncTarget, err := r.getSyncTarget(locationContext, regCluster)
-	if err != nil {
-		return "", err
-	}
 
 	if syncTarget == nil {
 		return "", fmt.Errorf("failed to get kcp syncer name. Synctarget not exists")
@@ -752,7 +1187,7 @@ func (r *RegisteredClusterReconciler) getKcpSyncerSAToken(computeContext context
 
 		secret, err := r.ComputeKubeClient.CoreV1().Secrets("default").Get(locationContext, secretRef.Name, metav1.GetOptions{})
 		if err != nil {
-			r.Log.Error(err,
+			r.Log.Error(err, "failed to read secret",
 				"secret", secretRef.Name)
 			continue
 		}
@@ -776,6 +1211,20 @@ func (r *RegisteredClusterReconciler) getKcpSyncerSAToken(computeContext context
 			continue
 		}
 
+		// Label the token secret so its deletion is mapped to the RegisteredCluster
+		secretLabels := secret.GetLabels()
+		modified := mergeMap(&secretLabels, getServiceAccountLabels(regCluster))
+		secretAnnotations := secret.GetAnnotations()
+		modified = mergeMap(&secretAnnotations, map[string]string{ClusterNameAnnotation: logicalcluster.From(regCluster).String()}) || modified
+		if modified {
+			secret.SetLabels(secretLabels)
+			secret.SetAnnotations(secretAnnotations)
+			if _, err := r.ComputeKubeClient.CoreV1().Secrets("default").Update(locationContext, secret, metav1.UpdateOptions{}); err != nil {
+				return "", giterrors.WithStack(err)
+			}
+		}
+
+		registeredClusterMetrics.setSyncerTokenCreation(regCluster, locationWorkspace, secret.CreationTimestamp.Time)
 		return string(token), nil
 	}
 
@@ -790,7 +1239,8 @@ func getSyncerImage() string {
 	return defaultSyncerImage
 }
 
-func (r *RegisteredClusterReconciler) syncKcpSyncer(computeContext context.Context, ctx context.Context, regCluster *singaporev1alpha1.RegisteredCluster, locationWorkspace string, managedCluster *clusterapiv1.ManagedCluster, hubCluster *helpers.HubInstance, token string) error {
+// syncKcpSyncer applies the kcp-syncer ManifestWork of the location and returns true once it is applied on the managedcluster
+func (r *RegisteredClusterReconciler) syncKcpSyncer(computeContext context.Context, ctx context.Context, regCluster *singaporev1alpha1.RegisteredCluster, locationWorkspace string, managedCluster *clusterapiv1.ManagedCluster, hubCluster *helpers.HubInstance, syncTarget *unstructured.Unstructured, token string) (bool, error) {
 	logger := r.Log.WithName("syncKcpSyncer").WithValues("namespace", regCluster.Namespace, "name", regCluster.Name, "managed cluster name", managedCluster.Name)
 
 	// If cluster has joined, sync the ManifestWork to create the kcp-syncer deployment and supporting resources
@@ -800,20 +1250,14 @@ func (r *RegisteredClusterReconciler) syncKcpSyncer(computeContext context.Conte
 
 		applier := hubCluster.ApplierBuilder.Build()
 
-		locationContext := logicalcluster.WithCluster(computeContext, logicalcluster.New(locationWorkspace))
-		syncTarget, err := r.getSyncTarget(locationContext, regCluster)
-		if err != nil {
-			return err
-		}
-
 		if syncTarget == nil {
-			return fmt.Errorf("failed to get syncer name. Synctarget not exists")
+			return false, fmt.Errorf("failed to get syncer name. Synctarget not exists")
 		}
 		syncerName := helpers.GetSyncerName(syncTarget)
 
 		kcpURL, err := url.Parse(r.ComputeConfig.Host)
 		if err != nil {
-			return err
+			return false, err
 		}
 
 		logger.V(2).Info("syncKcpSyncer", "url path", kcpURL.Path)
@@ -828,8 +1272,10 @@ func (r *RegisteredClusterReconciler) syncKcpSyncer(computeContext context.Conte
 			ManagedClusterName              string
 			RegisteredClusterNameLabel      string
 			RegisteredClusterNamespaceLabel string
+			RegisteredClusterUidLabel       string
 			RegisteredClusterName           string
 			RegisteredClusterNamespace      string
+			RegisteredClusterUid            string
 			ClusterNameAnnotation           string
 			RegisteredClusterClusterName    string
 			LogicalClusterLabel             string
@@ -844,8 +1290,10 @@ func (r *RegisteredClusterReconciler) syncKcpSyncer(computeContext context.Conte
 			ManagedClusterName:              managedCluster.Name,
 			RegisteredClusterNameLabel:      RegisteredClusterNamelabel,
 			RegisteredClusterNamespaceLabel: RegisteredClusterNamespacelabel,
+			RegisteredClusterUidLabel:       RegisteredClusterUidLabel,
 			RegisteredClusterName:           regCluster.Name,
 			RegisteredClusterNamespace:      regCluster.Namespace,
+			RegisteredClusterUid:            string(regCluster.UID),
 			ClusterNameAnnotation:           ClusterNameAnnotation,
 			RegisteredClusterClusterName:    managedCluster.Annotations[ClusterNameAnnotation],
 			LogicalCluster:                  locationWorkspace,
@@ -859,30 +1307,50 @@ func (r *RegisteredClusterReconciler) syncKcpSyncer(computeContext context.Conte
 			"cluster-registration/kcp_syncer_manifestwork.yaml",
 		}
 
+		work := &manifestworkv1.ManifestWork{}
+		err = hubCluster.Client.Get(ctx,
+			types.NamespacedName{Name: values.KcpSyncerName, Namespace: managedCluster.Name},
+			work)
+		if err != nil && !k8serrors.IsNotFound(err) {
+			return false, giterrors.WithStack(err)
+		}
+		created := k8serrors.IsNotFound(err)
+
 		_, err = applier.ApplyCustomResources(readerDeploy, values, false, "", files...)
 		if err != nil {
-			return giterrors.WithStack(err)
+			return false, giterrors.WithStack(err)
+		}
+		if created {
+			r.EventRecorder.Eventf(regCluster, corev1.EventTypeNormal, EventReasonSyncerDeploying,
+				"kcp-syncer ManifestWork %s created for the location workspace %s", values.KcpSyncerName, locationWorkspace)
 		}
-
-		work := &manifestworkv1.ManifestWork{}
 
 		err = hubCluster.Client.Get(ctx,
 			types.NamespacedName{Name: values.KcpSyncerName, Namespace: managedCluster.Name},
 			work)
 
 		if err != nil {
-			return giterrors.WithStack(err)
+			return false, giterrors.WithStack(err)
 		}
 
-		if status, ok := helpers.GetConditionStatus(work.Status.Conditions, string(manifestworkv1.ManifestApplied)); ok && status == metav1.ConditionTrue {
-			logger.V(1).Info("manifestwork applied. TODO: update status...")
-			//TODO - update status
+		if applied := meta.FindStatusCondition(work.Status.Conditions, string(manifestworkv1.ManifestApplied)); applied != nil && applied.Status == metav1.ConditionTrue {
+			logger.V(1).Info("manifestwork applied")
+			if registeredClusterMetrics.observeSyncerReady(regCluster, work.UID, applied.LastTransitionTime.Time, hubCluster.HubConfig.Name) {
+				r.EventRecorder.Eventf(regCluster, corev1.EventTypeNormal, EventReasonSyncerDeployed,
+					"kcp-syncer deployed for the location workspace %s", locationWorkspace)
+			}
+			return true, nil
 		}
 	}
-	return nil
+	return false, nil
 }
 
-func (r *RegisteredClusterReconciler) processRegclusterDeletion(ctx context.Context, regCluster *singaporev1alpha1.RegisteredCluster, managedCluster *clusterapiv1.ManagedCluster, hubCluster *helpers.HubInstance) (ctrl.Result, error) {
+func (r *RegisteredClusterReconciler) processRegclusterDeletion(computeContext context.Context,
+	ctx context.Context,
+	regCluster *singaporev1alpha1.RegisteredCluster,
+	managedCluster *clusterapiv1.ManagedCluster,
+	hubCluster *helpers.HubInstance,
+	syncTargets map[string]*unstructured.Unstructured) (ctrl.Result, error) {
 
 	if len(regCluster.Spec.Location) > 0 {
 		var syncerName string
@@ -890,77 +1358,203 @@ func (r *RegisteredClusterReconciler) processRegclusterDeletion(ctx context.Cont
 		for _, locationWorkspace := range regCluster.Spec.Location {
 
 			locationContext := logicalcluster.WithCluster(ctx, logicalcluster.New(locationWorkspace))
-			syncTarget, err := r.getSyncTarget(locationContext, regCluster)
-			if err != nil {
-				return ctrl.Result{}, giterrors.WithStack(err)
-			}
-
-			if syncTarget != nil {
+			if syncTarget, ok := syncTargets[locationWorkspace]; ok {
 				syncerName = helpers.GetSyncerName(syncTarget)
 				synctargetName = syncTarget.GetName()
 
-				manifestwork := &manifestworkv1.ManifestWork{}
-				err = hubCluster.Client.Get(ctx,
-					types.NamespacedName{
-						Name:      syncerName,
-						Namespace: managedCluster.Name},
-					manifestwork)
-				switch {
-				case err == nil:
-					r.Log.Info("delete manifestwork", "name", syncerName)
-					if err := hubCluster.Client.Delete(ctx, manifestwork); err != nil {
+				if !skipCleanupStep(regCluster, CleanupStepManifestWork) {
+					r.updateDeletionPhase(regCluster, singaporev1alpha1.DeletionPhaseSyncerManifestWork)
+					manifestwork := &manifestworkv1.ManifestWork{}
+					err := hubCluster.Client.Get(ctx,
+						types.NamespacedName{
+							Name:      syncerName,
+							Namespace: managedCluster.Name},
+						manifestwork)
+					switch {
+					case err == nil:
+						r.Log.Info("delete manifestwork", "name", syncerName)
+						if err := hubCluster.Client.Delete(ctx, manifestwork); err != nil {
+							return ctrl.Result{}, giterrors.WithStack(err)
+						}
+						r.Log.Info("waiting manifestwork to be deleted",
+							"name", syncerName,
+							"namespace", managedCluster.Name)
+						return ctrl.Result{Requeue: true, RequeueAfter: 1 * time.Second}, nil
+					case !k8serrors.IsNotFound(err):
+
 						return ctrl.Result{}, giterrors.WithStack(err)
 					}
-					r.Log.Info("waiting manifestwork to be deleted",
-						"name", syncerName,
-						"namespace", managedCluster.Name)
-					return ctrl.Result{Requeue: true, RequeueAfter: 1 * time.Second}, nil
-				case !k8serrors.IsNotFound(err):
-
-					return ctrl.Result{}, giterrors.WithStack(err)
+					r.Log.Info("deleted manifestwork", "name", syncerName)
 				}
-				r.Log.Info("deleted manifestwork", "name", syncerName)
 
-				r.Log.Info("delete service account", "name", syncerName)
-				_, err = r.ComputeKubeClient.CoreV1().ServiceAccounts("default").Get(locationContext, syncerName, metav1.GetOptions{})
-				switch {
-				case err == nil:
+				if !skipCleanupStep(regCluster, CleanupStepServiceAccount) {
+					r.updateDeletionPhase(regCluster, singaporev1alpha1.DeletionPhaseSyncerServiceAccount)
 					r.Log.Info("delete service account", "name", syncerName)
-					if err := r.ComputeKubeClient.CoreV1().ServiceAccounts("default").Delete(locationContext, syncerName, metav1.DeleteOptions{}); err != nil {
+					_, err := r.ComputeKubeClient.CoreV1().ServiceAccounts("default").Get(locationContext, syncerName, metav1.GetOptions{})
+					switch {
+					case err == nil:
+						r.Log.Info("delete service account", "name", syncerName)
+						if err := r.ComputeKubeClient.CoreV1().ServiceAccounts("default").Delete(locationContext, syncerName, metav1.DeleteOptions{}); err != nil {
+							return ctrl.Result{}, giterrors.WithStack(err)
+						}
+						r.Log.Info("waiting service account to be deleted",
+							"name", syncerName,
+							"namespace", "default")
+						return ctrl.Result{Requeue: true, RequeueAfter: 1 * time.Second}, nil
+					case !k8serrors.IsNotFound(err):
 						return ctrl.Result{}, giterrors.WithStack(err)
 					}
-					r.Log.Info("waiting service account to be deleted",
-						"name", syncerName,
-						"namespace", "default")
-					return ctrl.Result{Requeue: true, RequeueAfter: 1 * time.Second}, nil
-				case !k8serrors.IsNotFound(err):
-					return ctrl.Result{}, giterrors.WithStack(err)
+					r.Log.Info("deleted service account", "name", syncerName)
 				}
-				r.Log.Info("deleted service account", "name", syncerName)
 
-				syncTarget, err := r.getSyncTarget(locationContext, regCluster)
-				if syncTarget != nil && err == nil {
+				if !skipCleanupStep(regCluster, CleanupStepSyncTarget) {
+					r.updateDeletionPhase(regCluster, singaporev1alpha1.DeletionPhaseSyncTarget)
+					// The cache may still hold a SyncTarget which is already deleted
 					r.Log.Info("delete synctarget", "name", synctargetName)
-					if err := r.ComputeDynamicClient.Resource(syncTargetGVR).Delete(locationContext, synctargetName, metav1.DeleteOptions{}); err != nil {
+					err := r.ComputeDynamicClient.Resource(syncTargetGVR).Delete(locationContext, synctargetName, metav1.DeleteOptions{})
+					switch {
+					case err == nil:
+						r.Log.Info("waiting synctarget to be deleted",
+							"name", synctargetName,
+							"location workspace", locationWorkspace)
+						return ctrl.Result{Requeue: true, RequeueAfter: 1 * time.Second}, nil
+					case !k8serrors.IsNotFound(err):
 						return ctrl.Result{}, giterrors.WithStack(err)
 					}
-					r.Log.Info("waiting synctarget to be deleted",
-						"name", synctargetName,
-						"location workspace", locationWorkspace)
-					return ctrl.Result{Requeue: true, RequeueAfter: 1 * time.Second}, nil
+					r.Log.Info("deleted synctarget", "name", synctargetName)
 				}
-				if err != nil {
-					return ctrl.Result{}, giterrors.WithStack(err)
-				}
-				r.Log.Info("deleted synctarget", "name", synctargetName)
 
 			}
 
 		}
 	}
 
-	// TODO - remaining cleanup - https://issues.redhat.com/browse/CMCS-145
-	// delete managedcluster
+	// ManifestWorks of locations which were removed from the spec
+	if !skipCleanupStep(regCluster, CleanupStepManifestWork) {
+		r.updateDeletionPhase(regCluster, singaporev1alpha1.DeletionPhaseSyncerManifestWork)
+		if r, err := r.deleteManifestWorks(ctx, regCluster, hubCluster); err != nil || r.Requeue {
+			return r, err
+		}
+	}
+
+	if !skipCleanupStep(regCluster, CleanupStepManagedCluster) {
+		r.updateDeletionPhase(regCluster, singaporev1alpha1.DeletionPhaseManagedCluster)
+		// a detached managedcluster is released and stays on the hub
+		if r.getDeletionPolicy(regCluster, managedCluster) == singaporev1alpha1.DeletionPolicyDetach {
+			if err := r.releaseManagedCluster(ctx, managedCluster, hubCluster); err != nil {
+				return ctrl.Result{}, err
+			}
+		} else {
+			if r, err := r.deleteManagedCluster(ctx, managedCluster, hubCluster); err != nil || r.Requeue {
+				return r, err
+			}
+		}
+	}
+
+	if !skipCleanupStep(regCluster, CleanupStepImportSecret) {
+		r.updateDeletionPhase(regCluster, singaporev1alpha1.DeletionPhaseImportSecret)
+		if err := r.deleteImportSecret(computeContext, regCluster); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	if skipCleanupStep(regCluster, CleanupStepManagedClusterSet) {
+		// The orphan scan must keep the managedclusterset too
+		return ctrl.Result{}, r.skipManagedClusterSetCleanup(ctx, hubCluster, regCluster)
+	}
+	r.updateDeletionPhase(regCluster, singaporev1alpha1.DeletionPhaseManagedClusterSet)
+
+	// The managedclusterset is shared by all RegisteredClusters of the workspace
+	lastRegisteredCluster, err := r.isLastRegisteredCluster(computeContext, regCluster)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if !lastRegisteredCluster {
+		return ctrl.Result{}, nil
+	}
+
+	managedClusterSetList, err := r.getManagedClusterSetList(ctx, hubCluster, regCluster)
+	if err != nil {
+		return ctrl.Result{}, giterrors.WithStack(err)
+	}
+
+	for i := range managedClusterSetList.Items {
+		if r, err := r.deleteManagedClusterSet(ctx, hubCluster, &managedClusterSetList.Items[i]); err != nil || r.Requeue {
+			return r, err
+		}
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// updateDeletionPhase records the cleanup step in progress in the RegisteredCluster status
+func (r *RegisteredClusterReconciler) updateDeletionPhase(regCluster *singaporev1alpha1.RegisteredCluster, phase singaporev1alpha1.DeletionPhase) {
+	if regCluster.Status.DeletionPhase == phase {
+		return
+	}
+	regCluster.Status.DeletionPhase = phase
+	r.EventRecorder.Event(regCluster, corev1.EventTypeNormal, string(phase), deletionPhaseMessages[phase])
+}
+
+// skipCleanupStep returns true if the cleanup step is listed in the skip-cleanup annotation of the RegisteredCluster
+func skipCleanupStep(regCluster *singaporev1alpha1.RegisteredCluster, step string) bool {
+	return isCleanupStepSkipped(regCluster.GetAnnotations(), step)
+}
+
+// isCleanupStepSkipped returns true if the cleanup step is listed in the skip-cleanup annotation
+func isCleanupStepSkipped(annotations map[string]string, step string) bool {
+	skipCleanup, ok := annotations[SkipCleanupAnnotation]
+	if !ok {
+		return false
+	}
+	for _, skippedStep := range strings.Split(skipCleanup, ",") {
+		skippedStep = strings.TrimSpace(skippedStep)
+		if skippedStep == step || skippedStep == CleanupStepAll {
+			return true
+		}
+	}
+	return false
+}
+
+// isDeletionTimedOut returns true if the RegisteredCluster deletion started for longer than the deletion timeout
+func (r *RegisteredClusterReconciler) isDeletionTimedOut(regCluster *singaporev1alpha1.RegisteredCluster) bool {
+	if r.DeletionTimeout <= 0 || regCluster.DeletionTimestamp == nil {
+		return false
+	}
+	return time.Since(regCluster.DeletionTimestamp.Time) > r.DeletionTimeout
+}
+
+// forceRegclusterDeletion removes the finalizer of a RegisteredCluster whose cleanup didn't complete before the deletion timeout
+func (r *RegisteredClusterReconciler) forceRegclusterDeletion(computeContext context.Context, regCluster *singaporev1alpha1.RegisteredCluster) (ctrl.Result, error) {
+	message := fmt.Sprintf("cleanup not completed after %s in phase %q, resources may remain on the hub and in the location workspaces",
+		r.DeletionTimeout, regCluster.Status.DeletionPhase)
+	r.Log.Info("force registeredcluster deletion",
+		"namespace", regCluster.Namespace,
+		"name", regCluster.Name,
+		"phase", regCluster.Status.DeletionPhase)
+
+	patch := client.MergeFrom(regCluster.DeepCopy())
+	meta.SetStatusCondition(&regCluster.Status.Conditions, metav1.Condition{
+		Type:    singaporev1alpha1.RegisteredClusterConditionForceDeleted,
+		Status:  metav1.ConditionTrue,
+		Reason:  "DeletionTimeout",
+		Message: message,
+	})
+	if err := r.Client.Status().Patch(computeContext, regCluster, patch); err != nil {
+		return ctrl.Result{}, giterrors.WithStack(err)
+	}
+	r.EventRecorder.Event(regCluster, corev1.EventTypeWarning, singaporev1alpha1.RegisteredClusterConditionForceDeleted, message)
+
+	controllerutil.RemoveFinalizer(regCluster, helpers.RegisteredClusterFinalizer)
+	if err := r.Client.Update(computeContext, regCluster); err != nil {
+		return ctrl.Result{}, giterrors.WithStack(err)
+	}
+	observeDeletion(regCluster, deletionResultForced)
+	registeredClusterMetrics.forget(newRegisteredClusterKey(regCluster))
+	return ctrl.Result{}, nil
+}
+
+func (r *RegisteredClusterReconciler) deleteManagedCluster(ctx context.Context, managedCluster *clusterapiv1.ManagedCluster, hubCluster *helpers.HubInstance) (ctrl.Result, error) {
 	cluster := &clusterapiv1.ManagedCluster{}
 	err := hubCluster.Client.Get(ctx,
 		types.NamespacedName{
@@ -979,35 +1573,77 @@ func (r *RegisteredClusterReconciler) processRegclusterDeletion(ctx context.Cont
 		return ctrl.Result{}, giterrors.WithStack(err)
 	}
 	r.Log.Info("deleted managedcluster", "name", managedCluster.Name)
+	return ctrl.Result{}, nil
+}
 
-	managedClusterSetList, err := r.getManagedClusterSetList(ctx, hubCluster, regCluster)
-	if err != nil {
-		return ctrl.Result{}, giterrors.WithStack(err)
+// releaseManagedCluster removes the RegisteredCluster labels and annotations from an adopted managedcluster,
+// the managedcluster stays on the hub.
+func (r *RegisteredClusterReconciler) releaseManagedCluster(ctx context.Context, managedCluster *clusterapiv1.ManagedCluster, hubCluster *helpers.HubInstance) error {
+	cluster := &clusterapiv1.ManagedCluster{}
+	err := hubCluster.Client.Get(ctx,
+		types.NamespacedName{
+			Name: managedCluster.Name},
+		cluster)
+	switch {
+	case k8serrors.IsNotFound(err):
+		return nil
+	case err != nil:
+		return giterrors.WithStack(err)
 	}
 
-	// delete managedclusterset
-	if len(managedClusterSetList.Items) > 0 {
-		if status, ok := helpers.GetConditionStatus(managedClusterSetList.Items[0].Status.Conditions, clusterapiv1beta1.ManagedClusterSetConditionEmpty); ok && status == metav1.ConditionTrue {
-
-			mcsName := managedClusterSetList.Items[0].Name
-			switch {
-			case err == nil:
-				r.Log.Info("delete managedclusterset", "name", mcsName)
-				if err := hubCluster.Client.Delete(ctx, &managedClusterSetList.Items[0]); err != nil {
-					return ctrl.Result{}, giterrors.WithStack(err)
-				}
-				r.Log.Info("waiting managedclusterset to be deleted",
-					"name", mcsName)
-				return ctrl.Result{Requeue: true, RequeueAfter: 5 * time.Second}, nil
-			case !k8serrors.IsNotFound(err):
-				return ctrl.Result{}, giterrors.WithStack(err)
-			}
-			r.Log.Info("deleted managedclusterset", "name", mcsName)
+	patch := client.MergeFrom(cluster.DeepCopy())
+	labels := cluster.GetLabels()
+	for _, label := range []string{
+		RegisteredClusterNamelabel,
+		RegisteredClusterNamespacelabel,
+		RegisteredClusterUidLabel,
+		ManagedClusterSetlabel,
+	} {
+		delete(labels, label)
+	}
+	// An adopted managedcluster goes back to its original managedclusterset
+	annotations := cluster.GetAnnotations()
+	if clusterSet := annotations[OriginalClusterSetAnnotation]; len(clusterSet) != 0 {
+		if labels == nil {
+			labels = make(map[string]string)
 		}
-		return ctrl.Result{}, nil
+		labels[ManagedClusterSetlabel] = clusterSet
 	}
+	cluster.SetLabels(labels)
+	delete(annotations, ClusterNameAnnotation)
+	delete(annotations, AdoptedManagedClusterAnnotation)
+	delete(annotations, OriginalClusterSetAnnotation)
+	cluster.SetAnnotations(annotations)
 
-	return ctrl.Result{}, nil
+	r.Log.Info("release managedcluster", "name", cluster.Name)
+	if err := hubCluster.Client.Patch(ctx, cluster, patch); err != nil {
+		return giterrors.WithStack(err)
+	}
+	return nil
+}
+
+// getDeletionPolicy returns the deletion policy of the RegisteredCluster, if not set an adopted managedcluster
+// is detached as it was not created by the RegisteredCluster and otherwise the ClusterRegistrar default is used.
+func (r *RegisteredClusterReconciler) getDeletionPolicy(regCluster *singaporev1alpha1.RegisteredCluster, managedCluster *clusterapiv1.ManagedCluster) singaporev1alpha1.DeletionPolicy {
+	switch {
+	case len(regCluster.Spec.DeletionPolicy) != 0:
+		return regCluster.Spec.DeletionPolicy
+	case isAdoptedManagedCluster(managedCluster):
+		return singaporev1alpha1.DeletionPolicyDetach
+	case len(r.DefaultDeletionPolicy) != 0:
+		return r.DefaultDeletionPolicy
+	}
+	return singaporev1alpha1.DeletionPolicyDelete
+}
+
+func isAdoptedManagedCluster(managedCluster *clusterapiv1.ManagedCluster) bool {
+	_, ok := managedCluster.GetAnnotations()[AdoptedManagedClusterAnnotation]
+	return ok
+}
+
+func isManagedClusterJoined(managedCluster *clusterapiv1.ManagedCluster) bool {
+	status, ok := helpers.GetConditionStatus(managedCluster.Status.Conditions, clusterapiv1.ManagedClusterConditionJoined)
+	return ok && status == metav1.ConditionTrue
 }
 
 func getRegisteredClusterLabels(regCluster *singaporev1alpha1.RegisteredCluster, mcsName string) map[string]string {
@@ -1038,11 +1674,17 @@ func (r *RegisteredClusterReconciler) createManagedCluster(ctx context.Context,
 	managedClusterList := &clusterapiv1.ManagedClusterList{}
 	labels := getRegisteredClusterLabels(regCluster, mcsName)
 	logger.V(2).Info("get managedclusterlist", "labels", labels)
-	if err := hubCluster.Client.List(ctx, managedClusterList, client.MatchingLabels(labels)); err != nil {
+	if err := hubCluster.Client.List(ctx, managedClusterList,
+		client.MatchingFields{managedClusterRegisteredClusterIndex: string(regCluster.UID)},
+		client.MatchingLabels(labels)); err != nil {
 		// Error reading the object - requeue the request.
 		return ctrl.Result{}, giterrors.WithStack(err)
 	}
 
+	if len(managedClusterList.Items) < 1 && len(regCluster.Spec.ExistingManagedClusterName) != 0 {
+		return ctrl.Result{}, r.adoptManagedCluster(ctx, regCluster, hubCluster, clusterName, labels)
+	}
+
 	if len(managedClusterList.Items) < 1 {
 		managedCluster := &clusterapiv1.ManagedCluster{
 			TypeMeta: metav1.TypeMeta{
@@ -1066,10 +1708,81 @@ func (r *RegisteredClusterReconciler) createManagedCluster(ctx context.Context,
 			return ctrl.Result{}, giterrors.WithStack(err)
 		}
 		logger.V(2).Info("managedclusterset is created")
+		r.EventRecorder.Eventf(regCluster, corev1.EventTypeNormal, EventReasonManagedClusterCreated,
+			"ManagedCluster %s created on the hub %s", managedCluster.Name, hubCluster.HubConfig.Name)
 	}
 	return ctrl.Result{}, nil
 }
 
+// adoptManagedCluster labels the existing managedcluster as the managedcluster of the RegisteredCluster,
+// the labels add it to the ManagedClusterSet of the workspace.
+func (r *RegisteredClusterReconciler) adoptManagedCluster(ctx context.Context,
+	regCluster *singaporev1alpha1.RegisteredCluster,
+	hubCluster *helpers.HubInstance,
+	clusterName string,
+	labels map[string]string) error {
+	logger := r.Log.WithName("adoptManagedCluster").WithValues("namespace", regCluster.Namespace, "name", regCluster.Name, "hub", hubCluster.HubConfig.Name)
+
+	managedCluster := &clusterapiv1.ManagedCluster{}
+	if err := hubCluster.Client.Get(ctx,
+		types.NamespacedName{Name: regCluster.Spec.ExistingManagedClusterName},
+		managedCluster); err != nil {
+		return giterrors.WithStack(err)
+	}
+
+	if err := validateManagedClusterAdoption(managedCluster, labels[ManagedClusterSetlabel]); err != nil {
+		return err
+	}
+
+	patch := client.MergeFrom(managedCluster.DeepCopy())
+	managedClusterAnnotations := managedCluster.GetAnnotations()
+	// The managedclusterset is restored when the managedcluster is released
+	if _, ok := managedClusterAnnotations[OriginalClusterSetAnnotation]; !ok {
+		mergeMap(&managedClusterAnnotations, map[string]string{
+			OriginalClusterSetAnnotation: managedCluster.GetLabels()[ManagedClusterSetlabel],
+		})
+	}
+	mergeMap(&managedClusterAnnotations, map[string]string{
+		ClusterNameAnnotation:           clusterName,
+		AdoptedManagedClusterAnnotation: "true",
+	})
+	managedCluster.SetAnnotations(managedClusterAnnotations)
+	managedClusterLabels := managedCluster.GetLabels()
+	mergeMap(&managedClusterLabels, labels)
+	managedCluster.SetLabels(managedClusterLabels)
+
+	if err := hubCluster.Client.Patch(ctx, managedCluster, patch); err != nil {
+		return giterrors.WithStack(err)
+	}
+	logger.V(2).Info("managedcluster is adopted", "managedCluster", managedCluster.Name)
+	r.EventRecorder.Eventf(regCluster, corev1.EventTypeNormal, EventReasonManagedClusterAdopted,
+		"ManagedCluster %s adopted on the hub %s", managedCluster.Name, hubCluster.HubConfig.Name)
+	return nil
+}
+
+// validateManagedClusterAdoption checks the managedcluster is not owned by another RegisteredCluster
+// or member of a ManagedClusterSet other than the default one or the one of the workspace
+func validateManagedClusterAdoption(managedCluster *clusterapiv1.ManagedCluster, mcsName string) error {
+	if managedCluster.DeletionTimestamp != nil {
+		return fmt.Errorf("managedcluster %s is being deleted", managedCluster.Name)
+	}
+	if uid, ok := managedCluster.GetLabels()[RegisteredClusterUidLabel]; ok {
+		return helpers.NewConfigurationError(fmt.Errorf("managedcluster %s is already owned by the registeredcluster %s/%s (uid %s)",
+			managedCluster.Name,
+			managedCluster.GetLabels()[RegisteredClusterNamespacelabel],
+			managedCluster.GetLabels()[RegisteredClusterNamelabel],
+			uid))
+	}
+	// The hub adds the managedclusters without clusterset to the default one
+	if clusterSet, ok := managedCluster.GetLabels()[ManagedClusterSetlabel]; ok && clusterSet != mcsName && clusterSet != defaultManagedClusterSetName {
+		return helpers.NewConfigurationError(fmt.Errorf("managedcluster %s is a member of the managedclusterset %s, "+
+			"only the managedclusters of the %s managedclusterset or without managedclusterset can be adopted, "+
+			"move it to the %s managedclusterset to adopt it",
+			managedCluster.Name, clusterSet, defaultManagedClusterSetName, defaultManagedClusterSetName))
+	}
+	return nil
+}
+
 func registeredClusterPredicate() predicate.Predicate {
 	return predicate.Predicate(predicate.Funcs{
 		GenericFunc: func(e event.GenericEvent) bool { return false },
@@ -1129,8 +1842,9 @@ func managedClusterPredicate() predicate.Predicate {
 		GenericFunc: func(event event.GenericEvent) bool {
 			return false
 		},
+		// The RegisteredCluster is detached when its ManagedCluster is deleted on the hub
 		DeleteFunc: func(event event.DeleteEvent) bool {
-			return false
+			return f(event.Object)
 		},
 	}
 }
@@ -1174,7 +1888,26 @@ func manifestWorkPredicate() predicate.Predicate {
 
 // SetupWithManager sets up the controller with the Manager.
 
+// enqueueShard enqueues the RegisteredClusters of a shard acquired by the replica,
+// the events received while another replica owned the shard were ignored.
+func (r *RegisteredClusterReconciler) enqueueShard(ctx context.Context, shard int) {
+	regClusterList := &singaporev1alpha1.RegisteredClusterList{}
+	if err := r.Client.List(logicalcluster.WithCluster(ctx, logicalcluster.Wildcard), regClusterList); err != nil {
+		r.Log.Error(err, "failed to list the registeredclusters of the shard", "shard", shard)
+		return
+	}
+	for i := range regClusterList.Items {
+		regCluster := &regClusterList.Items[i]
+		if helpers.ShardOf(logicalcluster.From(regCluster).String(), r.ShardManager.Shards()) != shard {
+			continue
+		}
+		r.shardEvents <- event.GenericEvent{Object: regCluster}
+	}
+}
+
 func (r *RegisteredClusterReconciler) SetupWithManager(mgr ctrl.Manager, scheme *runtime.Scheme) error {
+	r.waitBackoff = newWaitBackoff()
+	r.workspaceLocks = helpers.NewKeyedMutex()
 
 	controllerBuilder := ctrl.NewControllerManagedBy(mgr).
 		For(&singaporev1alpha1.RegisteredCluster{}, builder.WithPredicates(registeredClusterPredicate()))
@@ -1182,6 +1915,9 @@ func (r *RegisteredClusterReconciler) SetupWithManager(mgr ctrl.Manager, scheme
 	for _, hubCluster := range r.HubClusters {
 
 		r.Log.V(1).Info("add watchers for ", "hubConfig.Name", hubCluster.HubConfig.Name)
+		if err := addHubIndexes(context.TODO(), &hubCluster); err != nil {
+			return err
+		}
 		controllerBuilder.Watches(source.NewKindWithCache(&clusterapiv1.ManagedCluster{}, hubCluster.Cluster.GetCache()), handler.EnqueueRequestsFromMapFunc(func(o client.Object) []reconcile.Request {
 			managedCluster := o.(*clusterapiv1.ManagedCluster)
 			r.Log.Info("Processing ManagedCluster event", "name", managedCluster.Name)
@@ -1212,6 +1948,56 @@ func (r *RegisteredClusterReconciler) SetupWithManager(mgr ctrl.Manager, scheme
 			}), builder.WithPredicates(manifestWorkPredicate()))
 	}
 
+	// Repair the SyncTargets, kcp-syncer ServiceAccounts and token secrets of the location workspaces
+	controllerBuilder.
+		Watches(&source.Kind{Type: newSyncTarget()}, handler.EnqueueRequestsFromMapFunc(mapToRegisteredCluster),
+			builder.WithPredicates(syncTargetPredicate())).
+		Watches(&source.Kind{Type: &corev1.ServiceAccount{}}, handler.EnqueueRequestsFromMapFunc(mapToRegisteredCluster),
+			builder.WithPredicates(serviceAccountPredicate())).
+		Watches(&source.Kind{Type: &corev1.Secret{}}, handler.EnqueueRequestsFromMapFunc(mapToRegisteredCluster),
+			builder.WithPredicates(tokenSecretPredicate()))
+
+	if err := registerHubCollector(r.HubClusters); err != nil {
+		return giterrors.WithStack(err)
+	}
+
+	if r.OrphanScanInterval > 0 {
+		if err := mgr.Add(manager.RunnableFunc(func(ctx context.Context) error {
+			wait.UntilWithContext(ctx, func(ctx context.Context) {
+				// The orphans are collected by the owner of the first shard
+				if r.ShardManager != nil && !r.ShardManager.OwnsShard(0) {
+					return
+				}
+				r.collectOrphans(ctx)
+			}, r.OrphanScanInterval)
+			return nil
+		})); err != nil {
+			return giterrors.WithStack(err)
+		}
+	}
+
+	if r.ShardManager != nil {
+		r.shardEvents = make(chan event.GenericEvent)
+		controllerBuilder.Watches(&source.Channel{Source: r.shardEvents}, handler.EnqueueRequestsFromMapFunc(func(o client.Object) []reconcile.Request {
+			return []reconcile.Request{
+				{
+					NamespacedName: types.NamespacedName{
+						Name:      o.GetName(),
+						Namespace: o.GetNamespace(),
+					},
+					ClusterName: logicalcluster.From(o).String(),
+				},
+			}
+		}))
+		r.ShardManager.OnAcquired(func(shard int) {
+			go r.enqueueShard(context.Background(), shard)
+		})
+		r.ShardManager.OnReleased(func(shard int) {
+			registeredClusterMetrics.forgetShard(shard, r.ShardManager.Shards())
+		})
+	}
+
 	return controllerBuilder.
+		WithOptions(controller.Options{MaxConcurrentReconciles: r.MaxConcurrentReconciles}).
 		Complete(r)
 }
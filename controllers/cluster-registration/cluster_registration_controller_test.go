@@ -0,0 +1,651 @@
+// Copyright Red Hat
+
+package registeredcluster
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	singaporev1alpha1 "github.com/stolostron/compute-operator/api/singapore/v1alpha1"
+	"github.com/stolostron/compute-operator/pkg/helpers"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clusterapiv1 "open-cluster-management.io/api/cluster/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func newTestManagedCluster(name string, adopted bool) *clusterapiv1.ManagedCluster {
+	managedCluster := &clusterapiv1.ManagedCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+		},
+	}
+	if adopted {
+		managedCluster.SetAnnotations(map[string]string{AdoptedManagedClusterAnnotation: "true"})
+	}
+	return managedCluster
+}
+
+func TestGetDeletionPolicy(t *testing.T) {
+	tests := []struct {
+		name          string
+		policy        singaporev1alpha1.DeletionPolicy
+		defaultPolicy singaporev1alpha1.DeletionPolicy
+		adopted       bool
+		want          singaporev1alpha1.DeletionPolicy
+	}{
+		{
+			name: "no policy",
+			want: singaporev1alpha1.DeletionPolicyDelete,
+		},
+		{
+			name:    "adopted without policy",
+			adopted: true,
+			want:    singaporev1alpha1.DeletionPolicyDetach,
+		},
+		{
+			name:          "registrar default",
+			defaultPolicy: singaporev1alpha1.DeletionPolicyDetach,
+			want:          singaporev1alpha1.DeletionPolicyDetach,
+		},
+		{
+			name:          "adopted with registrar default delete",
+			defaultPolicy: singaporev1alpha1.DeletionPolicyDelete,
+			adopted:       true,
+			want:          singaporev1alpha1.DeletionPolicyDetach,
+		},
+		{
+			name:          "registered cluster policy over registrar default",
+			policy:        singaporev1alpha1.DeletionPolicyDelete,
+			defaultPolicy: singaporev1alpha1.DeletionPolicyDetach,
+			want:          singaporev1alpha1.DeletionPolicyDelete,
+		},
+		{
+			name:    "adopted with registered cluster policy delete",
+			policy:  singaporev1alpha1.DeletionPolicyDelete,
+			adopted: true,
+			want:    singaporev1alpha1.DeletionPolicyDelete,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &RegisteredClusterReconciler{DefaultDeletionPolicy: tt.defaultPolicy}
+			regCluster := &singaporev1alpha1.RegisteredCluster{
+				Spec: singaporev1alpha1.RegisteredClusterSpec{DeletionPolicy: tt.policy},
+			}
+			got := r.getDeletionPolicy(regCluster, newTestManagedCluster("cluster1", tt.adopted))
+			if got != tt.want {
+				t.Fatalf("Deletion policy not as expected. Expected %s, actual %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestNeedsResync(t *testing.T) {
+	now := metav1.Now()
+	tests := []struct {
+		name    string
+		objects []client.Object
+		want    bool
+	}{
+		{
+			name: "not found",
+			want: false,
+		},
+		{
+			name: "existing",
+			objects: []client.Object{
+				&singaporev1alpha1.RegisteredCluster{
+					ObjectMeta: metav1.ObjectMeta{Name: "cluster1", Namespace: "ns1"},
+				},
+			},
+			want: true,
+		},
+		{
+			name: "being deleted",
+			objects: []client.Object{
+				&singaporev1alpha1.RegisteredCluster{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:              "cluster1",
+						Namespace:         "ns1",
+						DeletionTimestamp: &now,
+						Finalizers:        []string{helpers.RegisteredClusterFinalizer},
+					},
+				},
+			},
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &RegisteredClusterReconciler{
+				Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(tt.objects...).Build(),
+			}
+			req := ctrl.Request{
+				NamespacedName: types.NamespacedName{Name: "cluster1", Namespace: "ns1"},
+				ClusterName:    "root:ws1",
+			}
+			if got := r.needsResync(context.TODO(), req); got != tt.want {
+				t.Fatalf("Resync not as expected. Expected %t, actual %t", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestSkipCleanupStep(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		step        string
+		want        bool
+	}{
+		{
+			name: "no annotation",
+			step: CleanupStepManifestWork,
+			want: false,
+		},
+		{
+			name:        "listed step",
+			annotations: map[string]string{SkipCleanupAnnotation: CleanupStepManifestWork},
+			step:        CleanupStepManifestWork,
+			want:        true,
+		},
+		{
+			name:        "other step",
+			annotations: map[string]string{SkipCleanupAnnotation: CleanupStepManifestWork},
+			step:        CleanupStepSyncTarget,
+			want:        false,
+		},
+		{
+			name:        "comma separated with spaces",
+			annotations: map[string]string{SkipCleanupAnnotation: "manifestwork, synctarget"},
+			step:        CleanupStepSyncTarget,
+			want:        true,
+		},
+		{
+			name:        "all",
+			annotations: map[string]string{SkipCleanupAnnotation: CleanupStepAll},
+			step:        CleanupStepManagedClusterSet,
+			want:        true,
+		},
+		{
+			name:        "empty annotation",
+			annotations: map[string]string{SkipCleanupAnnotation: ""},
+			step:        CleanupStepManagedCluster,
+			want:        false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			regCluster := newTestRegisteredCluster("cluster1", "uid1")
+			regCluster.SetAnnotations(tt.annotations)
+			if got := skipCleanupStep(regCluster, tt.step); got != tt.want {
+				t.Fatalf("Skip cleanup not as expected. Expected %t, actual %t", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestIsDeletionTimedOut(t *testing.T) {
+	tests := []struct {
+		name            string
+		deletionTimeout time.Duration
+		deletedSince    time.Duration
+		deleting        bool
+		want            bool
+	}{
+		{
+			name:            "not being deleted",
+			deletionTimeout: time.Minute,
+			want:            false,
+		},
+		{
+			name:         "no timeout",
+			deleting:     true,
+			deletedSince: time.Hour,
+			want:         false,
+		},
+		{
+			name:            "before timeout",
+			deletionTimeout: time.Hour,
+			deleting:        true,
+			deletedSince:    time.Minute,
+			want:            false,
+		},
+		{
+			name:            "after timeout",
+			deletionTimeout: time.Minute,
+			deleting:        true,
+			deletedSince:    time.Hour,
+			want:            true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &RegisteredClusterReconciler{DeletionTimeout: tt.deletionTimeout}
+			regCluster := newTestRegisteredCluster("cluster1", "uid1")
+			if tt.deleting {
+				deletionTimestamp := metav1.NewTime(time.Now().Add(-tt.deletedSince))
+				regCluster.DeletionTimestamp = &deletionTimestamp
+			}
+			if got := r.isDeletionTimedOut(regCluster); got != tt.want {
+				t.Fatalf("Deletion timeout not as expected. Expected %t, actual %t", tt.want, got)
+			}
+		})
+	}
+}
+
+// waitForEvents returns the events of the namespace once count events are recorded
+func waitForEvents(t *testing.T, kubeClient *kubefake.Clientset, namespace string, count int) []corev1.Event {
+	var events *corev1.EventList
+	if err := wait.PollImmediate(10*time.Millisecond, 5*time.Second, func() (bool, error) {
+		var err error
+		events, err = kubeClient.CoreV1().Events(namespace).List(context.TODO(), metav1.ListOptions{})
+		if err != nil {
+			return false, err
+		}
+		return len(events.Items) >= count, nil
+	}); err != nil {
+		t.Fatalf("Expected %d events: %v", count, err)
+	}
+	return events.Items
+}
+
+func TestUpdateDeletionPhase(t *testing.T) {
+	kubeClient := kubefake.NewSimpleClientset()
+	r := &RegisteredClusterReconciler{
+		EventRecorder: helpers.NewEventRecorder(kubeClient, scheme, "test"),
+	}
+	regCluster := newTestRegisteredCluster("cluster1", "uid1")
+	phases := []singaporev1alpha1.DeletionPhase{
+		singaporev1alpha1.DeletionPhaseSyncerManifestWork,
+		singaporev1alpha1.DeletionPhaseSyncerManifestWork,
+		singaporev1alpha1.DeletionPhaseManagedCluster,
+	}
+	for _, phase := range phases {
+		r.updateDeletionPhase(regCluster, phase)
+		if regCluster.Status.DeletionPhase != phase {
+			t.Fatalf("Deletion phase not as expected. Expected %s, actual %s", phase, regCluster.Status.DeletionPhase)
+		}
+	}
+
+	waitForEvents(t, kubeClient, regCluster.Namespace, 2)
+	// Leave the time to an unexpected duplicated event to reach the fake client
+	time.Sleep(100 * time.Millisecond)
+	events := waitForEvents(t, kubeClient, regCluster.Namespace, 2)
+	reasons := sets.NewString()
+	for _, event := range events {
+		if event.Count > 1 {
+			t.Fatalf("Event %s recorded %d times, expected once per phase", event.Reason, event.Count)
+		}
+		reasons.Insert(event.Reason)
+	}
+	wantReasons := sets.NewString(string(singaporev1alpha1.DeletionPhaseSyncerManifestWork), string(singaporev1alpha1.DeletionPhaseManagedCluster))
+	if len(events) != 2 || !reasons.Equal(wantReasons) {
+		t.Fatalf("Events not as expected. Expected %v, actual %v", wantReasons.List(), reasons.List())
+	}
+}
+
+func newTestSyncTarget(name string, regCluster *singaporev1alpha1.RegisteredCluster) *unstructured.Unstructured {
+	syncTarget := newSyncTarget()
+	syncTarget.SetName(name)
+	syncTarget.SetLabels(getSyncTargetSelector(regCluster))
+	return syncTarget
+}
+
+// newTestSyncTargetScheme returns a scheme of the unstructured SyncTargets
+func newTestSyncTargetScheme() *runtime.Scheme {
+	testScheme := runtime.NewScheme()
+	testScheme.AddKnownTypeWithName(syncTargetGVR.GroupVersion().WithKind("SyncTarget"), &unstructured.Unstructured{})
+	testScheme.AddKnownTypeWithName(syncTargetGVR.GroupVersion().WithKind("SyncTargetList"), &unstructured.UnstructuredList{})
+	return testScheme
+}
+
+func TestGetSyncTarget(t *testing.T) {
+	regCluster := newTestRegisteredCluster("cluster1", "uid1")
+	tests := []struct {
+		name         string
+		cached       []client.Object
+		live         []runtime.Object
+		want         string
+		wantUncached string
+	}{
+		{
+			name:         "cached",
+			cached:       []client.Object{newTestSyncTarget("cluster1-abcde", regCluster)},
+			live:         []runtime.Object{newTestSyncTarget("cluster1-abcde", regCluster)},
+			want:         "cluster1-abcde",
+			wantUncached: "cluster1-abcde",
+		},
+		{
+			name:         "not yet cached",
+			live:         []runtime.Object{newTestSyncTarget("cluster1-abcde", regCluster)},
+			wantUncached: "cluster1-abcde",
+		},
+		{
+			name:   "other registered cluster",
+			cached: []client.Object{newTestSyncTarget("cluster1-abcde", newTestRegisteredCluster("cluster1", "uid0"))},
+			live:   []runtime.Object{newTestSyncTarget("cluster1-abcde", newTestRegisteredCluster("cluster1", "uid0"))},
+		},
+		{
+			name: "missing",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testScheme := newTestSyncTargetScheme()
+			r := &RegisteredClusterReconciler{
+				Client: fake.NewClientBuilder().WithScheme(testScheme).WithObjects(tt.cached...).Build(),
+				ComputeDynamicClient: dynamicfake.NewSimpleDynamicClientWithCustomListKinds(testScheme,
+					map[schema.GroupVersionResource]string{syncTargetGVR: "SyncTargetList"}, tt.live...),
+				Log: logr.Discard(),
+			}
+			syncTarget, err := r.getSyncTarget(context.TODO(), regCluster)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if name := getTestSyncTargetName(syncTarget); name != tt.want {
+				t.Fatalf("SyncTarget not as expected. Expected %q, actual %q", tt.want, name)
+			}
+			syncTarget, err = r.getUncachedSyncTarget(context.TODO(), regCluster)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if name := getTestSyncTargetName(syncTarget); name != tt.wantUncached {
+				t.Fatalf("Uncached SyncTarget not as expected. Expected %q, actual %q", tt.wantUncached, name)
+			}
+		})
+	}
+}
+
+func TestGetSyncTargets(t *testing.T) {
+	regCluster := newTestRegisteredCluster("cluster1", "uid1")
+	regCluster.Spec.Location = []string{"root:location1", "root:location2"}
+	r := &RegisteredClusterReconciler{
+		Client: fake.NewClientBuilder().WithScheme(newTestSyncTargetScheme()).
+			WithObjects(newTestSyncTarget("cluster1-abcde", regCluster)).Build(),
+		Log: logr.Discard(),
+	}
+	syncTargets, err := r.getSyncTargets(context.TODO(), regCluster)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The fake client ignores the logical cluster, both locations find the same SyncTarget
+	for _, locationWorkspace := range regCluster.Spec.Location {
+		if name := getTestSyncTargetName(syncTargets[locationWorkspace]); name != "cluster1-abcde" {
+			t.Fatalf("SyncTarget of %s not as expected. Expected cluster1-abcde, actual %q", locationWorkspace, name)
+		}
+	}
+}
+
+func getTestSyncTargetName(syncTarget *unstructured.Unstructured) string {
+	if syncTarget == nil {
+		return ""
+	}
+	return syncTarget.GetName()
+}
+
+func TestGetSyncTargetSettings(t *testing.T) {
+	regCluster := newTestRegisteredCluster("cluster1", "uid1")
+	regCluster.Spec.SyncTarget = singaporev1alpha1.SyncTargetSettings{Unschedulable: true}
+	regCluster.Spec.LocationOverrides = []singaporev1alpha1.LocationOverride{
+		{
+			Location:   "root:location2",
+			SyncTarget: singaporev1alpha1.SyncTargetSettings{Unschedulable: false},
+		},
+	}
+	if settings := getSyncTargetSettings(regCluster, "root:location1"); !settings.Unschedulable {
+		t.Fatal("Expected the spec.syncTarget settings for a location without override")
+	}
+	if settings := getSyncTargetSettings(regCluster, "root:location2"); settings.Unschedulable {
+		t.Fatal("Expected the location override settings")
+	}
+}
+
+func TestApplySyncTargetSettings(t *testing.T) {
+	evictAfter := metav1.NewTime(time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC))
+	tests := []struct {
+		name         string
+		spec         map[string]interface{}
+		settings     singaporev1alpha1.SyncTargetSettings
+		wantModified bool
+		wantSpec     map[string]interface{}
+	}{
+		{
+			name:         "no settings",
+			spec:         map[string]interface{}{"unschedulable": false},
+			wantModified: false,
+			wantSpec:     map[string]interface{}{"unschedulable": false},
+		},
+		{
+			name:         "cordon",
+			spec:         map[string]interface{}{},
+			settings:     singaporev1alpha1.SyncTargetSettings{Unschedulable: true},
+			wantModified: true,
+			wantSpec:     map[string]interface{}{"unschedulable": true},
+		},
+		{
+			name:         "uncordon",
+			spec:         map[string]interface{}{"unschedulable": true},
+			wantModified: true,
+			wantSpec:     map[string]interface{}{"unschedulable": false},
+		},
+		{
+			name:         "set evictAfter",
+			spec:         map[string]interface{}{"unschedulable": false},
+			settings:     singaporev1alpha1.SyncTargetSettings{EvictAfter: &evictAfter},
+			wantModified: true,
+			wantSpec:     map[string]interface{}{"unschedulable": false, "evictAfter": "2022-10-01T12:00:00Z"},
+		},
+		{
+			name:         "same evictAfter",
+			spec:         map[string]interface{}{"unschedulable": false, "evictAfter": "2022-10-01T12:00:00Z"},
+			settings:     singaporev1alpha1.SyncTargetSettings{EvictAfter: &evictAfter},
+			wantModified: false,
+			wantSpec:     map[string]interface{}{"unschedulable": false, "evictAfter": "2022-10-01T12:00:00Z"},
+		},
+		{
+			name:         "remove evictAfter",
+			spec:         map[string]interface{}{"unschedulable": false, "evictAfter": "2022-10-01T12:00:00Z"},
+			wantModified: true,
+			wantSpec:     map[string]interface{}{"unschedulable": false},
+		},
+		{
+			name: "supported apiexports",
+			spec: map[string]interface{}{"unschedulable": false},
+			settings: singaporev1alpha1.SyncTargetSettings{
+				SupportedAPIExports: []singaporev1alpha1.APIExportReference{
+					{ExportName: "kubernetes"},
+					{Path: "root:compute", ExportName: "compute"},
+				},
+			},
+			wantModified: true,
+			wantSpec: map[string]interface{}{
+				"unschedulable": false,
+				"supportedAPIExports": []interface{}{
+					map[string]interface{}{"workspace": map[string]interface{}{"path": "root:location1", "exportName": "kubernetes"}},
+					map[string]interface{}{"workspace": map[string]interface{}{"path": "root:compute", "exportName": "compute"}},
+				},
+			},
+		},
+		{
+			name: "supported apiexports left to kcp",
+			spec: map[string]interface{}{
+				"unschedulable": false,
+				"supportedAPIExports": []interface{}{
+					map[string]interface{}{"workspace": map[string]interface{}{"path": "root:location1", "exportName": "kubernetes"}},
+				},
+			},
+			wantModified: false,
+			wantSpec: map[string]interface{}{
+				"unschedulable": false,
+				"supportedAPIExports": []interface{}{
+					map[string]interface{}{"workspace": map[string]interface{}{"path": "root:location1", "exportName": "kubernetes"}},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			syncTarget := newSyncTarget()
+			if err := unstructured.SetNestedField(syncTarget.Object, tt.spec, "spec"); err != nil {
+				t.Fatal(err)
+			}
+			modified, err := applySyncTargetSettings(syncTarget, tt.settings, "root:location1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if modified != tt.wantModified {
+				t.Fatalf("Modified not as expected. Expected %t, actual %t", tt.wantModified, modified)
+			}
+			spec, _, err := unstructured.NestedMap(syncTarget.Object, "spec")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !equality.Semantic.DeepEqual(spec, tt.wantSpec) {
+				t.Fatalf("SyncTarget spec not as expected. Expected %v, actual %v", tt.wantSpec, spec)
+			}
+			if modified, err := applySyncTargetSettings(syncTarget, tt.settings, "root:location1"); err != nil || modified {
+				t.Fatalf("Expected the settings to be applied once, modified %t, error %v", modified, err)
+			}
+		})
+	}
+}
+
+func TestValidateManagedClusterAdoption(t *testing.T) {
+	now := metav1.Now()
+	tests := []struct {
+		name        string
+		labels      map[string]string
+		deleting    bool
+		wantErr     bool
+		wantConfErr bool
+	}{
+		{
+			name: "without managedclusterset",
+		},
+		{
+			name:   "default managedclusterset",
+			labels: map[string]string{ManagedClusterSetlabel: defaultManagedClusterSetName},
+		},
+		{
+			name:   "managedclusterset of the workspace",
+			labels: map[string]string{ManagedClusterSetlabel: "ws1-abcde"},
+		},
+		{
+			name:        "other managedclusterset",
+			labels:      map[string]string{ManagedClusterSetlabel: "other"},
+			wantErr:     true,
+			wantConfErr: true,
+		},
+		{
+			name: "owned by another registered cluster",
+			labels: map[string]string{
+				RegisteredClusterNamelabel:      "cluster2",
+				RegisteredClusterNamespacelabel: "ns1",
+				RegisteredClusterUidLabel:       "uid2",
+			},
+			wantErr:     true,
+			wantConfErr: true,
+		},
+		{
+			name:     "being deleted",
+			deleting: true,
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			managedCluster := newTestManagedCluster("cluster1", false)
+			managedCluster.SetLabels(tt.labels)
+			if tt.deleting {
+				managedCluster.DeletionTimestamp = &now
+			}
+			err := validateManagedClusterAdoption(managedCluster, "ws1-abcde")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Error not as expected. Expected error %t, actual %v", tt.wantErr, err)
+			}
+			if err != nil && (helpers.ClassifyError(err) == helpers.ErrorClassConfiguration) != tt.wantConfErr {
+				t.Fatalf("Error class not as expected. Expected configuration error %t, actual %s", tt.wantConfErr, helpers.ClassifyError(err))
+			}
+		})
+	}
+}
+
+func TestAdoptAndReleaseManagedCluster(t *testing.T) {
+	tests := []struct {
+		name       string
+		clusterSet string
+	}{
+		{
+			name: "without managedclusterset",
+		},
+		{
+			name:       "default managedclusterset",
+			clusterSet: defaultManagedClusterSetName,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			managedCluster := newTestManagedCluster("cluster1", false)
+			if len(tt.clusterSet) != 0 {
+				managedCluster.SetLabels(map[string]string{ManagedClusterSetlabel: tt.clusterSet})
+			}
+			regCluster := newTestRegisteredCluster("cluster1", "uid1")
+			regCluster.Spec.ExistingManagedClusterName = managedCluster.Name
+			r := &RegisteredClusterReconciler{
+				Log:           logr.Discard(),
+				EventRecorder: helpers.NewEventRecorder(kubefake.NewSimpleClientset(), scheme, "test"),
+			}
+			hubCluster := newTestHubInstance(managedCluster)
+
+			if err := r.adoptManagedCluster(context.TODO(), regCluster, hubCluster, "root:ws1", map[string]string{
+				RegisteredClusterUidLabel: string(regCluster.UID),
+				ManagedClusterSetlabel:    "ws1-abcde",
+			}); err != nil {
+				t.Fatal(err)
+			}
+			adopted := &clusterapiv1.ManagedCluster{}
+			if err := hubCluster.Client.Get(context.TODO(), client.ObjectKeyFromObject(managedCluster), adopted); err != nil {
+				t.Fatal(err)
+			}
+			if clusterSet := adopted.GetLabels()[ManagedClusterSetlabel]; clusterSet != "ws1-abcde" {
+				t.Fatalf("ManagedClusterSet not as expected. Expected ws1-abcde, actual %s", clusterSet)
+			}
+			if !isAdoptedManagedCluster(adopted) {
+				t.Fatal("Expected the ManagedCluster to be adopted")
+			}
+
+			if err := r.releaseManagedCluster(context.TODO(), adopted, hubCluster); err != nil {
+				t.Fatal(err)
+			}
+			released := &clusterapiv1.ManagedCluster{}
+			if err := hubCluster.Client.Get(context.TODO(), client.ObjectKeyFromObject(managedCluster), released); err != nil {
+				t.Fatal(err)
+			}
+			clusterSet, ok := released.GetLabels()[ManagedClusterSetlabel]
+			if clusterSet != tt.clusterSet || ok != (len(tt.clusterSet) != 0) {
+				t.Fatalf("ManagedClusterSet not restored. Expected %q, actual %q", tt.clusterSet, clusterSet)
+			}
+			if _, ok := released.GetAnnotations()[OriginalClusterSetAnnotation]; ok {
+				t.Fatal("Expected the original clusterset annotation to be removed")
+			}
+			if _, ok := released.GetLabels()[RegisteredClusterUidLabel]; ok {
+				t.Fatal("Expected the RegisteredCluster labels to be removed")
+			}
+		})
+	}
+}
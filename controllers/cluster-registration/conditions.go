@@ -0,0 +1,125 @@
+// Copyright Red Hat
+
+package registeredcluster
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	giterrors "github.com/pkg/errors"
+
+	singaporev1alpha1 "github.com/stolostron/compute-operator/api/singapore/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterapiv1 "open-cluster-management.io/api/cluster/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// readyConditionTypes are the operator conditions which must be true for the RegisteredCluster to be ready
+var readyConditionTypes = []string{
+	singaporev1alpha1.RegisteredClusterConditionHubAssigned,
+	singaporev1alpha1.RegisteredClusterConditionManagedClusterCreated,
+	singaporev1alpha1.RegisteredClusterConditionImportCommandReady,
+	singaporev1alpha1.RegisteredClusterConditionSyncTargetsReady,
+	singaporev1alpha1.RegisteredClusterConditionSyncersReady,
+}
+
+// setConditions sets the operator conditions and computes the Ready condition and the phase,
+// the status is patched once at the end of the reconcile by patchStatus.
+func setConditions(regCluster *singaporev1alpha1.RegisteredCluster, conditions ...metav1.Condition) {
+	for _, condition := range conditions {
+		condition.ObservedGeneration = regCluster.Generation
+		meta.SetStatusCondition(&regCluster.Status.Conditions, condition)
+	}
+	readyCondition := getReadyCondition(regCluster)
+	readyCondition.ObservedGeneration = regCluster.Generation
+	meta.SetStatusCondition(&regCluster.Status.Conditions, readyCondition)
+	regCluster.Status.Phase = getPhase(regCluster)
+}
+
+// patchStatus patches the status changes accumulated during the reconcile in a single request,
+// originalStatus is the status read at the beginning of the reconcile.
+func (r *RegisteredClusterReconciler) patchStatus(computeContext context.Context,
+	regCluster *singaporev1alpha1.RegisteredCluster,
+	originalStatus *singaporev1alpha1.RegisteredClusterStatus) error {
+	// The ManagedCluster conditions mirrored in the status change the Ready condition and the phase
+	setConditions(regCluster)
+	if equality.Semantic.DeepEqual(*originalStatus, regCluster.Status) {
+		return nil
+	}
+	original := regCluster.DeepCopy()
+	original.Status = *originalStatus
+	if err := r.Client.Status().Patch(computeContext, regCluster, client.MergeFrom(original)); err != nil {
+		// The RegisteredCluster is gone once its finalizer is removed
+		if k8serrors.IsNotFound(err) {
+			return nil
+		}
+		return giterrors.WithStack(err)
+	}
+	return nil
+}
+
+func getReadyCondition(regCluster *singaporev1alpha1.RegisteredCluster) metav1.Condition {
+	if regCluster.DeletionTimestamp != nil {
+		return metav1.Condition{
+			Type:    singaporev1alpha1.RegisteredClusterConditionReady,
+			Status:  metav1.ConditionFalse,
+			Reason:  singaporev1alpha1.ReasonDeleting,
+			Message: "the RegisteredCluster is being deleted",
+		}
+	}
+	notReady := make([]string, 0)
+	for _, conditionType := range readyConditionTypes {
+		if !meta.IsStatusConditionTrue(regCluster.Status.Conditions, conditionType) {
+			notReady = append(notReady, conditionType)
+		}
+	}
+	if len(notReady) > 0 {
+		return metav1.Condition{
+			Type:    singaporev1alpha1.RegisteredClusterConditionReady,
+			Status:  metav1.ConditionFalse,
+			Reason:  singaporev1alpha1.ReasonNotReady,
+			Message: fmt.Sprintf("conditions not true: %s", strings.Join(notReady, ", ")),
+		}
+	}
+	return metav1.Condition{
+		Type:    singaporev1alpha1.RegisteredClusterConditionReady,
+		Status:  metav1.ConditionTrue,
+		Reason:  singaporev1alpha1.ReasonReady,
+		Message: "the RegisteredCluster is ready",
+	}
+}
+
+func getPhase(regCluster *singaporev1alpha1.RegisteredCluster) singaporev1alpha1.RegisteredClusterPhase {
+	switch {
+	case regCluster.DeletionTimestamp != nil:
+		return singaporev1alpha1.RegisteredClusterPhaseDeleting
+	case isDetached(regCluster):
+		return singaporev1alpha1.RegisteredClusterPhaseDetached
+	case meta.IsStatusConditionTrue(regCluster.Status.Conditions, singaporev1alpha1.RegisteredClusterConditionReady):
+		return singaporev1alpha1.RegisteredClusterPhaseReady
+	case meta.IsStatusConditionTrue(regCluster.Status.Conditions, clusterapiv1.ManagedClusterConditionJoined):
+		return singaporev1alpha1.RegisteredClusterPhaseProvisioning
+	case meta.IsStatusConditionTrue(regCluster.Status.Conditions, singaporev1alpha1.RegisteredClusterConditionManagedClusterCreated):
+		return singaporev1alpha1.RegisteredClusterPhaseImporting
+	}
+	return singaporev1alpha1.RegisteredClusterPhasePending
+}
+
+// isDetached returns true when the ManagedCluster of the RegisteredCluster was deleted on the hub
+func isDetached(regCluster *singaporev1alpha1.RegisteredCluster) bool {
+	managedClusterCreated := meta.FindStatusCondition(regCluster.Status.Conditions, singaporev1alpha1.RegisteredClusterConditionManagedClusterCreated)
+	return managedClusterCreated != nil && managedClusterCreated.Reason == singaporev1alpha1.ReasonManagedClusterDeleted
+}
+
+func newCondition(conditionType string, status metav1.ConditionStatus, reason, message string) metav1.Condition {
+	return metav1.Condition{
+		Type:    conditionType,
+		Status:  status,
+		Reason:  reason,
+		Message: message,
+	}
+}
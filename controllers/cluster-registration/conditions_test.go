@@ -0,0 +1,84 @@
+// Copyright Red Hat
+
+package registeredcluster
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+	singaporev1alpha1 "github.com/stolostron/compute-operator/api/singapore/v1alpha1"
+	"github.com/stolostron/compute-operator/pkg/helpers"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestPatchStatus(t *testing.T) {
+	regCluster := newTestRegisteredCluster("cluster1", "uid1")
+	r := &RegisteredClusterReconciler{
+		Client:        fake.NewClientBuilder().WithScheme(scheme).WithObjects(regCluster).Build(),
+		Log:           logr.Discard(),
+		EventRecorder: helpers.NewEventRecorder(kubefake.NewSimpleClientset(), scheme, "test"),
+	}
+	if err := r.Client.Get(context.TODO(), client.ObjectKeyFromObject(regCluster), regCluster); err != nil {
+		t.Fatal(err)
+	}
+	originalStatus := regCluster.Status.DeepCopy()
+
+	setConditions(regCluster,
+		newCondition(singaporev1alpha1.RegisteredClusterConditionHubAssigned, metav1.ConditionTrue,
+			singaporev1alpha1.ReasonHubSelected, "assigned to the hub hub1"),
+		newCondition(singaporev1alpha1.RegisteredClusterConditionManagedClusterCreated, metav1.ConditionTrue,
+			singaporev1alpha1.ReasonManagedClusterCreated, "ManagedCluster cluster1 created"))
+	r.recordDrift(regCluster, []singaporev1alpha1.DriftRecord{
+		{
+			Kind:    singaporev1alpha1.DriftKindManagedCluster,
+			Message: "the ManagedCluster was deleted from the hub hub1",
+		},
+	})
+
+	// Nothing is patched before the end of the reconcile
+	stored := &singaporev1alpha1.RegisteredCluster{}
+	if err := r.Client.Get(context.TODO(), client.ObjectKeyFromObject(regCluster), stored); err != nil {
+		t.Fatal(err)
+	}
+	if len(stored.Status.Conditions) != 0 || len(stored.Status.Drift) != 0 {
+		t.Fatalf("Status patched before the end of the reconcile: %v", stored.Status)
+	}
+
+	if err := r.patchStatus(context.TODO(), regCluster, originalStatus); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Client.Get(context.TODO(), client.ObjectKeyFromObject(regCluster), stored); err != nil {
+		t.Fatal(err)
+	}
+	if !meta.IsStatusConditionTrue(stored.Status.Conditions, singaporev1alpha1.RegisteredClusterConditionHubAssigned) ||
+		!meta.IsStatusConditionTrue(stored.Status.Conditions, singaporev1alpha1.RegisteredClusterConditionManagedClusterCreated) {
+		t.Fatalf("Conditions not patched: %v", stored.Status.Conditions)
+	}
+	if meta.FindStatusCondition(stored.Status.Conditions, singaporev1alpha1.RegisteredClusterConditionReady) == nil {
+		t.Fatalf("Ready condition not patched: %v", stored.Status.Conditions)
+	}
+	if stored.Status.Phase != singaporev1alpha1.RegisteredClusterPhaseImporting {
+		t.Fatalf("Phase not as expected. Expected %s, actual %s", singaporev1alpha1.RegisteredClusterPhaseImporting, stored.Status.Phase)
+	}
+	if len(stored.Status.Drift) != 1 || stored.Status.LastDriftTime == nil {
+		t.Fatalf("Drift not patched: %v", stored.Status.Drift)
+	}
+}
+
+func TestPatchStatusDeleted(t *testing.T) {
+	regCluster := newTestRegisteredCluster("cluster1", "uid1")
+	r := &RegisteredClusterReconciler{
+		Client: fake.NewClientBuilder().WithScheme(scheme).Build(),
+		Log:    logr.Discard(),
+	}
+	originalStatus := regCluster.Status.DeepCopy()
+	regCluster.Status.DeletionPhase = singaporev1alpha1.DeletionPhaseManagedClusterSet
+	if err := r.patchStatus(context.TODO(), regCluster, originalStatus); err != nil {
+		t.Fatalf("Expected no error once the RegisteredCluster is deleted, actual %v", err)
+	}
+}
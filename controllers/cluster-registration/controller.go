@@ -6,6 +6,7 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"time"
 
 	giterrors "github.com/pkg/errors"
 
@@ -18,8 +19,11 @@ import (
 	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
 	"k8s.io/klog/v2"
 
+	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/kcp"
 
@@ -43,9 +47,15 @@ var (
 )
 
 type managerOptions struct {
-	metricsAddr          string
-	probeAddr            string
-	enableLeaderElection bool
+	metricsAddr             string
+	probeAddr               string
+	enableLeaderElection    bool
+	deletionTimeout         time.Duration
+	orphanScanInterval      time.Duration
+	shards                  int
+	maxConcurrentReconciles int
+	resyncPeriod            time.Duration
+	shardLeaseDuration      time.Duration
 }
 
 func init() {
@@ -75,6 +85,22 @@ func NewManager() *cobra.Command {
 	cmd.Flags().BoolVar(&o.enableLeaderElection, "enable-leader-election", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	cmd.Flags().DurationVar(&o.deletionTimeout, "deletion-timeout", 30*time.Minute,
+		"The duration after which the cleanup of a deleted RegisteredCluster is forced and its finalizer removed. "+
+			"Zero disables the timeout.")
+	cmd.Flags().DurationVar(&o.orphanScanInterval, "orphan-scan-interval", 10*time.Minute,
+		"The period of the scan removing the hub and compute resources left by deleted RegisteredClusters. "+
+			"Zero disables the scan.")
+	cmd.Flags().DurationVar(&o.resyncPeriod, "resync-period", 10*time.Minute,
+		"The period of the full reconcile of each RegisteredCluster, which detects and repairs the drift of its objects. "+
+			"Zero disables the resync.")
+	cmd.Flags().IntVar(&o.maxConcurrentReconciles, "max-concurrent-reconciles", 10,
+		"The number of RegisteredClusters reconciled in parallel, the RegisteredClusters of a workspace are always reconciled one at a time.")
+	cmd.Flags().IntVar(&o.shards, "shards", 1,
+		"The number of shards the workspaces are spread in. "+
+			"With more than one shard, the replicas share the shards using leases instead of electing a leader.")
+	cmd.Flags().DurationVar(&o.shardLeaseDuration, "shard-lease-duration", 15*time.Second,
+		"The duration of the shard leases, a shard of a stopped replica is taken over after this duration.")
 	return cmd
 }
 
@@ -144,6 +170,12 @@ func (o *managerOptions) run() {
 		os.Exit(1)
 	}
 
+	// The shard leases replace the leader election
+	if o.shards > 1 && o.enableLeaderElection {
+		setupLog.Info("leader election disabled, the replicas share the shards", "shards", o.shards)
+		o.enableLeaderElection = false
+	}
+
 	opts := ctrl.Options{
 		Scheme:                 scheme,
 		MetricsBindAddress:     o.metricsAddr,
@@ -154,6 +186,29 @@ func (o *managerOptions) run() {
 		LeaderElectionConfig: ctrl.GetConfigOrDie(),
 		LeaderElectionID:     "628f2987.cluster-registration.io",
 		// NewCache:             helpers.NewClusterAwareCacheFunc,
+		// Only the location workspace objects of the RegisteredClusters are cached
+		NewCache: func(config *rest.Config, cacheOpts cache.Options) (cache.Cache, error) {
+			cacheOpts.SelectorsByObject = CacheSelectors()
+			return kcp.NewClusterAwareCache(config, cacheOpts)
+		},
+		// The SyncTargets are unstructured, they are read from the cache as the typed objects
+		NewClient: func(cache cache.Cache, config *rest.Config, clientOpts client.Options, uncachedObjects ...client.Object) (client.Client, error) {
+			httpClient, err := kcp.ClusterAwareHTTPClient(config)
+			if err != nil {
+				return nil, err
+			}
+			clientOpts.HTTPClient = httpClient
+			c, err := client.New(config, clientOpts)
+			if err != nil {
+				return nil, err
+			}
+			return client.NewDelegatingClient(client.NewDelegatingClientInput{
+				CacheReader:       cache,
+				Client:            c,
+				UncachedObjects:   uncachedObjects,
+				CacheUnstructured: true,
+			})
+		},
 	}
 
 	// cfg = apimachineryclient.NewClusterConfig(cfg)
@@ -214,6 +269,21 @@ func (o *managerOptions) run() {
 		setupLog.Error(giterrors.WithStack(err), "unable to retreive the hubCluster", "controller", "Cluster Registration")
 		os.Exit(1)
 	}
+	var shardManager *helpers.ShardManager
+	if o.shards > 1 {
+		identity, err := os.Hostname()
+		if err != nil {
+			setupLog.Error(giterrors.WithStack(err), "unable to get the shard identity")
+			os.Exit(1)
+		}
+		shardManager = helpers.NewShardManager(kubeClient, podNamespace, identity, o.shards, o.shardLeaseDuration,
+			ctrl.Log.WithName("controllers").WithName("RegisteredCluster"))
+		if err := mgr.Add(shardManager); err != nil {
+			setupLog.Error(giterrors.WithStack(err), "unable to add the shard manager")
+			os.Exit(1)
+		}
+	}
+
 	if err = (&RegisteredClusterReconciler{
 		Client:                    mgr.GetClient(),
 		Log:                       ctrl.Log.WithName("controllers").WithName("RegisteredCluster"),
@@ -223,6 +293,13 @@ func (o *managerOptions) run() {
 		ComputeKubeClient:         computeKubeClient,
 		ComputeDynamicClient:      computeDynamicClient,
 		ComputeAPIExtensionClient: computeApiExtensionClient,
+		DefaultDeletionPolicy:     clusterRegistrar.Spec.DefaultDeletionPolicy,
+		DeletionTimeout:           o.deletionTimeout,
+		OrphanScanInterval:        o.orphanScanInterval,
+		EventRecorder:             helpers.NewEventRecorder(computeKubeClient, scheme, "compute-operator"),
+		ShardManager:              shardManager,
+		MaxConcurrentReconciles:   o.maxConcurrentReconciles,
+		ResyncPeriod:              o.resyncPeriod,
 	}).SetupWithManager(mgr, scheme); err != nil {
 		setupLog.Error(giterrors.WithStack(err), "unable to create controller", "controller", "Cluster Registration")
 		os.Exit(1)
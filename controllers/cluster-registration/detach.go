@@ -0,0 +1,137 @@
+// Copyright Red Hat
+
+package registeredcluster
+
+import (
+	"context"
+	"fmt"
+
+	giterrors "github.com/pkg/errors"
+
+	"github.com/kcp-dev/logicalcluster/v2"
+	singaporev1alpha1 "github.com/stolostron/compute-operator/api/singapore/v1alpha1"
+	"github.com/stolostron/compute-operator/pkg/helpers"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	clusterapiv1 "open-cluster-management.io/api/cluster/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// EventReasonManagedClusterDeleted is the reason of the event recorded when the ManagedCluster is deleted on the hub
+	EventReasonManagedClusterDeleted string = "ManagedClusterDeleted"
+)
+
+// operatorConditionTypes are the conditions set by the operator, the other conditions are mirrored from the ManagedCluster
+var operatorConditionTypes = append([]string{
+	singaporev1alpha1.RegisteredClusterConditionForceDeleted,
+	singaporev1alpha1.RegisteredClusterConditionReady,
+}, readyConditionTypes...)
+
+// syncManagedClusterDeletion detects the deletion of the ManagedCluster on the hub and detaches the RegisteredCluster,
+// it returns true while the RegisteredCluster must stay detached, otherwise the ManagedCluster is created or adopted again.
+func (r *RegisteredClusterReconciler) syncManagedClusterDeletion(computeContext context.Context,
+	ctx context.Context,
+	regCluster *singaporev1alpha1.RegisteredCluster,
+	hubCluster *helpers.HubInstance,
+	syncTargets map[string]*unstructured.Unstructured) (bool, error) {
+	managedClusterCreated := meta.FindStatusCondition(regCluster.Status.Conditions, singaporev1alpha1.RegisteredClusterConditionManagedClusterCreated)
+	if managedClusterCreated == nil {
+		return false, nil
+	}
+	detached := managedClusterCreated.Reason == singaporev1alpha1.ReasonManagedClusterDeleted
+	if managedClusterCreated.Status != metav1.ConditionTrue && !detached {
+		return false, nil
+	}
+
+	managedClusterList := &clusterapiv1.ManagedClusterList{}
+	if err := hubCluster.Client.List(ctx, managedClusterList,
+		client.MatchingFields{managedClusterRegisteredClusterIndex: string(regCluster.UID)}); err != nil {
+		return false, helpers.NewHubError(hubCluster.HubConfig.Name, giterrors.WithStack(err))
+	}
+	if len(managedClusterList.Items) != 0 {
+		return false, nil
+	}
+
+	if !detached {
+		if err := r.detachRegisteredCluster(computeContext, regCluster, hubCluster, syncTargets); err != nil {
+			return false, err
+		}
+	}
+
+	// An adopted ManagedCluster is adopted again once it is back on the hub
+	if len(regCluster.Spec.ExistingManagedClusterName) != 0 {
+		managedCluster := &clusterapiv1.ManagedCluster{}
+		if err := hubCluster.Client.Get(ctx, types.NamespacedName{Name: regCluster.Spec.ExistingManagedClusterName}, managedCluster); err != nil {
+			if k8serrors.IsNotFound(err) {
+				return true, nil
+			}
+			return false, helpers.NewHubError(hubCluster.HubConfig.Name, giterrors.WithStack(err))
+		}
+		return false, nil
+	}
+	return regCluster.Spec.OnManagedClusterDeletion != singaporev1alpha1.ManagedClusterDeletionActionRecreate, nil
+}
+
+// detachRegisteredCluster marks the SyncTargets unschedulable, removes the stale ManagedCluster conditions
+// and import command and sets the operator conditions of a detached RegisteredCluster.
+// The updated SyncTargets replace the ones of syncTargets.
+func (r *RegisteredClusterReconciler) detachRegisteredCluster(computeContext context.Context,
+	regCluster *singaporev1alpha1.RegisteredCluster,
+	hubCluster *helpers.HubInstance,
+	syncTargets map[string]*unstructured.Unstructured) error {
+	r.Log.Info("managedcluster deleted on the hub, detach the registeredcluster",
+		"namespace", regCluster.Namespace,
+		"name", regCluster.Name,
+		"hub", hubCluster.HubConfig.Name)
+	r.EventRecorder.Eventf(regCluster, corev1.EventTypeWarning, EventReasonManagedClusterDeleted,
+		"The ManagedCluster was deleted on the hub %s", hubCluster.HubConfig.Name)
+
+	// kcp must stop scheduling workloads on a cluster which is no longer managed
+	for _, locationWorkspace := range regCluster.Spec.Location {
+		syncTarget, ok := syncTargets[locationWorkspace]
+		if !ok {
+			continue
+		}
+		if unschedulable, _, _ := unstructured.NestedBool(syncTarget.Object, "spec", "unschedulable"); unschedulable {
+			continue
+		}
+		syncTarget = syncTarget.DeepCopy()
+		if err := unstructured.SetNestedField(syncTarget.Object, true, "spec", "unschedulable"); err != nil {
+			return giterrors.WithStack(err)
+		}
+		locationContext := logicalcluster.WithCluster(computeContext, logicalcluster.New(locationWorkspace))
+		syncTarget, err := r.ComputeDynamicClient.Resource(syncTargetGVR).Update(locationContext, syncTarget, metav1.UpdateOptions{})
+		if err != nil {
+			return giterrors.Wrapf(err, "location %s", locationWorkspace)
+		}
+		syncTargets[locationWorkspace] = syncTarget
+		r.Log.V(2).Info("SyncTarget marked unschedulable", "location", locationWorkspace, "syncTarget", syncTarget.GetName())
+	}
+
+	conditions := make([]metav1.Condition, 0, len(operatorConditionTypes))
+	for _, conditionType := range operatorConditionTypes {
+		if condition := meta.FindStatusCondition(regCluster.Status.Conditions, conditionType); condition != nil {
+			conditions = append(conditions, *condition)
+		}
+	}
+	regCluster.Status.Conditions = conditions
+	// The import command is regenerated for the next ManagedCluster
+	regCluster.Status.ImportCommandRef = corev1.LocalObjectReference{}
+
+	message := fmt.Sprintf("the ManagedCluster was deleted on the hub %s", hubCluster.HubConfig.Name)
+	setConditions(regCluster,
+		newCondition(singaporev1alpha1.RegisteredClusterConditionManagedClusterCreated, metav1.ConditionFalse,
+			singaporev1alpha1.ReasonManagedClusterDeleted, message),
+		newCondition(singaporev1alpha1.RegisteredClusterConditionImportCommandReady, metav1.ConditionFalse,
+			singaporev1alpha1.ReasonManagedClusterDeleted, message),
+		newCondition(singaporev1alpha1.RegisteredClusterConditionSyncTargetsReady, metav1.ConditionFalse,
+			singaporev1alpha1.ReasonManagedClusterDeleted, "the SyncTargets are unschedulable until a ManagedCluster joins the hub"),
+		newCondition(singaporev1alpha1.RegisteredClusterConditionSyncersReady, metav1.ConditionFalse,
+			singaporev1alpha1.ReasonManagedClusterDeleted, message))
+	return nil
+}
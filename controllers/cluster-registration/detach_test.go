@@ -0,0 +1,161 @@
+// Copyright Red Hat
+
+package registeredcluster
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+	singaporev1alpha1 "github.com/stolostron/compute-operator/api/singapore/v1alpha1"
+	"github.com/stolostron/compute-operator/pkg/helpers"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// managedClusterIndexClient emulates the RegisteredCluster index of the hub cache,
+// the fake client ignores the field selectors.
+type managedClusterIndexClient struct {
+	client.Client
+}
+
+func (c *managedClusterIndexClient) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	listOpts := &client.ListOptions{}
+	listOpts.ApplyOptions(opts)
+	if listOpts.FieldSelector != nil {
+		if uid, ok := listOpts.FieldSelector.RequiresExactMatch(managedClusterRegisteredClusterIndex); ok {
+			return c.Client.List(ctx, list, client.MatchingLabels{RegisteredClusterUidLabel: uid})
+		}
+	}
+	return c.Client.List(ctx, list, opts...)
+}
+
+func TestSyncManagedClusterDeletion(t *testing.T) {
+	managedCluster := newTestManagedCluster("cluster1-abcde", false)
+	managedCluster.SetLabels(map[string]string{RegisteredClusterUidLabel: "uid1"})
+	releasedManagedCluster := newTestManagedCluster("cluster1", false)
+	created := newCondition(singaporev1alpha1.RegisteredClusterConditionManagedClusterCreated, metav1.ConditionTrue,
+		singaporev1alpha1.ReasonManagedClusterCreated, "")
+	deleted := newCondition(singaporev1alpha1.RegisteredClusterConditionManagedClusterCreated, metav1.ConditionFalse,
+		singaporev1alpha1.ReasonManagedClusterDeleted, "")
+	tests := []struct {
+		name           string
+		condition      *metav1.Condition
+		action         singaporev1alpha1.ManagedClusterDeletionAction
+		existing       bool
+		hubObjects     []client.Object
+		want           bool
+		wantDetached   bool
+		wantCordoned   bool
+		wantImportKept bool
+	}{
+		{
+			name:           "managedcluster not created yet",
+			want:           false,
+			wantImportKept: true,
+		},
+		{
+			name:           "managedcluster on the hub",
+			condition:      &created,
+			hubObjects:     []client.Object{managedCluster},
+			want:           false,
+			wantImportKept: true,
+		},
+		{
+			name:         "managedcluster deleted",
+			condition:    &created,
+			want:         true,
+			wantDetached: true,
+			wantCordoned: true,
+		},
+		{
+			name:         "managedcluster deleted with recreate",
+			condition:    &created,
+			action:       singaporev1alpha1.ManagedClusterDeletionActionRecreate,
+			want:         false,
+			wantDetached: true,
+			wantCordoned: true,
+		},
+		{
+			name:           "already detached",
+			condition:      &deleted,
+			want:           true,
+			wantDetached:   true,
+			wantImportKept: true,
+		},
+		{
+			name:         "adopted managedcluster deleted",
+			condition:    &created,
+			existing:     true,
+			want:         true,
+			wantDetached: true,
+			wantCordoned: true,
+		},
+		{
+			name:           "adopted managedcluster back on the hub",
+			condition:      &deleted,
+			existing:       true,
+			hubObjects:     []client.Object{releasedManagedCluster},
+			want:           false,
+			wantDetached:   true,
+			wantImportKept: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			regCluster := newTestRegisteredCluster("cluster1", "uid1")
+			regCluster.Spec.Location = []string{"root:location1"}
+			regCluster.Spec.OnManagedClusterDeletion = tt.action
+			if tt.existing {
+				regCluster.Spec.ExistingManagedClusterName = releasedManagedCluster.Name
+			}
+			if tt.condition != nil {
+				regCluster.Status.Conditions = []metav1.Condition{*tt.condition}
+			}
+			regCluster.Status.ImportCommandRef = corev1.LocalObjectReference{Name: "cluster1-import"}
+			syncTarget := newTestSyncTarget("cluster1-abcde", regCluster)
+
+			testScheme := newTestSyncTargetScheme()
+			r := &RegisteredClusterReconciler{
+				ComputeDynamicClient: dynamicfake.NewSimpleDynamicClientWithCustomListKinds(testScheme,
+					map[schema.GroupVersionResource]string{syncTargetGVR: "SyncTargetList"}, syncTarget),
+				Log:           logr.Discard(),
+				EventRecorder: helpers.NewEventRecorder(kubefake.NewSimpleClientset(), scheme, "test"),
+			}
+			hubCluster := newTestHubInstance(tt.hubObjects...)
+			hubCluster.Client = &managedClusterIndexClient{Client: hubCluster.Client}
+
+			got, err := r.syncManagedClusterDeletion(context.TODO(), context.TODO(), regCluster, hubCluster,
+				map[string]*unstructured.Unstructured{"root:location1": syncTarget})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Fatalf("Detached not as expected. Expected %t, actual %t", tt.want, got)
+			}
+			if detached := isDetached(regCluster); detached != tt.wantDetached {
+				t.Fatalf("Detached condition not as expected. Expected %t, actual %t", tt.wantDetached, detached)
+			}
+			if tt.wantDetached && meta.IsStatusConditionTrue(regCluster.Status.Conditions, singaporev1alpha1.RegisteredClusterConditionSyncersReady) {
+				t.Fatal("Expected the syncers not to be ready")
+			}
+			if importKept := len(regCluster.Status.ImportCommandRef.Name) != 0; importKept != tt.wantImportKept {
+				t.Fatalf("Import command not as expected. Expected kept %t, actual %t", tt.wantImportKept, importKept)
+			}
+
+			updated, err := r.ComputeDynamicClient.Resource(syncTargetGVR).Get(context.TODO(), syncTarget.GetName(), metav1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cordoned, _, _ := unstructured.NestedBool(updated.Object, "spec", "unschedulable"); cordoned != tt.wantCordoned {
+				t.Fatalf("SyncTarget unschedulable not as expected. Expected %t, actual %t", tt.wantCordoned, cordoned)
+			}
+		})
+	}
+}
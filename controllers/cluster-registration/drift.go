@@ -0,0 +1,222 @@
+// Copyright Red Hat
+
+package registeredcluster
+
+import (
+	"context"
+	"fmt"
+
+	giterrors "github.com/pkg/errors"
+
+	"github.com/kcp-dev/logicalcluster/v2"
+	singaporev1alpha1 "github.com/stolostron/compute-operator/api/singapore/v1alpha1"
+	"github.com/stolostron/compute-operator/pkg/helpers"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	clusterapiv1 "open-cluster-management.io/api/cluster/v1"
+	manifestworkv1 "open-cluster-management.io/api/work/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// EventReasonDriftDetected is the reason of the events recorded for each drift
+	EventReasonDriftDetected string = "DriftDetected"
+)
+
+// detectDrift compares the objects created for the RegisteredCluster with their actual state,
+// records the differences in the status, the events and the metrics and repairs the ManagedClusterSet membership.
+// The other differences are repaired by the rest of the reconcile.
+// Only the objects of the steps which already succeeded are checked.
+func (r *RegisteredClusterReconciler) detectDrift(computeContext context.Context,
+	ctx context.Context,
+	regCluster *singaporev1alpha1.RegisteredCluster,
+	hubCluster *helpers.HubInstance,
+	syncTargets map[string]*unstructured.Unstructured) error {
+	if !meta.IsStatusConditionTrue(regCluster.Status.Conditions, singaporev1alpha1.RegisteredClusterConditionManagedClusterCreated) {
+		return nil
+	}
+	drift := make([]singaporev1alpha1.DriftRecord, 0)
+
+	managedClusterDrift, managedCluster, err := r.detectManagedClusterDrift(ctx, regCluster, hubCluster)
+	if err != nil {
+		return helpers.NewHubError(hubCluster.HubConfig.Name, err)
+	}
+	drift = append(drift, managedClusterDrift...)
+
+	if meta.IsStatusConditionTrue(regCluster.Status.Conditions, singaporev1alpha1.RegisteredClusterConditionSyncTargetsReady) {
+		for _, locationWorkspace := range regCluster.Spec.Location {
+			locationDrift, err := r.detectLocationDrift(computeContext, ctx, regCluster, locationWorkspace, syncTargets[locationWorkspace], managedCluster, hubCluster)
+			if err != nil {
+				return err
+			}
+			drift = append(drift, locationDrift...)
+		}
+	}
+
+	r.recordDrift(regCluster, drift)
+	return nil
+}
+
+// detectManagedClusterDrift checks the ManagedCluster exists and is a member of the ManagedClusterSet of the workspace,
+// the ManagedCluster is returned if it exists.
+func (r *RegisteredClusterReconciler) detectManagedClusterDrift(ctx context.Context,
+	regCluster *singaporev1alpha1.RegisteredCluster,
+	hubCluster *helpers.HubInstance) ([]singaporev1alpha1.DriftRecord, *clusterapiv1.ManagedCluster, error) {
+	managedClusterList := &clusterapiv1.ManagedClusterList{}
+	if err := hubCluster.Client.List(ctx, managedClusterList,
+		client.MatchingFields{managedClusterRegisteredClusterIndex: string(regCluster.UID)}); err != nil {
+		return nil, nil, giterrors.WithStack(err)
+	}
+	if len(managedClusterList.Items) == 0 {
+		return []singaporev1alpha1.DriftRecord{
+			{
+				Kind:    singaporev1alpha1.DriftKindManagedCluster,
+				Message: fmt.Sprintf("the ManagedCluster was deleted from the hub %s", hubCluster.HubConfig.Name),
+			},
+		}, nil, nil
+	}
+	managedCluster := &managedClusterList.Items[0]
+
+	managedClusterSetList, err := r.getManagedClusterSetList(ctx, hubCluster, regCluster)
+	if err != nil || len(managedClusterSetList.Items) == 0 {
+		return nil, managedCluster, err
+	}
+	mcsName := managedClusterSetList.Items[0].Name
+	clusterSet := managedCluster.GetLabels()[ManagedClusterSetlabel]
+	if clusterSet == mcsName {
+		return nil, managedCluster, nil
+	}
+
+	// Put the ManagedCluster back in the ManagedClusterSet, without the label it would not be found by the reconcile
+	patch := client.MergeFrom(managedCluster.DeepCopy())
+	managedClusterLabels := managedCluster.GetLabels()
+	mergeMap(&managedClusterLabels, map[string]string{ManagedClusterSetlabel: mcsName})
+	managedCluster.SetLabels(managedClusterLabels)
+	if err := hubCluster.Client.Patch(ctx, managedCluster, patch); err != nil {
+		return nil, nil, giterrors.WithStack(err)
+	}
+	return []singaporev1alpha1.DriftRecord{
+		{
+			Kind: singaporev1alpha1.DriftKindManagedClusterSetMembership,
+			Name: managedCluster.Name,
+			Message: fmt.Sprintf("the ManagedCluster was moved from the ManagedClusterSet %s to %q",
+				mcsName, clusterSet),
+		},
+	}, managedCluster, nil
+}
+
+// detectLocationDrift checks the SyncTarget, the kcp-syncer ServiceAccount, RBAC and ManifestWork of a location
+func (r *RegisteredClusterReconciler) detectLocationDrift(computeContext context.Context,
+	ctx context.Context,
+	regCluster *singaporev1alpha1.RegisteredCluster,
+	locationWorkspace string,
+	syncTarget *unstructured.Unstructured,
+	managedCluster *clusterapiv1.ManagedCluster,
+	hubCluster *helpers.HubInstance) ([]singaporev1alpha1.DriftRecord, error) {
+	locationContext := logicalcluster.WithCluster(computeContext, logicalcluster.New(locationWorkspace))
+	if syncTarget == nil {
+		return []singaporev1alpha1.DriftRecord{
+			{
+				Kind:     singaporev1alpha1.DriftKindSyncTarget,
+				Location: locationWorkspace,
+				Message:  "the SyncTarget was deleted",
+			},
+		}, nil
+	}
+
+	drift := make([]singaporev1alpha1.DriftRecord, 0)
+	specModified, err := applySyncTargetSettings(syncTarget.DeepCopy(), getSyncTargetSettings(regCluster, locationWorkspace), locationWorkspace)
+	if err != nil {
+		return nil, giterrors.WithStack(err)
+	}
+	if specModified {
+		drift = append(drift, singaporev1alpha1.DriftRecord{
+			Kind:     singaporev1alpha1.DriftKindSyncTarget,
+			Name:     syncTarget.GetName(),
+			Location: locationWorkspace,
+			Message:  "the SyncTarget spec differs from the RegisteredCluster syncTarget settings",
+		})
+	}
+
+	syncerName := helpers.GetSyncerName(syncTarget)
+	if _, err := r.ComputeKubeClient.CoreV1().ServiceAccounts("default").Get(locationContext, syncerName, metav1.GetOptions{}); err != nil {
+		if !k8serrors.IsNotFound(err) {
+			return nil, giterrors.WithStack(err)
+		}
+		drift = append(drift, singaporev1alpha1.DriftRecord{
+			Kind:     singaporev1alpha1.DriftKindServiceAccount,
+			Name:     syncerName,
+			Location: locationWorkspace,
+			Message:  "the kcp-syncer ServiceAccount was deleted",
+		})
+	}
+
+	_, clusterRoleErr := r.ComputeKubeClient.RbacV1().ClusterRoles().Get(locationContext, syncerName, metav1.GetOptions{})
+	_, clusterRoleBindingErr := r.ComputeKubeClient.RbacV1().ClusterRoleBindings().Get(locationContext, syncerName, metav1.GetOptions{})
+	for _, err := range []error{clusterRoleErr, clusterRoleBindingErr} {
+		if err != nil && !k8serrors.IsNotFound(err) {
+			return nil, giterrors.WithStack(err)
+		}
+	}
+	if k8serrors.IsNotFound(clusterRoleErr) || k8serrors.IsNotFound(clusterRoleBindingErr) {
+		drift = append(drift, singaporev1alpha1.DriftRecord{
+			Kind:     singaporev1alpha1.DriftKindRBAC,
+			Name:     syncerName,
+			Location: locationWorkspace,
+			Message:  "the kcp-syncer ClusterRole or ClusterRoleBinding was deleted",
+		})
+	}
+
+	if managedCluster == nil || !meta.IsStatusConditionTrue(regCluster.Status.Conditions, singaporev1alpha1.RegisteredClusterConditionSyncersReady) {
+		return drift, nil
+	}
+	manifestWork := &manifestworkv1.ManifestWork{}
+	if err := hubCluster.Client.Get(ctx, types.NamespacedName{Namespace: managedCluster.Name, Name: syncerName}, manifestWork); err != nil {
+		if !k8serrors.IsNotFound(err) {
+			return nil, helpers.NewHubError(hubCluster.HubConfig.Name, giterrors.WithStack(err))
+		}
+		drift = append(drift, singaporev1alpha1.DriftRecord{
+			Kind:     singaporev1alpha1.DriftKindManifestWork,
+			Name:     syncerName,
+			Location: locationWorkspace,
+			Message:  fmt.Sprintf("the kcp-syncer ManifestWork was deleted from the hub %s", hubCluster.HubConfig.Name),
+		})
+	}
+	return drift, nil
+}
+
+// recordDrift sets the drift in the status and records an event and a metric per new drift
+func (r *RegisteredClusterReconciler) recordDrift(regCluster *singaporev1alpha1.RegisteredCluster,
+	drift []singaporev1alpha1.DriftRecord) {
+	if len(drift) == 0 {
+		drift = nil
+	}
+	if equality.Semantic.DeepEqual(regCluster.Status.Drift, drift) {
+		return
+	}
+	regCluster.Status.Drift = drift
+	if len(drift) > 0 {
+		now := metav1.Now()
+		regCluster.Status.LastDriftTime = &now
+	}
+	for _, record := range drift {
+		registeredClusterDrift.WithLabelValues(string(record.Kind)).Inc()
+		r.Log.Info("drift detected",
+			"namespace", regCluster.Namespace,
+			"name", regCluster.Name,
+			"kind", record.Kind,
+			"object", record.Name,
+			"location", record.Location,
+			"message", record.Message)
+		message := record.Message
+		if len(record.Location) != 0 {
+			message = fmt.Sprintf("location %s: %s", record.Location, record.Message)
+		}
+		r.EventRecorder.Event(regCluster, corev1.EventTypeWarning, EventReasonDriftDetected, message)
+	}
+}
@@ -0,0 +1,180 @@
+// Copyright Red Hat
+
+package registeredcluster
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+	singaporev1alpha1 "github.com/stolostron/compute-operator/api/singapore/v1alpha1"
+	"github.com/stolostron/compute-operator/pkg/helpers"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clusterapiv1 "open-cluster-management.io/api/cluster/v1"
+	clusterapiv1beta1 "open-cluster-management.io/api/cluster/v1beta1"
+	manifestworkv1 "open-cluster-management.io/api/work/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestDetectDrift(t *testing.T) {
+	regCluster := newTestRegisteredCluster("cluster1", "uid1")
+	regCluster.Spec.Location = []string{"root:location1"}
+	syncTarget := newTestSyncTarget("cluster1-abcde", regCluster)
+	syncTarget.SetUID(types.UID("synctarget-uid1"))
+	syncerName := helpers.GetSyncerName(syncTarget)
+	cordonedSyncTarget := syncTarget.DeepCopy()
+	if err := unstructured.SetNestedField(cordonedSyncTarget.Object, true, "spec", "unschedulable"); err != nil {
+		t.Fatal(err)
+	}
+
+	managedClusterSet := &clusterapiv1beta1.ManagedClusterSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "ws1-abcde"},
+	}
+	managedCluster := newTestManagedCluster("cluster1-abcde", false)
+	managedCluster.SetLabels(map[string]string{
+		RegisteredClusterUidLabel: string(regCluster.UID),
+		ManagedClusterSetlabel:    managedClusterSet.Name,
+	})
+	movedManagedCluster := managedCluster.DeepCopy()
+	movedManagedCluster.Labels[ManagedClusterSetlabel] = "other"
+	manifestWork := &manifestworkv1.ManifestWork{
+		ObjectMeta: metav1.ObjectMeta{Name: syncerName, Namespace: managedCluster.Name},
+	}
+	serviceAccount := &corev1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{Name: syncerName, Namespace: "default"},
+	}
+	clusterRole := &rbacv1.ClusterRole{
+		ObjectMeta: metav1.ObjectMeta{Name: syncerName},
+	}
+	clusterRoleBinding := &rbacv1.ClusterRoleBinding{
+		ObjectMeta: metav1.ObjectMeta{Name: syncerName},
+	}
+
+	tests := []struct {
+		name          string
+		notCreated    bool
+		hubObjects    []client.Object
+		syncTarget    *unstructured.Unstructured
+		kubeObjects   []runtime.Object
+		previousDrift []singaporev1alpha1.DriftRecord
+		wantDrift     []singaporev1alpha1.DriftKind
+	}{
+		{
+			name:       "managedcluster not created yet",
+			notCreated: true,
+		},
+		{
+			name:        "no drift",
+			hubObjects:  []client.Object{managedClusterSet, managedCluster, manifestWork},
+			syncTarget:  syncTarget,
+			kubeObjects: []runtime.Object{serviceAccount, clusterRole, clusterRoleBinding},
+		},
+		{
+			name:        "drift repaired",
+			hubObjects:  []client.Object{managedClusterSet, managedCluster, manifestWork},
+			syncTarget:  syncTarget,
+			kubeObjects: []runtime.Object{serviceAccount, clusterRole, clusterRoleBinding},
+			previousDrift: []singaporev1alpha1.DriftRecord{
+				{Kind: singaporev1alpha1.DriftKindManagedCluster},
+			},
+		},
+		{
+			name:        "managedcluster deleted",
+			hubObjects:  []client.Object{managedClusterSet},
+			syncTarget:  syncTarget,
+			kubeObjects: []runtime.Object{serviceAccount, clusterRole, clusterRoleBinding},
+			wantDrift:   []singaporev1alpha1.DriftKind{singaporev1alpha1.DriftKindManagedCluster},
+		},
+		{
+			name:        "managedcluster moved to another managedclusterset",
+			hubObjects:  []client.Object{managedClusterSet, movedManagedCluster, manifestWork},
+			syncTarget:  syncTarget,
+			kubeObjects: []runtime.Object{serviceAccount, clusterRole, clusterRoleBinding},
+			wantDrift:   []singaporev1alpha1.DriftKind{singaporev1alpha1.DriftKindManagedClusterSetMembership},
+		},
+		{
+			name:        "synctarget deleted",
+			hubObjects:  []client.Object{managedClusterSet, managedCluster, manifestWork},
+			kubeObjects: []runtime.Object{serviceAccount, clusterRole, clusterRoleBinding},
+			wantDrift:   []singaporev1alpha1.DriftKind{singaporev1alpha1.DriftKindSyncTarget},
+		},
+		{
+			name:        "synctarget spec modified",
+			hubObjects:  []client.Object{managedClusterSet, managedCluster, manifestWork},
+			syncTarget:  cordonedSyncTarget,
+			kubeObjects: []runtime.Object{serviceAccount, clusterRole, clusterRoleBinding},
+			wantDrift:   []singaporev1alpha1.DriftKind{singaporev1alpha1.DriftKindSyncTarget},
+		},
+		{
+			name:        "serviceaccount and rbac deleted",
+			hubObjects:  []client.Object{managedClusterSet, managedCluster, manifestWork},
+			syncTarget:  syncTarget,
+			kubeObjects: []runtime.Object{clusterRole},
+			wantDrift:   []singaporev1alpha1.DriftKind{singaporev1alpha1.DriftKindServiceAccount, singaporev1alpha1.DriftKindRBAC},
+		},
+		{
+			name:        "manifestwork deleted",
+			hubObjects:  []client.Object{managedClusterSet, managedCluster},
+			syncTarget:  syncTarget,
+			kubeObjects: []runtime.Object{serviceAccount, clusterRole, clusterRoleBinding},
+			wantDrift:   []singaporev1alpha1.DriftKind{singaporev1alpha1.DriftKindManifestWork},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			regCluster := regCluster.DeepCopy()
+			regCluster.Status.Drift = tt.previousDrift
+			if !tt.notCreated {
+				for _, conditionType := range []string{
+					singaporev1alpha1.RegisteredClusterConditionManagedClusterCreated,
+					singaporev1alpha1.RegisteredClusterConditionSyncTargetsReady,
+					singaporev1alpha1.RegisteredClusterConditionSyncersReady,
+				} {
+					regCluster.Status.Conditions = append(regCluster.Status.Conditions, metav1.Condition{
+						Type:   conditionType,
+						Status: metav1.ConditionTrue,
+					})
+				}
+			}
+			syncTargets := map[string]*unstructured.Unstructured{}
+			if tt.syncTarget != nil {
+				syncTargets["root:location1"] = tt.syncTarget
+			}
+			r := &RegisteredClusterReconciler{
+				ComputeKubeClient: kubefake.NewSimpleClientset(tt.kubeObjects...),
+				Log:               logr.Discard(),
+				EventRecorder:     helpers.NewEventRecorder(kubefake.NewSimpleClientset(), scheme, "test"),
+			}
+			hubCluster := newTestHubInstance(tt.hubObjects...)
+
+			if err := r.detectDrift(context.TODO(), context.TODO(), regCluster, hubCluster, syncTargets); err != nil {
+				t.Fatal(err)
+			}
+			if len(regCluster.Status.Drift) != len(tt.wantDrift) {
+				t.Fatalf("Drift not as expected. Expected %v, actual %v", tt.wantDrift, regCluster.Status.Drift)
+			}
+			for i, kind := range tt.wantDrift {
+				if regCluster.Status.Drift[i].Kind != kind {
+					t.Fatalf("Drift not as expected. Expected %v, actual %v", tt.wantDrift, regCluster.Status.Drift)
+				}
+			}
+			if len(tt.wantDrift) > 0 && regCluster.Status.LastDriftTime == nil {
+				t.Fatal("Expected the last drift time to be set")
+			}
+
+			// The ManagedClusterSet membership is repaired
+			repaired := &clusterapiv1.ManagedCluster{}
+			if err := hubCluster.Client.Get(context.TODO(), client.ObjectKeyFromObject(managedCluster), repaired); err == nil {
+				if clusterSet := repaired.GetLabels()[ManagedClusterSetlabel]; clusterSet != managedClusterSet.Name {
+					t.Fatalf("ManagedClusterSet not as expected. Expected %s, actual %s", managedClusterSet.Name, clusterSet)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+// Copyright Red Hat
+
+package registeredcluster
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	singaporev1alpha1 "github.com/stolostron/compute-operator/api/singapore/v1alpha1"
+	"github.com/stolostron/compute-operator/pkg/helpers"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// errorReasons is the condition reason per error class
+var errorReasons = map[helpers.ErrorClass]string{
+	helpers.ErrorClassTransient:      singaporev1alpha1.ReasonTransientError,
+	helpers.ErrorClassConfiguration:  singaporev1alpha1.ReasonConfigurationError,
+	helpers.ErrorClassPermission:     singaporev1alpha1.ReasonPermissionError,
+	helpers.ErrorClassHubUnreachable: singaporev1alpha1.ReasonHubUnreachableError,
+}
+
+// errorBackoff is the requeue delay per error class,
+// the transient errors are returned to the workqueue which applies its exponential backoff.
+var errorBackoff = map[helpers.ErrorClass]time.Duration{
+	helpers.ErrorClassConfiguration:  5 * time.Minute,
+	helpers.ErrorClassPermission:     2 * time.Minute,
+	helpers.ErrorClassHubUnreachable: 30 * time.Second,
+}
+
+const (
+	// maxWaitBackoff caps the delay between the requeues of a RegisteredCluster waiting for a resource
+	maxWaitBackoff = time.Minute
+)
+
+// reportError writes the error in the condition of the failed step, records a warning event
+// and returns the reconcile result applying the backoff of the error class.
+func (r *RegisteredClusterReconciler) reportError(computeContext context.Context,
+	regCluster *singaporev1alpha1.RegisteredCluster,
+	conditionType string,
+	err error,
+	keysAndValues ...interface{}) (ctrl.Result, error) {
+	class := helpers.ClassifyError(err)
+	reason := errorReasons[class]
+	r.Log.Error(err, "reconcile failed",
+		append([]interface{}{
+			"namespace", regCluster.Namespace,
+			"name", regCluster.Name,
+			"condition", conditionType,
+			"class", class,
+		}, keysAndValues...)...)
+
+	setConditions(regCluster, newCondition(conditionType, metav1.ConditionFalse, reason, err.Error()))
+	r.EventRecorder.Event(regCluster, corev1.EventTypeWarning, reason, fmt.Sprintf("%s: %s", conditionType, err.Error()))
+
+	if backoff, ok := errorBackoff[class]; ok {
+		return ctrl.Result{RequeueAfter: backoff}, nil
+	}
+	return ctrl.Result{}, err
+}
+
+// waitBackoff increases the requeue delay of a RegisteredCluster while it waits for a resource,
+// to avoid requeuing it every second.
+type waitBackoff struct {
+	mu     sync.Mutex
+	delays map[registeredClusterKey]time.Duration
+}
+
+func newWaitBackoff() *waitBackoff {
+	return &waitBackoff{
+		delays: make(map[registeredClusterKey]time.Duration),
+	}
+}
+
+// next returns the next requeue delay, at least minDelay and twice the previous delay
+func (b *waitBackoff) next(key registeredClusterKey, minDelay time.Duration) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delay := b.delays[key] * 2
+	if delay < minDelay {
+		delay = minDelay
+	}
+	if delay > maxWaitBackoff {
+		delay = maxWaitBackoff
+	}
+	b.delays[key] = delay
+	return delay
+}
+
+// reset forgets the delay once the RegisteredCluster no longer waits
+func (b *waitBackoff) reset(key registeredClusterKey) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.delays, key)
+}
@@ -0,0 +1,288 @@
+// Copyright Red Hat
+
+package registeredcluster
+
+import (
+	"context"
+	"time"
+
+	giterrors "github.com/pkg/errors"
+
+	"github.com/kcp-dev/logicalcluster/v2"
+	singaporev1alpha1 "github.com/stolostron/compute-operator/api/singapore/v1alpha1"
+	"github.com/stolostron/compute-operator/pkg/helpers"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	clusterapiv1beta1 "open-cluster-management.io/api/cluster/v1beta1"
+	manifestworkv1 "open-cluster-management.io/api/work/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// isLastRegisteredCluster returns true if no other RegisteredCluster exists in the workspace of the regCluster
+func (r *RegisteredClusterReconciler) isLastRegisteredCluster(computeContext context.Context, regCluster *singaporev1alpha1.RegisteredCluster) (bool, error) {
+	regClusterList := &singaporev1alpha1.RegisteredClusterList{}
+	if err := r.Client.List(computeContext, regClusterList); err != nil {
+		return false, giterrors.WithStack(err)
+	}
+	for _, item := range regClusterList.Items {
+		if item.UID != regCluster.UID {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// deleteManagedClusterSet deletes the bindings of the managedclusterset and then the managedclusterset
+// once all managedclusters left it.
+func (r *RegisteredClusterReconciler) deleteManagedClusterSet(ctx context.Context, hubCluster *helpers.HubInstance, managedClusterSet *clusterapiv1beta1.ManagedClusterSet) (ctrl.Result, error) {
+	bindingList := &clusterapiv1beta1.ManagedClusterSetBindingList{}
+	if err := hubCluster.Client.List(ctx, bindingList,
+		client.MatchingFields{managedClusterSetBindingClusterSetIndex: managedClusterSet.Name}); err != nil {
+		return ctrl.Result{}, giterrors.WithStack(err)
+	}
+	for i := range bindingList.Items {
+		binding := &bindingList.Items[i]
+		if binding.Spec.ClusterSet != managedClusterSet.Name || binding.DeletionTimestamp != nil {
+			continue
+		}
+		r.Log.Info("delete managedclustersetbinding", "name", binding.Name, "namespace", binding.Namespace)
+		if err := hubCluster.Client.Delete(ctx, binding); err != nil && !k8serrors.IsNotFound(err) {
+			return ctrl.Result{}, giterrors.WithStack(err)
+		}
+	}
+
+	if status, ok := helpers.GetConditionStatus(managedClusterSet.Status.Conditions, clusterapiv1beta1.ManagedClusterSetConditionEmpty); !ok || status != metav1.ConditionTrue {
+		r.Log.Info("waiting managedclusterset to be empty",
+			"name", managedClusterSet.Name)
+		return ctrl.Result{Requeue: true, RequeueAfter: 5 * time.Second}, nil
+	}
+
+	if managedClusterSet.DeletionTimestamp == nil {
+		r.Log.Info("delete managedclusterset", "name", managedClusterSet.Name)
+		if err := hubCluster.Client.Delete(ctx, managedClusterSet); err != nil {
+			if k8serrors.IsNotFound(err) {
+				return ctrl.Result{}, nil
+			}
+			return ctrl.Result{}, giterrors.WithStack(err)
+		}
+	}
+	r.Log.Info("waiting managedclusterset to be deleted",
+		"name", managedClusterSet.Name)
+	return ctrl.Result{Requeue: true, RequeueAfter: 5 * time.Second}, nil
+}
+
+// skipManagedClusterSetCleanup annotates the managedclustersets of the workspace of a RegisteredCluster
+// which skips their cleanup, so the orphan scan keeps them too.
+func (r *RegisteredClusterReconciler) skipManagedClusterSetCleanup(ctx context.Context, hubCluster *helpers.HubInstance, regCluster *singaporev1alpha1.RegisteredCluster) error {
+	managedClusterSetList, err := r.getManagedClusterSetList(ctx, hubCluster, regCluster)
+	if err != nil {
+		return err
+	}
+	for i := range managedClusterSetList.Items {
+		managedClusterSet := &managedClusterSetList.Items[i]
+		if isCleanupStepSkipped(managedClusterSet.GetAnnotations(), CleanupStepManagedClusterSet) {
+			continue
+		}
+		patch := client.MergeFrom(managedClusterSet.DeepCopy())
+		annotations := managedClusterSet.GetAnnotations()
+		mergeMap(&annotations, map[string]string{SkipCleanupAnnotation: CleanupStepManagedClusterSet})
+		managedClusterSet.SetAnnotations(annotations)
+		r.Log.Info("skip managedclusterset cleanup", "name", managedClusterSet.Name)
+		if err := hubCluster.Client.Patch(ctx, managedClusterSet, patch); err != nil {
+			return giterrors.WithStack(err)
+		}
+	}
+	return nil
+}
+
+// deleteManifestWorks deletes all ManifestWorks of the regCluster on the hub,
+// including the ones of locations which were removed from the spec.
+func (r *RegisteredClusterReconciler) deleteManifestWorks(ctx context.Context, regCluster *singaporev1alpha1.RegisteredCluster, hubCluster *helpers.HubInstance) (ctrl.Result, error) {
+	manifestWorkList := &manifestworkv1.ManifestWorkList{}
+	if err := hubCluster.Client.List(ctx, manifestWorkList, client.MatchingLabels(map[string]string{
+		RegisteredClusterNamelabel:      regCluster.Name,
+		RegisteredClusterNamespacelabel: regCluster.Namespace,
+	})); err != nil {
+		return ctrl.Result{}, giterrors.WithStack(err)
+	}
+
+	found := false
+	for i := range manifestWorkList.Items {
+		manifestWork := &manifestWorkList.Items[i]
+		if manifestWork.GetAnnotations()[ClusterNameAnnotation] != logicalcluster.From(regCluster).String() {
+			continue
+		}
+		found = true
+		if manifestWork.DeletionTimestamp != nil {
+			continue
+		}
+		r.Log.Info("delete manifestwork", "name", manifestWork.Name, "namespace", manifestWork.Namespace)
+		if err := hubCluster.Client.Delete(ctx, manifestWork); err != nil && !k8serrors.IsNotFound(err) {
+			return ctrl.Result{}, giterrors.WithStack(err)
+		}
+	}
+	if found {
+		r.Log.Info("waiting manifestworks to be deleted",
+			"registeredcluster", regCluster.Name)
+		return ctrl.Result{Requeue: true, RequeueAfter: 1 * time.Second}, nil
+	}
+	return ctrl.Result{}, nil
+}
+
+// deleteImportSecret deletes the secret holding the import command of the regCluster
+func (r *RegisteredClusterReconciler) deleteImportSecret(computeContext context.Context, regCluster *singaporev1alpha1.RegisteredCluster) error {
+	r.Log.Info("delete import secret", "name", regCluster.Name+"-import", "namespace", regCluster.Namespace)
+	err := r.ComputeKubeClient.CoreV1().Secrets(regCluster.Namespace).Delete(computeContext, regCluster.Name+"-import", metav1.DeleteOptions{})
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return giterrors.WithStack(err)
+	}
+	return nil
+}
+
+// collectOrphans removes the resources left on the hubs and in the compute workspaces
+// by RegisteredClusters which no longer exist, for example when their finalizer was removed.
+func (r *RegisteredClusterReconciler) collectOrphans(ctx context.Context) {
+	logger := r.Log.WithName("collectOrphans")
+	logger.V(1).Info("scan orphan resources")
+	for i := range r.HubClusters {
+		hubCluster := &r.HubClusters[i]
+		if err := r.collectOrphanManagedClusterSets(ctx, hubCluster); err != nil {
+			logger.Error(err, "failed to collect orphan managedclustersets", "hub", hubCluster.HubConfig.Name)
+		}
+		if err := r.collectOrphanManifestWorks(ctx, hubCluster); err != nil {
+			logger.Error(err, "failed to collect orphan manifestworks", "hub", hubCluster.HubConfig.Name)
+		}
+	}
+	if err := r.collectOrphanImportSecrets(ctx); err != nil {
+		logger.Error(err, "failed to collect orphan import secrets")
+	}
+}
+
+// collectOrphanManagedClusterSets deletes the managedclustersets of the workspaces without RegisteredClusters
+func (r *RegisteredClusterReconciler) collectOrphanManagedClusterSets(ctx context.Context, hubCluster *helpers.HubInstance) error {
+	managedClusterSetList := &clusterapiv1beta1.ManagedClusterSetList{}
+	if err := hubCluster.Client.List(ctx, managedClusterSetList, client.HasLabels{ManagedClusterSetClustername}); err != nil {
+		return giterrors.WithStack(err)
+	}
+
+	for i := range managedClusterSetList.Items {
+		managedClusterSet := &managedClusterSetList.Items[i]
+		clusterName, ok := managedClusterSet.GetAnnotations()[ClusterNameAnnotation]
+		if !ok {
+			continue
+		}
+		if err := r.collectOrphanManagedClusterSet(ctx, hubCluster, managedClusterSet, clusterName); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// collectOrphanManagedClusterSet deletes the managedclusterset if its workspace has no RegisteredCluster,
+// the workspace is locked to not race with the reconcile of a new RegisteredCluster.
+// A managedclusterset whose skip-cleanup annotation lists the managedclusterset step is kept.
+func (r *RegisteredClusterReconciler) collectOrphanManagedClusterSet(ctx context.Context,
+	hubCluster *helpers.HubInstance,
+	managedClusterSet *clusterapiv1beta1.ManagedClusterSet,
+	clusterName string) error {
+	if isCleanupStepSkipped(managedClusterSet.GetAnnotations(), CleanupStepManagedClusterSet) {
+		r.Log.V(1).Info("managedclusterset cleanup skipped", "name", managedClusterSet.Name, "clusterName", clusterName)
+		return nil
+	}
+	unlock := r.workspaceLocks.Lock(clusterName)
+	defer unlock()
+	regClusterList := &singaporev1alpha1.RegisteredClusterList{}
+	if err := r.Client.List(logicalcluster.WithCluster(ctx, logicalcluster.New(clusterName)), regClusterList); err != nil {
+		return giterrors.WithStack(err)
+	}
+	if len(regClusterList.Items) > 0 {
+		return nil
+	}
+	r.Log.Info("orphan managedclusterset found", "name", managedClusterSet.Name, "clusterName", clusterName)
+	_, err := r.deleteManagedClusterSet(ctx, hubCluster, managedClusterSet)
+	return err
+}
+
+// collectOrphanManifestWorks deletes the ManifestWorks of the RegisteredClusters which no longer exist,
+// a ManifestWork of a deleted RegisteredCluster recreated with the same name is also an orphan.
+func (r *RegisteredClusterReconciler) collectOrphanManifestWorks(ctx context.Context, hubCluster *helpers.HubInstance) error {
+	manifestWorkList := &manifestworkv1.ManifestWorkList{}
+	if err := hubCluster.Client.List(ctx, manifestWorkList, client.HasLabels{RegisteredClusterNamelabel, RegisteredClusterNamespacelabel}); err != nil {
+		return giterrors.WithStack(err)
+	}
+
+	for i := range manifestWorkList.Items {
+		manifestWork := &manifestWorkList.Items[i]
+		clusterName, ok := manifestWork.GetAnnotations()[ClusterNameAnnotation]
+		if !ok || manifestWork.DeletionTimestamp != nil {
+			continue
+		}
+		exists, err := r.registeredClusterExists(ctx, clusterName,
+			manifestWork.GetLabels()[RegisteredClusterNamespacelabel],
+			manifestWork.GetLabels()[RegisteredClusterNamelabel],
+			manifestWork.GetLabels()[RegisteredClusterUidLabel])
+		if err != nil {
+			return err
+		}
+		if exists {
+			continue
+		}
+		r.Log.Info("delete orphan manifestwork", "name", manifestWork.Name, "namespace", manifestWork.Namespace)
+		if err := hubCluster.Client.Delete(ctx, manifestWork); err != nil && !k8serrors.IsNotFound(err) {
+			return giterrors.WithStack(err)
+		}
+	}
+	return nil
+}
+
+// collectOrphanImportSecrets deletes the import secrets of the RegisteredClusters which no longer exist,
+// an import secret of a deleted RegisteredCluster recreated with the same name is also an orphan.
+func (r *RegisteredClusterReconciler) collectOrphanImportSecrets(ctx context.Context) error {
+	secretList, err := r.ComputeKubeClient.CoreV1().Secrets(metav1.NamespaceAll).List(
+		logicalcluster.WithCluster(ctx, logicalcluster.Wildcard),
+		metav1.ListOptions{LabelSelector: RegisteredClusterNamelabel})
+	if err != nil {
+		return giterrors.WithStack(err)
+	}
+
+	for i := range secretList.Items {
+		secret := &secretList.Items[i]
+		clusterName := logicalcluster.From(secret)
+		exists, err := r.registeredClusterExists(ctx, clusterName.String(),
+			secret.Namespace,
+			secret.GetLabels()[RegisteredClusterNamelabel],
+			secret.GetLabels()[RegisteredClusterUidLabel])
+		if err != nil {
+			return err
+		}
+		if exists {
+			continue
+		}
+		r.Log.Info("delete orphan import secret", "name", secret.Name, "namespace", secret.Namespace, "clusterName", clusterName)
+		err = r.ComputeKubeClient.CoreV1().Secrets(secret.Namespace).Delete(logicalcluster.WithCluster(ctx, clusterName),
+			secret.Name,
+			metav1.DeleteOptions{})
+		if err != nil && !k8serrors.IsNotFound(err) {
+			return giterrors.WithStack(err)
+		}
+	}
+	return nil
+}
+
+// registeredClusterExists returns true if the RegisteredCluster exists with the uid,
+// the uid is not compared when empty as the objects created by the previous versions are not labeled with it.
+func (r *RegisteredClusterReconciler) registeredClusterExists(ctx context.Context, clusterName, namespace, name, uid string) (bool, error) {
+	regCluster := &singaporev1alpha1.RegisteredCluster{}
+	err := r.Client.Get(logicalcluster.WithCluster(ctx, logicalcluster.New(clusterName)),
+		types.NamespacedName{Namespace: namespace, Name: name},
+		regCluster)
+	switch {
+	case err == nil:
+		return len(uid) == 0 || string(regCluster.UID) == uid, nil
+	case k8serrors.IsNotFound(err):
+		return false, nil
+	}
+	return false, giterrors.WithStack(err)
+}
@@ -0,0 +1,211 @@
+// Copyright Red Hat
+
+package registeredcluster
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+	singaporev1alpha1 "github.com/stolostron/compute-operator/api/singapore/v1alpha1"
+	"github.com/stolostron/compute-operator/pkg/helpers"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clusterapiv1beta1 "open-cluster-management.io/api/cluster/v1beta1"
+	manifestworkv1 "open-cluster-management.io/api/work/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func newTestRegisteredCluster(name, uid string) *singaporev1alpha1.RegisteredCluster {
+	return &singaporev1alpha1.RegisteredCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "ns1",
+			UID:       types.UID(uid),
+		},
+	}
+}
+
+func newTestGCReconciler(objects ...client.Object) *RegisteredClusterReconciler {
+	return &RegisteredClusterReconciler{
+		Client:         fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build(),
+		Log:            logr.Discard(),
+		workspaceLocks: helpers.NewKeyedMutex(),
+	}
+}
+
+func newTestHubInstance(objects ...client.Object) *helpers.HubInstance {
+	return &helpers.HubInstance{
+		HubConfig: &singaporev1alpha1.HubConfig{ObjectMeta: metav1.ObjectMeta{Name: "hub1"}},
+		Client:    fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build(),
+	}
+}
+
+func TestCollectOrphanManifestWorks(t *testing.T) {
+	tests := []struct {
+		name       string
+		labels     map[string]string
+		wantExists bool
+	}{
+		{
+			name: "same uid",
+			labels: map[string]string{
+				RegisteredClusterNamelabel:      "cluster1",
+				RegisteredClusterNamespacelabel: "ns1",
+				RegisteredClusterUidLabel:       "uid1",
+			},
+			wantExists: true,
+		},
+		{
+			name: "recreated registered cluster",
+			labels: map[string]string{
+				RegisteredClusterNamelabel:      "cluster1",
+				RegisteredClusterNamespacelabel: "ns1",
+				RegisteredClusterUidLabel:       "uid0",
+			},
+			wantExists: false,
+		},
+		{
+			name: "without uid",
+			labels: map[string]string{
+				RegisteredClusterNamelabel:      "cluster1",
+				RegisteredClusterNamespacelabel: "ns1",
+			},
+			wantExists: true,
+		},
+		{
+			name: "deleted registered cluster",
+			labels: map[string]string{
+				RegisteredClusterNamelabel:      "cluster2",
+				RegisteredClusterNamespacelabel: "ns1",
+				RegisteredClusterUidLabel:       "uid2",
+			},
+			wantExists: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manifestWork := &manifestworkv1.ManifestWork{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "syncer1",
+					Namespace:   "managedcluster1",
+					Labels:      tt.labels,
+					Annotations: map[string]string{ClusterNameAnnotation: "root:ws1"},
+				},
+			}
+			r := newTestGCReconciler(newTestRegisteredCluster("cluster1", "uid1"))
+			hubCluster := newTestHubInstance(manifestWork)
+			if err := r.collectOrphanManifestWorks(context.TODO(), hubCluster); err != nil {
+				t.Fatal(err)
+			}
+			err := hubCluster.Client.Get(context.TODO(), client.ObjectKeyFromObject(manifestWork), &manifestworkv1.ManifestWork{})
+			if err != nil && !k8serrors.IsNotFound(err) {
+				t.Fatal(err)
+			}
+			if exists := err == nil; exists != tt.wantExists {
+				t.Fatalf("ManifestWork not as expected. Expected exists %t, actual %t", tt.wantExists, exists)
+			}
+		})
+	}
+}
+
+func TestCollectOrphanImportSecrets(t *testing.T) {
+	tests := []struct {
+		name       string
+		uid        string
+		wantExists bool
+	}{
+		{
+			name:       "same uid",
+			uid:        "uid1",
+			wantExists: true,
+		},
+		{
+			name:       "recreated registered cluster",
+			uid:        "uid0",
+			wantExists: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestGCReconciler(newTestRegisteredCluster("cluster1", "uid1"))
+			r.ComputeKubeClient = kubefake.NewSimpleClientset(&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "cluster1-import",
+					Namespace: "ns1",
+					Labels: map[string]string{
+						RegisteredClusterNamelabel: "cluster1",
+						RegisteredClusterUidLabel:  tt.uid,
+					},
+				},
+			})
+			if err := r.collectOrphanImportSecrets(context.TODO()); err != nil {
+				t.Fatal(err)
+			}
+			_, err := r.ComputeKubeClient.CoreV1().Secrets("ns1").Get(context.TODO(), "cluster1-import", metav1.GetOptions{})
+			if err != nil && !k8serrors.IsNotFound(err) {
+				t.Fatal(err)
+			}
+			if exists := err == nil; exists != tt.wantExists {
+				t.Fatalf("Import secret not as expected. Expected exists %t, actual %t", tt.wantExists, exists)
+			}
+		})
+	}
+}
+
+func TestCollectOrphanManagedClusterSet(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		wantExists  bool
+	}{
+		{
+			name:       "orphan",
+			wantExists: false,
+		},
+		{
+			name:        "cleanup skipped",
+			annotations: map[string]string{SkipCleanupAnnotation: CleanupStepManagedClusterSet},
+			wantExists:  true,
+		},
+		{
+			name:        "all cleanup skipped",
+			annotations: map[string]string{SkipCleanupAnnotation: CleanupStepAll},
+			wantExists:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			managedClusterSet := &clusterapiv1beta1.ManagedClusterSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "ws1-abcde",
+					Annotations: tt.annotations,
+				},
+				Status: clusterapiv1beta1.ManagedClusterSetStatus{
+					Conditions: []metav1.Condition{
+						{
+							Type:   clusterapiv1beta1.ManagedClusterSetConditionEmpty,
+							Status: metav1.ConditionTrue,
+						},
+					},
+				},
+			}
+			r := newTestGCReconciler()
+			hubCluster := newTestHubInstance(managedClusterSet)
+			if err := r.collectOrphanManagedClusterSet(context.TODO(), hubCluster, managedClusterSet, "root:ws1"); err != nil {
+				t.Fatal(err)
+			}
+			err := hubCluster.Client.Get(context.TODO(), client.ObjectKeyFromObject(managedClusterSet), &clusterapiv1beta1.ManagedClusterSet{})
+			if err != nil && !k8serrors.IsNotFound(err) {
+				t.Fatal(err)
+			}
+			if exists := err == nil; exists != tt.wantExists {
+				t.Fatalf("ManagedClusterSet not as expected. Expected exists %t, actual %t", tt.wantExists, exists)
+			}
+		})
+	}
+}
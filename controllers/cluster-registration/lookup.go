@@ -0,0 +1,58 @@
+// Copyright Red Hat
+
+package registeredcluster
+
+import (
+	"context"
+
+	giterrors "github.com/pkg/errors"
+
+	"github.com/stolostron/compute-operator/pkg/helpers"
+	clusterapiv1 "open-cluster-management.io/api/cluster/v1"
+	clusterapiv1beta1 "open-cluster-management.io/api/cluster/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// managedClusterSetWorkspaceIndex indexes the hub managedclustersets by workspace
+	managedClusterSetWorkspaceIndex = "managedClusterSetWorkspace"
+	// managedClusterRegisteredClusterIndex indexes the hub managedclusters by RegisteredCluster uid
+	managedClusterRegisteredClusterIndex = "managedClusterRegisteredCluster"
+	// managedClusterSetBindingClusterSetIndex indexes the hub managedclustersetbindings by managedclusterset
+	managedClusterSetBindingClusterSetIndex = "managedClusterSetBindingClusterSet"
+)
+
+// addHubIndexes adds the field indexes used to look up the hub objects of a RegisteredCluster in the hub cache
+func addHubIndexes(ctx context.Context, hubCluster *helpers.HubInstance) error {
+	if err := hubCluster.Cluster.GetFieldIndexer().IndexField(ctx, &clusterapiv1beta1.ManagedClusterSet{}, managedClusterSetWorkspaceIndex,
+		func(o client.Object) []string {
+			workspace, ok := o.GetLabels()[ManagedClusterSetClustername]
+			if !ok {
+				return nil
+			}
+			return []string{workspace}
+		}); err != nil {
+		return giterrors.WithStack(err)
+	}
+	if err := hubCluster.Cluster.GetFieldIndexer().IndexField(ctx, &clusterapiv1.ManagedCluster{}, managedClusterRegisteredClusterIndex,
+		func(o client.Object) []string {
+			uid, ok := o.GetLabels()[RegisteredClusterUidLabel]
+			if !ok {
+				return nil
+			}
+			return []string{uid}
+		}); err != nil {
+		return giterrors.WithStack(err)
+	}
+	if err := hubCluster.Cluster.GetFieldIndexer().IndexField(ctx, &clusterapiv1beta1.ManagedClusterSetBinding{}, managedClusterSetBindingClusterSetIndex,
+		func(o client.Object) []string {
+			binding, ok := o.(*clusterapiv1beta1.ManagedClusterSetBinding)
+			if !ok {
+				return nil
+			}
+			return []string{binding.Spec.ClusterSet}
+		}); err != nil {
+		return giterrors.WithStack(err)
+	}
+	return nil
+}
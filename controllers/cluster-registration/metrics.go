@@ -0,0 +1,281 @@
+// Copyright Red Hat
+
+package registeredcluster
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/kcp-dev/logicalcluster/v2"
+	"github.com/prometheus/client_golang/prometheus"
+	singaporev1alpha1 "github.com/stolostron/compute-operator/api/singapore/v1alpha1"
+	"github.com/stolostron/compute-operator/pkg/helpers"
+	"k8s.io/apimachinery/pkg/types"
+	clusterapiv1 "open-cluster-management.io/api/cluster/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// Results of a RegisteredCluster deletion exposed by the deletion duration metric
+const (
+	deletionResultCompleted string = "completed"
+	deletionResultForced    string = "forced"
+)
+
+var (
+	registeredClusterJoinDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: helpers.MetricsNamespace,
+			Name:      "registeredcluster_join_duration_seconds",
+			Help:      "Duration between the creation of a RegisteredCluster and its ManagedCluster joining the hub.",
+			Buckets:   []float64{30, 60, 120, 300, 600, 1200, 1800, 3600, 7200, 14400},
+		},
+		[]string{"hub"},
+	)
+	syncerReadyDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: helpers.MetricsNamespace,
+			Name:      "syncer_ready_duration_seconds",
+			Help:      "Duration between the creation of a RegisteredCluster and the kcp-syncer ManifestWork of a location being applied.",
+			Buckets:   []float64{30, 60, 120, 300, 600, 1200, 1800, 3600, 7200, 14400},
+		},
+		[]string{"hub"},
+	)
+	registeredClusterDeletionDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: helpers.MetricsNamespace,
+			Name:      "registeredcluster_deletion_duration_seconds",
+			Help:      "Duration between the deletion request of a RegisteredCluster and the removal of its finalizer.",
+			Buckets:   []float64{5, 15, 30, 60, 120, 300, 600, 1800, 3600},
+		},
+		[]string{"result"},
+	)
+	registeredClusterDrift = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: helpers.MetricsNamespace,
+			Name:      "registeredcluster_drift_total",
+			Help:      "Number of differences found between the desired and the actual state of the RegisteredClusters, by kind of object.",
+		},
+		[]string{"kind"},
+	)
+
+	syncTargetCacheMisses = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: helpers.MetricsNamespace,
+			Name:      "synctarget_cache_misses_total",
+			Help:      "Number of SyncTargets found in the location workspace while missing from the cache.",
+		},
+	)
+
+	registeredClustersDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(helpers.MetricsNamespace, "", "registeredclusters"),
+		"Number of RegisteredClusters by phase, workspace and hub.",
+		[]string{"phase", "workspace", "hub"}, nil)
+	syncerTokenAgeDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(helpers.MetricsNamespace, "", "syncer_token_age_seconds"),
+		"Age of the token used by the kcp-syncer of a RegisteredCluster location.",
+		[]string{"workspace", "namespace", "name", "location"}, nil)
+	hubManagedClustersDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(helpers.MetricsNamespace, "", "hub_managedclusters"),
+		"Number of ManagedClusters on the hub.",
+		[]string{"hub"}, nil)
+	hubMaxManagedClustersDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(helpers.MetricsNamespace, "", "hub_max_managedclusters"),
+		"Maximum number of ManagedClusters configured in the HubConfig.",
+		[]string{"hub"}, nil)
+
+	registeredClusterMetrics = newRegisteredClusterCollector()
+)
+
+func init() {
+	metrics.Registry.MustRegister(
+		registeredClusterJoinDuration,
+		syncerReadyDuration,
+		registeredClusterDeletionDuration,
+		registeredClusterDrift,
+		syncTargetCacheMisses,
+		registeredClusterMetrics,
+	)
+}
+
+// registeredClusterKey identifies a RegisteredCluster across the workspaces
+type registeredClusterKey struct {
+	clusterName string
+	namespace   string
+	name        string
+}
+
+func newRegisteredClusterKey(regCluster *singaporev1alpha1.RegisteredCluster) registeredClusterKey {
+	return registeredClusterKey{
+		clusterName: logicalcluster.From(regCluster).String(),
+		namespace:   regCluster.Namespace,
+		name:        regCluster.Name,
+	}
+}
+
+type registeredClusterState struct {
+	phase string
+	hub   string
+	// syncerTokenCreation is the creation time of the kcp-syncer token per location workspace
+	syncerTokenCreation map[string]time.Time
+}
+
+// registeredClusterCollector exposes the RegisteredClusters known by the reconciler
+type registeredClusterCollector struct {
+	mu     sync.Mutex
+	states map[registeredClusterKey]*registeredClusterState
+	// observedSyncers are the kcp-syncer ManifestWorks already observed in the syncer ready duration
+	observedSyncers map[types.UID]struct{}
+	// startTime avoids observing again the durations of the transitions which happened before a restart
+	startTime time.Time
+}
+
+var _ prometheus.Collector = &registeredClusterCollector{}
+
+func newRegisteredClusterCollector() *registeredClusterCollector {
+	return &registeredClusterCollector{
+		states:          make(map[registeredClusterKey]*registeredClusterState),
+		observedSyncers: make(map[types.UID]struct{}),
+		startTime:       time.Now(),
+	}
+}
+
+func (c *registeredClusterCollector) getState(key registeredClusterKey) *registeredClusterState {
+	state, ok := c.states[key]
+	if !ok {
+		state = &registeredClusterState{syncerTokenCreation: make(map[string]time.Time)}
+		c.states[key] = state
+	}
+	return state
+}
+
+// setPhase records the status phase and the hub of the regCluster
+func (c *registeredClusterCollector) setPhase(regCluster *singaporev1alpha1.RegisteredCluster, hub string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	state := c.getState(newRegisteredClusterKey(regCluster))
+	state.phase = string(regCluster.Status.Phase)
+	state.hub = hub
+}
+
+// setSyncerTokenCreation records the creation time of the kcp-syncer token of a location
+func (c *registeredClusterCollector) setSyncerTokenCreation(regCluster *singaporev1alpha1.RegisteredCluster, locationWorkspace string, creation time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.getState(newRegisteredClusterKey(regCluster)).syncerTokenCreation[locationWorkspace] = creation
+}
+
+// forget removes a RegisteredCluster which no longer exists
+func (c *registeredClusterCollector) forget(key registeredClusterKey) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.states, key)
+}
+
+// forgetShard removes the RegisteredClusters of a shard released by the replica,
+// the replica which acquires the shard exposes them.
+func (c *registeredClusterCollector) forgetShard(shard, shards int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key := range c.states {
+		if helpers.ShardOf(key.clusterName, shards) == shard {
+			delete(c.states, key)
+		}
+	}
+}
+
+// observeJoin observes the join duration of a managedcluster which just joined the hub
+func (c *registeredClusterCollector) observeJoin(regCluster *singaporev1alpha1.RegisteredCluster, managedCluster *clusterapiv1.ManagedCluster, hub string) {
+	for _, condition := range managedCluster.Status.Conditions {
+		if condition.Type != clusterapiv1.ManagedClusterConditionJoined || condition.LastTransitionTime.Time.Before(c.startTime) {
+			continue
+		}
+		registeredClusterJoinDuration.WithLabelValues(hub).
+			Observe(condition.LastTransitionTime.Sub(regCluster.CreationTimestamp.Time).Seconds())
+	}
+}
+
+// observeSyncerReady observes, once per ManifestWork, the duration until the kcp-syncer ManifestWork is applied.
+// It returns true when the ManifestWork is observed for the first time.
+func (c *registeredClusterCollector) observeSyncerReady(regCluster *singaporev1alpha1.RegisteredCluster, manifestWorkUID types.UID, appliedTime time.Time, hub string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.observedSyncers[manifestWorkUID]; ok || appliedTime.Before(c.startTime) {
+		return false
+	}
+	c.observedSyncers[manifestWorkUID] = struct{}{}
+	syncerReadyDuration.WithLabelValues(hub).Observe(appliedTime.Sub(regCluster.CreationTimestamp.Time).Seconds())
+	return true
+}
+
+// observeDeletion observes the deletion duration of a RegisteredCluster whose finalizer is removed
+func observeDeletion(regCluster *singaporev1alpha1.RegisteredCluster, result string) {
+	if regCluster.DeletionTimestamp == nil {
+		return
+	}
+	registeredClusterDeletionDuration.WithLabelValues(result).Observe(time.Since(regCluster.DeletionTimestamp.Time).Seconds())
+}
+
+func (c *registeredClusterCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- registeredClustersDesc
+	ch <- syncerTokenAgeDesc
+}
+
+func (c *registeredClusterCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	type phaseKey struct {
+		phase     string
+		workspace string
+		hub       string
+	}
+	counts := make(map[phaseKey]int)
+	for key, state := range c.states {
+		counts[phaseKey{phase: state.phase, workspace: key.clusterName, hub: state.hub}]++
+		for location, creation := range state.syncerTokenCreation {
+			ch <- prometheus.MustNewConstMetric(syncerTokenAgeDesc, prometheus.GaugeValue,
+				time.Since(creation).Seconds(),
+				key.clusterName, key.namespace, key.name, location)
+		}
+	}
+	for key, count := range counts {
+		ch <- prometheus.MustNewConstMetric(registeredClustersDesc, prometheus.GaugeValue,
+			float64(count),
+			key.phase, key.workspace, key.hub)
+	}
+}
+
+// hubCollector exposes the number of managedclusters of each hub and its configured maximum
+type hubCollector struct {
+	hubClusters []helpers.HubInstance
+}
+
+var _ prometheus.Collector = &hubCollector{}
+
+func (c *hubCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- hubManagedClustersDesc
+	ch <- hubMaxManagedClustersDesc
+}
+
+func (c *hubCollector) Collect(ch chan<- prometheus.Metric) {
+	log := ctrl.Log.WithName("hubCollector")
+	for _, hubCluster := range c.hubClusters {
+		ch <- prometheus.MustNewConstMetric(hubMaxManagedClustersDesc, prometheus.GaugeValue,
+			float64(hubCluster.HubConfig.Spec.MaxManagedCluster),
+			hubCluster.HubConfig.Name)
+		managedClusters, err := helpers.CountManagedClusters(context.TODO(), hubCluster)
+		if err != nil {
+			log.Error(err, "failed to count the managedclusters", "hub", hubCluster.HubConfig.Name)
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(hubManagedClustersDesc, prometheus.GaugeValue,
+			float64(managedClusters),
+			hubCluster.HubConfig.Name)
+	}
+}
+
+// registerHubCollector exposes the managedclusters metrics of the hubs
+func registerHubCollector(hubClusters []helpers.HubInstance) error {
+	return metrics.Registry.Register(&hubCollector{hubClusters: hubClusters})
+}
@@ -0,0 +1,86 @@
+// Copyright Red Hat
+
+package registeredcluster
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kcp-dev/logicalcluster/v2"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	singaporev1alpha1 "github.com/stolostron/compute-operator/api/singapore/v1alpha1"
+	"github.com/stolostron/compute-operator/pkg/helpers"
+)
+
+func newTestWorkspaceRegisteredCluster(workspace, name string, phase singaporev1alpha1.RegisteredClusterPhase) *singaporev1alpha1.RegisteredCluster {
+	regCluster := newTestRegisteredCluster(name, name)
+	regCluster.SetAnnotations(map[string]string{logicalcluster.AnnotationKey: workspace})
+	regCluster.Status.Phase = phase
+	return regCluster
+}
+
+func TestRegisteredClusterCollector(t *testing.T) {
+	c := newRegisteredClusterCollector()
+	cluster1 := newTestWorkspaceRegisteredCluster("root:org:ws1", "cluster1", singaporev1alpha1.RegisteredClusterPhaseReady)
+	cluster2 := newTestWorkspaceRegisteredCluster("root:org:ws1", "cluster2", singaporev1alpha1.RegisteredClusterPhaseReady)
+	cluster3 := newTestWorkspaceRegisteredCluster("root:org:ws2", "cluster3", singaporev1alpha1.RegisteredClusterPhasePending)
+	c.setPhase(cluster1, "hub1")
+	c.setPhase(cluster2, "hub1")
+	c.setPhase(cluster3, "hub2")
+
+	// A phase change replaces the previous phase of the RegisteredCluster
+	cluster2.Status.Phase = singaporev1alpha1.RegisteredClusterPhaseDetached
+	c.setPhase(cluster2, "hub1")
+
+	expected := `
+# HELP compute_operator_registeredclusters Number of RegisteredClusters by phase, workspace and hub.
+# TYPE compute_operator_registeredclusters gauge
+compute_operator_registeredclusters{hub="hub1",phase="Detached",workspace="root:org:ws1"} 1
+compute_operator_registeredclusters{hub="hub1",phase="Ready",workspace="root:org:ws1"} 1
+compute_operator_registeredclusters{hub="hub2",phase="Pending",workspace="root:org:ws2"} 1
+`
+	if err := testutil.CollectAndCompare(c, strings.NewReader(expected), "compute_operator_registeredclusters"); err != nil {
+		t.Fatal(err)
+	}
+
+	c.setSyncerTokenCreation(cluster3, "root:location1", time.Now().Add(-time.Hour))
+	if count := testutil.CollectAndCount(c, "compute_operator_syncer_token_age_seconds"); count != 1 {
+		t.Fatalf("Syncer token age metrics not as expected. Expected 1, actual %d", count)
+	}
+
+	c.forget(newRegisteredClusterKey(cluster2))
+	c.forget(newRegisteredClusterKey(cluster3))
+	expected = `
+# HELP compute_operator_registeredclusters Number of RegisteredClusters by phase, workspace and hub.
+# TYPE compute_operator_registeredclusters gauge
+compute_operator_registeredclusters{hub="hub1",phase="Ready",workspace="root:org:ws1"} 1
+`
+	if err := testutil.CollectAndCompare(c, strings.NewReader(expected), "compute_operator_registeredclusters"); err != nil {
+		t.Fatal(err)
+	}
+	if count := testutil.CollectAndCount(c, "compute_operator_syncer_token_age_seconds"); count != 0 {
+		t.Fatalf("Syncer token age metrics not as expected. Expected 0, actual %d", count)
+	}
+}
+
+func TestRegisteredClusterCollectorForgetShard(t *testing.T) {
+	c := newRegisteredClusterCollector()
+	keys := []registeredClusterKey{
+		{clusterName: "root:org:ws1", namespace: "ns1", name: "cluster1"},
+		{clusterName: "root:org:ws2", namespace: "ns1", name: "cluster1"},
+		{clusterName: "root:org:ws3", namespace: "ns1", name: "cluster1"},
+		{clusterName: "root:org:ws4", namespace: "ns1", name: "cluster1"},
+	}
+	for _, key := range keys {
+		c.getState(key)
+	}
+	shard := helpers.ShardOf(keys[0].clusterName, 2)
+	c.forgetShard(shard, 2)
+	for _, key := range keys {
+		_, ok := c.states[key]
+		if inShard := helpers.ShardOf(key.clusterName, 2) == shard; ok == inShard {
+			t.Fatalf("RegisteredCluster %v not as expected. Expected forgotten %t, actual %t", key, inShard, !ok)
+		}
+	}
+}
@@ -24,6 +24,7 @@ import (
 	"github.com/spf13/pflag"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	apiresource "k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
@@ -477,6 +478,47 @@ var _ = Describe("Process registeredCluster: ", func() {
 			}, 60, 10).Should(BeNil())
 		})
 
+		// Cordon the synctargets through the registeredcluster
+		By("Setting the registeredcluster synctargets unschedulable", func() {
+			Eventually(func() error {
+				err := computeRuntimeWorkspaceClient.Get(context.TODO(),
+					types.NamespacedName{
+						Name:      registeredCluster.Name,
+						Namespace: registeredCluster.Namespace,
+					},
+					registeredCluster)
+				if err != nil {
+					return err
+				}
+				registeredCluster.Spec.SyncTarget.Unschedulable = true
+				return computeRuntimeWorkspaceClient.Update(context.TODO(), registeredCluster)
+			}, 30, 1).Should(BeNil())
+		})
+
+		By("Checking synctargets are unschedulable", func() {
+			Eventually(func() error {
+				for _, locationWorkspace := range registeredCluster.Spec.Location {
+					locationContext := logicalcluster.WithCluster(computeContext, logicalcluster.New(locationWorkspace))
+
+					syncTarget, err := getSyncTarget(locationContext, registeredCluster)
+					if err != nil {
+						return err
+					}
+					if syncTarget == nil {
+						return fmt.Errorf("synctarget not found in the location workspace %s", locationWorkspace)
+					}
+					unschedulable, _, err := unstructured.NestedBool(syncTarget.Object, "spec", "unschedulable")
+					if err != nil {
+						return err
+					}
+					if !unschedulable {
+						return fmt.Errorf("synctarget %s is still schedulable", syncTarget.GetName())
+					}
+				}
+				return nil
+			}, 60, 5).Should(BeNil())
+		})
+
 		// Check if the service account was created in the location workspace
 		By("Checking syncer service account in location workspace", func() {
 			Eventually(func() error {
@@ -563,6 +605,27 @@ var _ = Describe("Process registeredCluster: ", func() {
 			}
 		})
 
+		By("Checking registeredcluster is ready", func() {
+			Eventually(func() error {
+				err := computeRuntimeWorkspaceClient.Get(context.TODO(),
+					types.NamespacedName{
+						Name:      registeredCluster.Name,
+						Namespace: registeredCluster.Namespace,
+					},
+					registeredCluster)
+				if err != nil {
+					return err
+				}
+				if !meta.IsStatusConditionTrue(registeredCluster.Status.Conditions, singaporev1alpha1.RegisteredClusterConditionReady) {
+					return fmt.Errorf("registeredcluster not ready: %v", registeredCluster.Status.Conditions)
+				}
+				if registeredCluster.Status.Phase != singaporev1alpha1.RegisteredClusterPhaseReady {
+					return fmt.Errorf("registeredcluster phase is %s", registeredCluster.Status.Phase)
+				}
+				return nil
+			}, 60, 1).Should(BeNil())
+		})
+
 		// Delete the registeredcluster
 		By("Deleting registeredcluster", func() {
 			Eventually(func() error {
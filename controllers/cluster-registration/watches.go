@@ -0,0 +1,124 @@
+// Copyright Red Hat
+
+package registeredcluster
+
+import (
+	"github.com/kcp-dev/logicalcluster/v2"
+	singaporev1alpha1 "github.com/stolostron/compute-operator/api/singapore/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// newSyncTarget returns an empty SyncTarget to watch the SyncTargets through the APIExport virtual workspace
+func newSyncTarget() *unstructured.Unstructured {
+	syncTarget := &unstructured.Unstructured{}
+	syncTarget.SetGroupVersionKind(syncTargetGVR.GroupVersion().WithKind("SyncTarget"))
+	return syncTarget
+}
+
+// getServiceAccountLabels returns the labels of the kcp-syncer ServiceAccounts of a RegisteredCluster and of their token secrets
+func getServiceAccountLabels(regCluster *singaporev1alpha1.RegisteredCluster) map[string]string {
+	return map[string]string{
+		RegisteredClusterNamelabel:      regCluster.Name,
+		RegisteredClusterNamespacelabel: regCluster.Namespace,
+		RegisteredClusterUidLabel:       string(regCluster.UID),
+	}
+}
+
+// CacheSelectors restricts the compute cache to the objects of the RegisteredClusters
+func CacheSelectors() cache.SelectorsByObject {
+	registeredClusterSelector := labels.NewSelector()
+	requirement, err := labels.NewRequirement(RegisteredClusterUidLabel, selection.Exists, nil)
+	if err == nil {
+		registeredClusterSelector = registeredClusterSelector.Add(*requirement)
+	}
+	return cache.SelectorsByObject{
+		newSyncTarget():          {Label: registeredClusterSelector},
+		&corev1.ServiceAccount{}: {Label: registeredClusterSelector},
+		&corev1.Secret{}: {
+			Label: registeredClusterSelector,
+			Field: fields.OneTermEqualSelector("type", string(corev1.SecretTypeServiceAccountToken)),
+		},
+	}
+}
+
+// mapToRegisteredCluster maps a SyncTarget, a ServiceAccount or a token secret to the RegisteredCluster labeled on it
+func mapToRegisteredCluster(o client.Object) []reconcile.Request {
+	clusterName, ok := o.GetAnnotations()[ClusterNameAnnotation]
+	if !ok {
+		return nil
+	}
+	name, ok := o.GetLabels()[RegisteredClusterNamelabel]
+	if !ok {
+		return nil
+	}
+	namespace, ok := o.GetLabels()[RegisteredClusterNamespacelabel]
+	if !ok {
+		return nil
+	}
+	return []reconcile.Request{
+		{
+			NamespacedName: types.NamespacedName{
+				Name:      name,
+				Namespace: namespace,
+			},
+			ClusterName: clusterName,
+		},
+	}
+}
+
+// syncTargetPredicate ignores the SyncTarget status updates, mainly the syncer heartbeats
+func syncTargetPredicate() predicate.Predicate {
+	return predicate.Or(
+		predicate.GenerationChangedPredicate{},
+		predicate.LabelChangedPredicate{},
+		predicate.AnnotationChangedPredicate{},
+	)
+}
+
+// serviceAccountPredicate processes the changes of the ServiceAccount token secrets
+func serviceAccountPredicate() predicate.Predicate {
+	return predicate.Funcs{
+		UpdateFunc: func(event event.UpdateEvent) bool {
+			new, okNew := event.ObjectNew.(*corev1.ServiceAccount)
+			old, okOld := event.ObjectOld.(*corev1.ServiceAccount)
+			if !okNew || !okOld {
+				return false
+			}
+			if !equality.Semantic.DeepEqual(old.Secrets, new.Secrets) {
+				log := ctrl.Log.WithName("controllers").WithName("RegisteredCluster").WithName("serviceAccountPredicate").
+					WithValues("clusterName", logicalcluster.From(new).String(), "namespace", new.GetNamespace(), "name", new.GetName())
+				log.V(1).Info("process serviceaccount update")
+				return true
+			}
+			return false
+		},
+	}
+}
+
+// tokenSecretPredicate processes the creation and deletion of the kcp-syncer token secrets,
+// they are labeled when their token is read, the new secrets are seen through their ServiceAccount.
+func tokenSecretPredicate() predicate.Predicate {
+	return predicate.And(
+		predicate.NewPredicateFuncs(func(o client.Object) bool {
+			_, ok := o.GetLabels()[RegisteredClusterUidLabel]
+			return ok
+		}),
+		predicate.Funcs{
+			UpdateFunc: func(event event.UpdateEvent) bool {
+				return false
+			},
+		},
+	)
+}
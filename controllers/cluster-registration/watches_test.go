@@ -0,0 +1,151 @@
+// Copyright Red Hat
+
+package registeredcluster
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/stolostron/compute-operator/pkg/helpers"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var testRegisteredClusterLabels = map[string]string{
+	RegisteredClusterNamelabel:      "cluster1",
+	RegisteredClusterNamespacelabel: "ns1",
+	RegisteredClusterUidLabel:       "uid1",
+}
+
+var testRegisteredClusterRequest = reconcile.Request{
+	NamespacedName: types.NamespacedName{Name: "cluster1", Namespace: "ns1"},
+	ClusterName:    "root:ws1",
+}
+
+func TestMapToRegisteredCluster(t *testing.T) {
+	tests := []struct {
+		name        string
+		labels      map[string]string
+		annotations map[string]string
+		want        []reconcile.Request
+	}{
+		{
+			name:        "labeled",
+			labels:      testRegisteredClusterLabels,
+			annotations: map[string]string{ClusterNameAnnotation: "root:ws1"},
+			want:        []reconcile.Request{testRegisteredClusterRequest},
+		},
+		{
+			name:   "without cluster name annotation",
+			labels: testRegisteredClusterLabels,
+		},
+		{
+			name:        "without namespace label",
+			labels:      map[string]string{RegisteredClusterNamelabel: "cluster1"},
+			annotations: map[string]string{ClusterNameAnnotation: "root:ws1"},
+		},
+		{
+			name:        "not labeled",
+			annotations: map[string]string{ClusterNameAnnotation: "root:ws1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			syncTarget := newSyncTarget()
+			syncTarget.SetName("cluster1-abcde")
+			syncTarget.SetLabels(tt.labels)
+			syncTarget.SetAnnotations(tt.annotations)
+			got := mapToRegisteredCluster(syncTarget)
+			if len(got) != len(tt.want) || (len(got) == 1 && got[0] != tt.want[0]) {
+				t.Fatalf("Requests not as expected. Expected %v, actual %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestGetKcpSyncerSAToken(t *testing.T) {
+	regCluster := newTestRegisteredCluster("cluster1", "uid1")
+	syncTarget := newTestSyncTarget("cluster1-abcde", regCluster)
+	saName := helpers.GetSyncerName(syncTarget)
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        saName + "-token-fghij",
+			Namespace:   "default",
+			Annotations: map[string]string{corev1.ServiceAccountNameKey: saName},
+		},
+		Type: corev1.SecretTypeServiceAccountToken,
+		Data: map[string][]byte{"token": []byte("token1")},
+	}
+	sa := &corev1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{Name: saName, Namespace: "default"},
+		Secrets:    []corev1.ObjectReference{{Name: secret.Name}},
+	}
+	r := &RegisteredClusterReconciler{
+		ComputeKubeClient: kubefake.NewSimpleClientset(secret),
+		Log:               logr.Discard(),
+	}
+
+	token, err := r.getKcpSyncerSAToken(context.TODO(), regCluster, "root:location1", syncTarget, sa)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "token1" {
+		t.Fatalf("Token not as expected. Expected token1, actual %s", token)
+	}
+
+	// The token secret is labeled to be mapped to the RegisteredCluster without reading its ServiceAccount
+	labeled, err := r.ComputeKubeClient.CoreV1().Secrets("default").Get(context.TODO(), secret.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !tokenSecretPredicate().Delete(event.DeleteEvent{Object: labeled}) {
+		t.Fatal("Expected the deletion of the labeled token secret to be processed")
+	}
+	got := mapToRegisteredCluster(labeled)
+	if len(got) != 1 || got[0].Name != regCluster.Name || got[0].Namespace != regCluster.Namespace {
+		t.Fatalf("Requests not as expected. Expected %s/%s, actual %v", regCluster.Namespace, regCluster.Name, got)
+	}
+}
+
+func TestTokenSecretPredicate(t *testing.T) {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "kcp-syncer-cluster1-abcde-token-fghij", Namespace: "default"},
+		Type:       corev1.SecretTypeServiceAccountToken,
+	}
+	labeled := secret.DeepCopy()
+	labeled.SetLabels(testRegisteredClusterLabels)
+
+	p := tokenSecretPredicate()
+	if p.Create(event.CreateEvent{Object: secret}) || p.Delete(event.DeleteEvent{Object: secret}) {
+		t.Fatal("Expected the events of the secrets without RegisteredCluster to be ignored")
+	}
+	if !p.Create(event.CreateEvent{Object: labeled}) || !p.Delete(event.DeleteEvent{Object: labeled}) {
+		t.Fatal("Expected the creation and deletion of the labeled token secrets to be processed")
+	}
+	if p.Update(event.UpdateEvent{ObjectOld: secret, ObjectNew: labeled}) {
+		t.Fatal("Expected the updates to be ignored")
+	}
+}
+
+func TestServiceAccountPredicate(t *testing.T) {
+	old := &corev1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{Name: "kcp-syncer-cluster1-abcde", Namespace: "default"},
+	}
+	labeled := old.DeepCopy()
+	labeled.SetLabels(testRegisteredClusterLabels)
+	withSecret := old.DeepCopy()
+	withSecret.Secrets = []corev1.ObjectReference{{Name: "kcp-syncer-cluster1-abcde-token-fghij"}}
+
+	p := serviceAccountPredicate()
+	if p.Update(event.UpdateEvent{ObjectOld: old, ObjectNew: labeled}) {
+		t.Fatal("Expected the update without secret change to be ignored")
+	}
+	if !p.Update(event.UpdateEvent{ObjectOld: old, ObjectNew: withSecret}) {
+		t.Fatal("Expected the update of the token secrets to be processed")
+	}
+}
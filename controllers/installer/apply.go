@@ -0,0 +1,127 @@
+// Copyright Red Hat
+
+package installer
+
+import (
+	"context"
+
+	giterrors "github.com/pkg/errors"
+
+	singaporev1alpha1 "github.com/stolostron/compute-operator/api/singapore/v1alpha1"
+	admissionregistration "k8s.io/api/admissionregistration/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	apiregistrationv1 "k8s.io/kube-aggregator/pkg/apis/apiregistration/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// applyValidatingWebhookConfiguration creates the ValidatingWebhookConfiguration or restores its webhooks and owner,
+// the caBundles injected in the existing webhooks are kept.
+// The ClusterRegistrar is the controller of the ValidatingWebhookConfiguration, so its changes are watched.
+func (r *ClusterRegistrarReconciler) applyValidatingWebhookConfiguration(ctx context.Context,
+	clusterRegistrar *singaporev1alpha1.ClusterRegistrar,
+	required *admissionregistration.ValidatingWebhookConfiguration) error {
+	if err := controllerutil.SetControllerReference(clusterRegistrar, required, r.Scheme); err != nil {
+		return giterrors.WithStack(err)
+	}
+	existing := &admissionregistration.ValidatingWebhookConfiguration{}
+	if err := r.Client.Get(ctx, types.NamespacedName{Name: required.Name}, existing); err != nil {
+		if !errors.IsNotFound(err) {
+			return giterrors.WithStack(err)
+		}
+		r.Log.Info("create validatingwebhookconfiguration", "name", required.Name)
+		return giterrors.WithStack(r.Client.Create(ctx, required, &client.CreateOptions{}))
+	}
+
+	caBundles := make(map[string][]byte, len(existing.Webhooks))
+	for _, webhook := range existing.Webhooks {
+		caBundles[webhook.Name] = webhook.ClientConfig.CABundle
+	}
+	webhooks := make([]admissionregistration.ValidatingWebhook, len(required.Webhooks))
+	for i := range required.Webhooks {
+		webhooks[i] = *required.Webhooks[i].DeepCopy()
+		if len(webhooks[i].ClientConfig.CABundle) == 0 {
+			webhooks[i].ClientConfig.CABundle = caBundles[webhooks[i].Name]
+		}
+	}
+
+	desired := existing.DeepCopy()
+	desired.Webhooks = webhooks
+	mergeObjectMeta(desired, required)
+	if equality.Semantic.DeepEqual(desired, existing) {
+		return nil
+	}
+	r.Log.Info("update validatingwebhookconfiguration", "name", required.Name)
+	return giterrors.WithStack(r.Client.Update(ctx, desired, &client.UpdateOptions{}))
+}
+
+// applyAPIService creates the APIService or restores its spec and owner,
+// the caBundle injected in the existing APIService is kept.
+// The ClusterRegistrar is the controller of the APIService, so its changes are watched.
+func (r *ClusterRegistrarReconciler) applyAPIService(ctx context.Context,
+	clusterRegistrar *singaporev1alpha1.ClusterRegistrar,
+	required *apiregistrationv1.APIService) error {
+	if err := controllerutil.SetControllerReference(clusterRegistrar, required, r.Scheme); err != nil {
+		return giterrors.WithStack(err)
+	}
+	existing := &apiregistrationv1.APIService{}
+	if err := r.Client.Get(ctx, types.NamespacedName{Name: required.Name}, existing); err != nil {
+		if !errors.IsNotFound(err) {
+			return giterrors.WithStack(err)
+		}
+		r.Log.Info("create apiservice", "name", required.Name)
+		return giterrors.WithStack(r.Client.Create(ctx, required, &client.CreateOptions{}))
+	}
+
+	desired := existing.DeepCopy()
+	desired.Spec = *required.Spec.DeepCopy()
+	if len(desired.Spec.CABundle) == 0 {
+		desired.Spec.CABundle = existing.Spec.CABundle
+	}
+	mergeObjectMeta(desired, required)
+	if equality.Semantic.DeepEqual(desired, existing) {
+		return nil
+	}
+	r.Log.Info("update apiservice", "name", required.Name)
+	return giterrors.WithStack(r.Client.Update(ctx, desired, &client.UpdateOptions{}))
+}
+
+// mergeObjectMeta adds the labels, annotations and owner references of the required object to the existing one,
+// an existing owner reference is replaced by the required one of the same owner.
+func mergeObjectMeta(existing, required client.Object) {
+	labels := existing.GetLabels()
+	if labels == nil {
+		labels = make(map[string]string)
+	}
+	for k, v := range required.GetLabels() {
+		labels[k] = v
+	}
+	existing.SetLabels(labels)
+
+	annotations := existing.GetAnnotations()
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+	for k, v := range required.GetAnnotations() {
+		annotations[k] = v
+	}
+	existing.SetAnnotations(annotations)
+
+	ownerReferences := existing.GetOwnerReferences()
+	for _, required := range required.GetOwnerReferences() {
+		found := false
+		for i := range ownerReferences {
+			if ownerReferences[i].UID == required.UID {
+				ownerReferences[i] = required
+				found = true
+				break
+			}
+		}
+		if !found {
+			ownerReferences = append(ownerReferences, required)
+		}
+	}
+	existing.SetOwnerReferences(ownerReferences)
+}
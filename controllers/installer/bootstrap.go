@@ -0,0 +1,209 @@
+// Copyright Red Hat
+
+package installer
+
+import (
+	"context"
+	"strings"
+
+	giterrors "github.com/pkg/errors"
+
+	apimachineryclient "github.com/kcp-dev/apimachinery/pkg/client"
+	"github.com/kcp-dev/logicalcluster/v2"
+	"github.com/stolostron/applier/pkg/apply"
+	singaporev1alpha1 "github.com/stolostron/compute-operator/api/singapore/v1alpha1"
+	croconfig "github.com/stolostron/compute-operator/config"
+	"github.com/stolostron/compute-operator/pkg/helpers"
+	"github.com/stolostron/compute-operator/resources"
+	apiextensionsclient "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+const (
+	// defaultComputeServiceAccountNamespace is the namespace of the compute-operator service account in the compute service
+	defaultComputeServiceAccountNamespace = "default"
+)
+
+// The files applied in the compute service
+var (
+	computeAPIResourceSchemaFile = "apiresourceschema/singapore.open-cluster-management.io_registeredclusters.yaml"
+	computeAPIExportFile         = "compute-templates/virtual-workspace/apiexport.yaml"
+	computeServiceAccountFiles   = []string{
+		"compute-templates/virtual-workspace/namespace.yaml",
+		"compute-templates/virtual-workspace/service_account.yaml",
+		"compute-templates/virtual-workspace/role.yaml",
+		"compute-templates/virtual-workspace/role_binding.yaml",
+	}
+	computeAPIBindingFile = "compute-templates/workspace/apibinding.yaml"
+	// computeUninstallFiles are the objects of the compute service workspace deleted by the uninstall,
+	// the namespace and the APIResourceSchema are left in place as they can be used by other components.
+	computeUninstallFiles = []string{
+		"compute-templates/virtual-workspace/role_binding.yaml",
+		"compute-templates/virtual-workspace/role.yaml",
+		"compute-templates/virtual-workspace/service_account.yaml",
+		computeAPIExportFile,
+	}
+)
+
+// computeTemplateValues are the values of the compute-templates
+type computeTemplateValues struct {
+	IdentityHash                             string
+	ControllerComputeServiceAccountNamespace string
+	ComputeWorkspacePath                     string
+}
+
+// getComputeBootstrapCondition applies the objects of spec.computeService.bootstrap in the compute service
+// and returns the ComputeServiceBootstrapped condition.
+func (r *ClusterRegistrarReconciler) getComputeBootstrapCondition(ctx context.Context,
+	clusterRegistrar *singaporev1alpha1.ClusterRegistrar) metav1.Condition {
+	if err := r.bootstrapComputeService(ctx, clusterRegistrar); err != nil {
+		r.Log.Error(err, "failed to bootstrap the compute service")
+		return newCondition(singaporev1alpha1.ClusterRegistrarConditionComputeServiceBootstrapped, metav1.ConditionFalse,
+			singaporev1alpha1.ReasonComputeBootstrapFailed, err.Error())
+	}
+	return newCondition(singaporev1alpha1.ClusterRegistrarConditionComputeServiceBootstrapped, metav1.ConditionTrue,
+		singaporev1alpha1.ReasonComputeServiceBootstrapped, "the compute-apis APIExport is applied")
+}
+
+// bootstrapComputeService applies the APIResourceSchema, the compute-apis APIExport with the identity hash
+// of the synctargets and the compute-operator service account in the workspace of the compute service kubeconfig,
+// then the APIBindings in the workspaces of spec.computeService.bootstrap.
+func (r *ClusterRegistrarReconciler) bootstrapComputeService(ctx context.Context,
+	clusterRegistrar *singaporev1alpha1.ClusterRegistrar) error {
+	bootstrap := clusterRegistrar.Spec.ComputeService.Bootstrap
+	config, err := r.getComputeConfig(ctx, clusterRegistrar)
+	if err != nil {
+		return err
+	}
+	workspace, err := helpers.WorkspaceFromHost(config.Host)
+	if err != nil {
+		return err
+	}
+	applier, dynamicClient, err := newComputeApplier(ctx, config)
+	if err != nil {
+		return err
+	}
+
+	r.Log.Info("apply the compute apiresourceschema", "workspace", workspace)
+	if _, err := applier.ApplyCustomResources(croconfig.GetScenarioResourcesReader(), nil, false, "", computeAPIResourceSchemaFile); err != nil {
+		return giterrors.WithStack(err)
+	}
+
+	identityHash, err := helpers.GetSyncTargetIdentityHash(ctx, dynamicClient)
+	if err != nil {
+		return err
+	}
+	values := computeTemplateValues{
+		IdentityHash:                             identityHash,
+		ControllerComputeServiceAccountNamespace: bootstrap.ServiceAccountNamespace,
+		ComputeWorkspacePath:                     workspace,
+	}
+	if len(values.ControllerComputeServiceAccountNamespace) == 0 {
+		values.ControllerComputeServiceAccountNamespace = defaultComputeServiceAccountNamespace
+	}
+
+	readerResources := resources.GetScenarioResourcesReader()
+	r.Log.Info("apply the compute-apis apiexport", "workspace", workspace)
+	if _, err := applier.ApplyCustomResources(readerResources, values, false, "", computeAPIExportFile); err != nil {
+		return giterrors.WithStack(err)
+	}
+	if _, err := applier.ApplyDirectly(readerResources, values, false, "", computeServiceAccountFiles...); err != nil {
+		return giterrors.WithStack(err)
+	}
+
+	for _, bindingWorkspace := range bootstrap.Workspaces {
+		r.Log.Info("apply the compute-operator apibinding", "workspace", bindingWorkspace)
+		bindingApplier, _, err := newComputeApplier(ctx, getWorkspaceConfig(config, workspace, bindingWorkspace))
+		if err != nil {
+			return err
+		}
+		if _, err := bindingApplier.ApplyCustomResources(readerResources, values, false, "", computeAPIBindingFile); err != nil {
+			return giterrors.Wrapf(err, "workspace %s", bindingWorkspace)
+		}
+	}
+	return nil
+}
+
+// getComputeObjects templates the objects of spec.computeService.bootstrap deleted by the uninstall,
+// the APIBindings are deleted before the APIExport.
+func (r *ClusterRegistrarReconciler) getComputeObjects(ctx context.Context,
+	clusterRegistrar *singaporev1alpha1.ClusterRegistrar) ([]singaporev1alpha1.InstalledObject, error) {
+	bootstrap := clusterRegistrar.Spec.ComputeService.Bootstrap
+	config, err := r.getComputeConfig(ctx, clusterRegistrar)
+	if err != nil {
+		return nil, err
+	}
+	workspace, err := helpers.WorkspaceFromHost(config.Host)
+	if err != nil {
+		return nil, err
+	}
+	applier := apply.NewApplierBuilder().
+		WithClient(r.KubeClient, r.APIExtensionClient, r.DynamicClient).
+		WithContext(ctx).
+		Build()
+	values := computeTemplateValues{
+		ControllerComputeServiceAccountNamespace: bootstrap.ServiceAccountNamespace,
+		ComputeWorkspacePath:                     workspace,
+	}
+	if len(values.ControllerComputeServiceAccountNamespace) == 0 {
+		values.ControllerComputeServiceAccountNamespace = defaultComputeServiceAccountNamespace
+	}
+
+	readerResources := resources.GetScenarioResourcesReader()
+	objects := make([]singaporev1alpha1.InstalledObject, 0, len(bootstrap.Workspaces)+len(computeUninstallFiles))
+	for _, bindingWorkspace := range bootstrap.Workspaces {
+		object, err := templateInstalledObject(applier, readerResources, values, computeAPIBindingFile)
+		if err != nil {
+			return nil, giterrors.Wrapf(err, "workspace %s", bindingWorkspace)
+		}
+		object.Workspace = bindingWorkspace
+		objects = append(objects, object)
+	}
+	for _, file := range computeUninstallFiles {
+		object, err := templateInstalledObject(applier, readerResources, values, file)
+		if err != nil {
+			return nil, err
+		}
+		object.Workspace = workspace
+		objects = append(objects, object)
+	}
+	return objects, nil
+}
+
+// getWorkspaceConfig returns the config of a workspace of the compute service,
+// the kubeconfig points to the workspace of the APIExport and the other workspaces are on the same server.
+func getWorkspaceConfig(config *rest.Config, configWorkspace, workspace string) *rest.Config {
+	workspaceConfig := rest.CopyConfig(config)
+	workspaceConfig.Host = strings.TrimSuffix(strings.TrimSuffix(config.Host, "/"), logicalcluster.New(configWorkspace).Path())
+	return apimachineryclient.ConfigWithCluster(workspaceConfig, logicalcluster.New(workspace))
+}
+
+// newComputeApplier returns an applier and a dynamic client of the workspace of the config,
+// the compute service objects can't be owned by the ClusterRegistrar.
+func newComputeApplier(ctx context.Context, config *rest.Config) (apply.Applier, dynamic.Interface, error) {
+	kubeClient, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return apply.Applier{}, nil, giterrors.WithStack(err)
+	}
+	dynamicClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return apply.Applier{}, nil, giterrors.WithStack(err)
+	}
+	apiExtensionClient, err := apiextensionsclient.NewForConfig(config)
+	if err != nil {
+		return apply.Applier{}, nil, giterrors.WithStack(err)
+	}
+	applier := apply.NewApplierBuilder().
+		WithClient(kubeClient, apiExtensionClient, dynamicClient).
+		WithContext(ctx).
+		Build()
+	return applier, dynamicClient, nil
+}
+
+// computeBootstrapEnabled returns true when the installer applies the objects of the compute service
+func computeBootstrapEnabled(clusterRegistrar *singaporev1alpha1.ClusterRegistrar) bool {
+	return clusterRegistrar.Spec.ComputeService.Bootstrap != nil
+}
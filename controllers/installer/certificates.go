@@ -0,0 +1,268 @@
+// Copyright Red Hat
+
+package installer
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"time"
+
+	giterrors "github.com/pkg/errors"
+
+	"github.com/openshift/library-go/pkg/crypto"
+	singaporev1alpha1 "github.com/stolostron/compute-operator/api/singapore/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+const (
+	// webhookServingSecretName is the secret of the serving certificate mounted by the webhook
+	webhookServingSecretName = "compute-operator-webhook-service"
+	webhookCASecretName      = "compute-operator-webhook-ca"
+	webhookServiceName       = "compute-operator-webhook-service"
+	webhookCALifetime        = 5 * 365 * 24 * time.Hour
+	webhookServingLifetime   = 365 * 24 * time.Hour
+	// caBundleKey is the key of the CA secret which keeps the previous CAs trusted until they expire
+	caBundleKey = "ca-bundle.crt"
+	// kubeRootCAConfigMapName is the configmap of the kube-apiserver CA published in each namespace
+	kubeRootCAConfigMapName = "kube-root-ca.crt"
+
+	serviceCAServingCertAnnotation    = "service.beta.openshift.io/serving-cert-secret-name"
+	serviceCAInjectCABundleAnnotation = "service.beta.openshift.io/inject-cabundle"
+)
+
+// installerManagedCertificates returns true when the installer provides the certificates of the webhook
+func installerManagedCertificates(clusterRegistrar *singaporev1alpha1.ClusterRegistrar) bool {
+	return clusterRegistrar.Spec.Webhook.CertificateManagement == singaporev1alpha1.CertificateManagementInstaller
+}
+
+// needsRenewal returns true when less than a fifth of the certificate lifetime is left
+func needsRenewal(cert *x509.Certificate, now time.Time) bool {
+	lifetime := cert.NotAfter.Sub(cert.NotBefore)
+	return now.After(cert.NotAfter.Add(-lifetime / 5))
+}
+
+// ensureWebhookCertificates generates or renews the CA and the serving certificate of the webhook
+// and returns the CA bundle of the APIService and the CA bundle of the ValidatingWebhookConfiguration.
+// The ValidatingWebhookConfiguration calls the kube-apiserver which serves the webhook APIService,
+// so it trusts the kube-apiserver CA.
+func (r *ClusterRegistrarReconciler) ensureWebhookCertificates(ctx context.Context,
+	clusterRegistrar *singaporev1alpha1.ClusterRegistrar) ([]byte, []byte, error) {
+	ca, caBundle, err := r.ensureWebhookCA(ctx, clusterRegistrar)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := r.ensureWebhookServingCertificate(ctx, clusterRegistrar, ca); err != nil {
+		return nil, nil, err
+	}
+
+	// The configmaps are read directly, the installer doesn't cache them
+	kubeRootCA, err := r.KubeClient.CoreV1().ConfigMaps(r.ControllerNamespace).Get(ctx, kubeRootCAConfigMapName, metav1.GetOptions{})
+	if err != nil {
+		return nil, nil, giterrors.WithStack(err)
+	}
+	return caBundle, []byte(kubeRootCA.Data["ca.crt"]), nil
+}
+
+// ensureWebhookCA returns the CA of the webhook and the CA bundle to inject, a new CA is generated
+// when it is missing or must be renewed and the previous CA stays in the bundle until it expires.
+func (r *ClusterRegistrarReconciler) ensureWebhookCA(ctx context.Context,
+	clusterRegistrar *singaporev1alpha1.ClusterRegistrar) (*crypto.CA, []byte, error) {
+	now := time.Now()
+	secret, err := r.KubeClient.CoreV1().Secrets(r.ControllerNamespace).Get(ctx, webhookCASecretName, metav1.GetOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return nil, nil, giterrors.WithStack(err)
+	}
+	if err != nil {
+		secret = nil
+	}
+
+	var ca *crypto.CA
+	previousCerts := make([]*x509.Certificate, 0)
+	if secret != nil {
+		ca, err = crypto.GetCAFromBytes(secret.Data[corev1.TLSCertKey], secret.Data[corev1.TLSPrivateKeyKey])
+		if err != nil {
+			r.Log.Info("invalid webhook ca, generating a new one", "error", err.Error())
+			ca = nil
+		}
+		if certs, err := crypto.CertsFromPEM(secret.Data[caBundleKey]); err == nil {
+			previousCerts = certs
+		}
+	}
+	if ca != nil && needsRenewal(ca.Config.Certs[0], now) {
+		r.Log.Info("renewing the webhook ca", "notAfter", ca.Config.Certs[0].NotAfter)
+		ca = nil
+	}
+	if ca == nil {
+		caConfig, err := crypto.MakeSelfSignedCAConfigForDuration(
+			fmt.Sprintf("%s@%d", webhookServiceName, now.Unix()), webhookCALifetime)
+		if err != nil {
+			return nil, nil, giterrors.WithStack(err)
+		}
+		ca = &crypto.CA{Config: caConfig, SerialGenerator: &crypto.RandomSerialGenerator{}}
+	}
+
+	// The current CA first, then the previous CAs which are not expired
+	bundleCerts := []*x509.Certificate{ca.Config.Certs[0]}
+	for _, cert := range previousCerts {
+		if now.After(cert.NotAfter) || cert.Equal(ca.Config.Certs[0]) {
+			continue
+		}
+		bundleCerts = append(bundleCerts, cert)
+	}
+	caBundle, err := crypto.EncodeCertificates(bundleCerts...)
+	if err != nil {
+		return nil, nil, giterrors.WithStack(err)
+	}
+	certPEM, keyPEM, err := ca.Config.GetPEMBytes()
+	if err != nil {
+		return nil, nil, giterrors.WithStack(err)
+	}
+
+	if err := r.applyCertificateSecret(ctx, clusterRegistrar, webhookCASecretName, map[string][]byte{
+		corev1.TLSCertKey:       certPEM,
+		corev1.TLSPrivateKeyKey: keyPEM,
+		caBundleKey:             caBundle,
+	}); err != nil {
+		return nil, nil, err
+	}
+	return ca, caBundle, nil
+}
+
+// ensureWebhookServingCertificate issues the serving certificate of the webhook service when it is missing,
+// not signed by the current CA, not valid for the service or must be renewed.
+func (r *ClusterRegistrarReconciler) ensureWebhookServingCertificate(ctx context.Context,
+	clusterRegistrar *singaporev1alpha1.ClusterRegistrar,
+	ca *crypto.CA) error {
+	hostnames := []string{
+		fmt.Sprintf("%s.%s.svc", webhookServiceName, r.ControllerNamespace),
+		fmt.Sprintf("%s.%s.svc.cluster.local", webhookServiceName, r.ControllerNamespace),
+	}
+	secret, err := r.KubeClient.CoreV1().Secrets(r.ControllerNamespace).Get(ctx, webhookServingSecretName, metav1.GetOptions{})
+	switch {
+	case errors.IsNotFound(err):
+	case err != nil:
+		return giterrors.WithStack(err)
+	default:
+		if r.isServingCertificateValid(secret, ca, hostnames) {
+			return nil
+		}
+	}
+
+	r.Log.Info("issuing the webhook serving certificate", "hostnames", hostnames)
+	servingConfig, err := ca.MakeServerCertForDuration(sets.NewString(hostnames...), webhookServingLifetime)
+	if err != nil {
+		return giterrors.WithStack(err)
+	}
+	certPEM, keyPEM, err := servingConfig.GetPEMBytes()
+	if err != nil {
+		return giterrors.WithStack(err)
+	}
+	return r.applyCertificateSecret(ctx, clusterRegistrar, webhookServingSecretName, map[string][]byte{
+		corev1.TLSCertKey:       certPEM,
+		corev1.TLSPrivateKeyKey: keyPEM,
+	})
+}
+
+func (r *ClusterRegistrarReconciler) isServingCertificateValid(secret *corev1.Secret, ca *crypto.CA, hostnames []string) bool {
+	servingConfig, err := crypto.GetTLSCertificateConfigFromBytes(secret.Data[corev1.TLSCertKey], secret.Data[corev1.TLSPrivateKeyKey])
+	if err != nil {
+		return false
+	}
+	cert := servingConfig.Certs[0]
+	if needsRenewal(cert, time.Now()) {
+		return false
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.Config.Certs[0])
+	if _, err := cert.Verify(x509.VerifyOptions{Roots: roots, KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}}); err != nil {
+		return false
+	}
+	for _, hostname := range hostnames {
+		if err := cert.VerifyHostname(hostname); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// applyCertificateSecret creates or updates a TLS secret owned by the ClusterRegistrar
+func (r *ClusterRegistrarReconciler) applyCertificateSecret(ctx context.Context,
+	clusterRegistrar *singaporev1alpha1.ClusterRegistrar,
+	name string,
+	data map[string][]byte) error {
+	required := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: r.ControllerNamespace,
+		},
+		Type: corev1.SecretTypeTLS,
+		Data: data,
+	}
+	// The secrets are garbage collected with the ClusterRegistrar
+	if err := controllerutil.SetOwnerReference(clusterRegistrar, required, r.Scheme); err != nil {
+		return giterrors.WithStack(err)
+	}
+
+	existing, err := r.KubeClient.CoreV1().Secrets(r.ControllerNamespace).Get(ctx, name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		r.Log.Info("create secret", "name", name, "namespace", r.ControllerNamespace)
+		_, err := r.KubeClient.CoreV1().Secrets(r.ControllerNamespace).Create(ctx, required, metav1.CreateOptions{})
+		return giterrors.WithStack(err)
+	}
+	if err != nil {
+		return giterrors.WithStack(err)
+	}
+	// The type is immutable, a secret of another type is recreated
+	if existing.Type != corev1.SecretTypeTLS {
+		r.Log.Info("recreate secret", "name", name, "namespace", r.ControllerNamespace, "type", existing.Type)
+		if err := r.KubeClient.CoreV1().Secrets(r.ControllerNamespace).Delete(ctx, name, metav1.DeleteOptions{}); err != nil {
+			return giterrors.WithStack(err)
+		}
+		_, err := r.KubeClient.CoreV1().Secrets(r.ControllerNamespace).Create(ctx, required, metav1.CreateOptions{})
+		return giterrors.WithStack(err)
+	}
+
+	desired := existing.DeepCopy()
+	desired.Data = data
+	mergeObjectMeta(desired, required)
+	if equality.Semantic.DeepEqual(desired, existing) {
+		return nil
+	}
+	r.Log.Info("update secret", "name", name, "namespace", r.ControllerNamespace)
+	_, err = r.KubeClient.CoreV1().Secrets(r.ControllerNamespace).Update(ctx, desired, metav1.UpdateOptions{})
+	return giterrors.WithStack(err)
+}
+
+// removeServiceCAAnnotations removes the annotations requesting the certificates from the OpenShift service-ca operator,
+// they are left on the objects applied before the installer managed the certificates.
+func (r *ClusterRegistrarReconciler) removeServiceCAAnnotations(ctx context.Context, objects ...client.Object) error {
+	for _, object := range objects {
+		if err := r.Client.Get(ctx, client.ObjectKeyFromObject(object), object); err != nil {
+			if errors.IsNotFound(err) {
+				continue
+			}
+			return giterrors.WithStack(err)
+		}
+		annotations := object.GetAnnotations()
+		_, servingCert := annotations[serviceCAServingCertAnnotation]
+		_, injectCABundle := annotations[serviceCAInjectCABundleAnnotation]
+		if !servingCert && !injectCABundle {
+			continue
+		}
+		patch := client.MergeFrom(object.DeepCopyObject().(client.Object))
+		delete(annotations, serviceCAServingCertAnnotation)
+		delete(annotations, serviceCAInjectCABundleAnnotation)
+		object.SetAnnotations(annotations)
+		if err := r.Client.Patch(ctx, object, patch); err != nil {
+			return giterrors.WithStack(err)
+		}
+	}
+	return nil
+}
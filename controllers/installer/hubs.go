@@ -0,0 +1,228 @@
+// Copyright Red Hat
+
+package installer
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	giterrors "github.com/pkg/errors"
+
+	"github.com/stolostron/applier/pkg/apply"
+	"github.com/stolostron/applier/pkg/asset"
+	singaporev1alpha1 "github.com/stolostron/compute-operator/api/singapore/v1alpha1"
+	"github.com/stolostron/compute-operator/deploy"
+	apiextensionsclient "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+const (
+	// hubNamespace is the namespace of the hub-side objects installed on each hub
+	hubNamespace = "compute-operator"
+	// hubTimeout bounds the requests to a hub
+	hubTimeout = 10 * time.Second
+	// managedClusterSetCRDName is the CRD of the ManagedClusterSets, installed on the hub by open-cluster-management
+	managedClusterSetCRDName = "managedclustersets.cluster.open-cluster-management.io"
+)
+
+// The files of the HubConfig and of the hub-side prerequisites
+var (
+	hubConfigFile = "hubconfig/hubconfig.yaml"
+	hubFiles      = []string{
+		"hub/namespace.yaml",
+		"hub/service_account.yaml",
+		"hub/clusterrole.yaml",
+		"hub/clusterrole_binding.yaml",
+	}
+	// hubUninstallFiles are the hub-side objects deleted by the uninstall,
+	// the namespace is left in place as it can hold objects of other components.
+	hubUninstallFiles = []string{
+		"hub/clusterrole_binding.yaml",
+		"hub/clusterrole.yaml",
+		"hub/service_account.yaml",
+	}
+)
+
+// hubConfigValues are the values of the HubConfig template
+type hubConfigValues struct {
+	Namespace string
+	Hub       singaporev1alpha1.HubSpec
+}
+
+// hubValues are the values of the hub-side templates
+type hubValues struct {
+	HubNamespace string
+}
+
+// getHubsHash returns the hash of spec.hubs, the manager reads the HubConfigs at startup
+// and is restarted when it changes.
+func getHubsHash(clusterRegistrar *singaporev1alpha1.ClusterRegistrar) string {
+	if len(clusterRegistrar.Spec.Hubs) == 0 {
+		return ""
+	}
+	b, err := json.Marshal(clusterRegistrar.Spec.Hubs)
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("%x", sha256.Sum256(b))[:16]
+}
+
+// applyHubConfigs creates or updates the HubConfigs of spec.hubs in the installer namespace
+func (r *ClusterRegistrarReconciler) applyHubConfigs(clusterRegistrar *singaporev1alpha1.ClusterRegistrar,
+	applier apply.Applier,
+	readerDeploy *asset.ScenarioResourcesReader) error {
+	for _, hub := range clusterRegistrar.Spec.Hubs {
+		r.Log.Info("apply hubconfig", "name", hub.Name)
+		values := hubConfigValues{
+			Namespace: r.ControllerNamespace,
+			Hub:       hub,
+		}
+		if _, err := applier.ApplyCustomResources(readerDeploy, values, false, "", hubConfigFile); err != nil {
+			return giterrors.Wrapf(err, "hub %s", hub.Name)
+		}
+	}
+	return nil
+}
+
+// getHubsCondition installs the prerequisites of the manager on the hubs of spec.hubs
+// and returns the HubsReachable condition listing the hubs which failed.
+func (r *ClusterRegistrarReconciler) getHubsCondition(ctx context.Context,
+	clusterRegistrar *singaporev1alpha1.ClusterRegistrar) metav1.Condition {
+	failures := make([]string, 0)
+	for _, hub := range clusterRegistrar.Spec.Hubs {
+		if err := r.installHubPrerequisites(ctx, hub); err != nil {
+			r.Log.Error(err, "failed to install the hub prerequisites", "hub", hub.Name)
+			failures = append(failures, fmt.Sprintf("hub %s: %s", hub.Name, err.Error()))
+		}
+	}
+	if len(failures) > 0 {
+		return newCondition(singaporev1alpha1.ClusterRegistrarConditionHubsReachable, metav1.ConditionFalse,
+			singaporev1alpha1.ReasonHubUnreachable, strings.Join(failures, "; "))
+	}
+	return newCondition(singaporev1alpha1.ClusterRegistrarConditionHubsReachable, metav1.ConditionTrue,
+		singaporev1alpha1.ReasonHubsReachable, fmt.Sprintf("%d hubs reachable", len(clusterRegistrar.Spec.Hubs)))
+}
+
+// installHubPrerequisites checks the hub is reachable and runs open-cluster-management,
+// then applies the hub-side RBAC of the ManagedClusterSets, ManagedClusters and ManifestWorks.
+func (r *ClusterRegistrarReconciler) installHubPrerequisites(ctx context.Context, hub singaporev1alpha1.HubSpec) error {
+	config, err := r.getHubConfig(ctx, hub)
+	if err != nil {
+		return err
+	}
+	kubeClient, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return giterrors.WithStack(err)
+	}
+	dynamicClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return giterrors.WithStack(err)
+	}
+	apiExtensionClient, err := apiextensionsclient.NewForConfig(config)
+	if err != nil {
+		return giterrors.WithStack(err)
+	}
+
+	if _, err := kubeClient.Discovery().ServerVersion(); err != nil {
+		return fmt.Errorf("%s unreachable: %s", config.Host, err.Error())
+	}
+	crd, err := apiExtensionClient.ApiextensionsV1().CustomResourceDefinitions().Get(ctx, managedClusterSetCRDName, metav1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return fmt.Errorf("CRD %s not found, open-cluster-management is not installed", managedClusterSetCRDName)
+		}
+		return giterrors.WithStack(err)
+	}
+	if !isCRDEstablished(crd) {
+		return fmt.Errorf("CRD %s not established", managedClusterSetCRDName)
+	}
+
+	// The hub-side objects are in another cluster, they can't be owned by the ClusterRegistrar
+	applier := apply.NewApplierBuilder().
+		WithClient(kubeClient, apiExtensionClient, dynamicClient).
+		WithContext(ctx).
+		Build()
+	values := hubValues{
+		HubNamespace: hubNamespace,
+	}
+	if _, err := applier.ApplyDirectly(deploy.GetScenarioResourcesReader(), values, false, "", hubFiles...); err != nil {
+		return giterrors.WithStack(err)
+	}
+	return nil
+}
+
+// getHubObjects templates the hub-side objects deleted by the uninstall on each hub of spec.hubs
+func (r *ClusterRegistrarReconciler) getHubObjects(ctx context.Context,
+	clusterRegistrar *singaporev1alpha1.ClusterRegistrar) ([]singaporev1alpha1.InstalledObject, error) {
+	applier := apply.NewApplierBuilder().
+		WithClient(r.KubeClient, r.APIExtensionClient, r.DynamicClient).
+		WithContext(ctx).
+		Build()
+	values := hubValues{
+		HubNamespace: hubNamespace,
+	}
+	objects := make([]singaporev1alpha1.InstalledObject, 0, len(clusterRegistrar.Spec.Hubs)*len(hubUninstallFiles))
+	for _, hub := range clusterRegistrar.Spec.Hubs {
+		for _, file := range hubUninstallFiles {
+			object, err := templateInstalledObject(applier, deploy.GetScenarioResourcesReader(), values, file)
+			if err != nil {
+				return nil, giterrors.Wrapf(err, "hub %s", hub.Name)
+			}
+			object.Hub = hub.Name
+			objects = append(objects, object)
+		}
+	}
+	return objects, nil
+}
+
+// getHubConfigByName returns the config of the hub of spec.hubs or of the HubConfig with the name,
+// the HubConfig of a hub removed from spec.hubs remains until the recorded objects are deleted.
+func (r *ClusterRegistrarReconciler) getHubConfigByName(ctx context.Context,
+	clusterRegistrar *singaporev1alpha1.ClusterRegistrar,
+	name string) (*rest.Config, error) {
+	for _, hub := range clusterRegistrar.Spec.Hubs {
+		if hub.Name == name {
+			return r.getHubConfig(ctx, hub)
+		}
+	}
+	hubConfig := &singaporev1alpha1.HubConfig{}
+	if err := r.Client.Get(ctx, types.NamespacedName{Namespace: r.ControllerNamespace, Name: name}, hubConfig); err != nil {
+		return nil, giterrors.WithStack(err)
+	}
+	return r.getHubConfig(ctx, singaporev1alpha1.HubSpec{
+		Name:                name,
+		KubeconfigSecretRef: hubConfig.Spec.KubeConfigSecretRef,
+	})
+}
+
+// getHubConfig returns the config of the hub kubeconfig
+func (r *ClusterRegistrarReconciler) getHubConfig(ctx context.Context, hub singaporev1alpha1.HubSpec) (*rest.Config, error) {
+	secretName := hub.KubeconfigSecretRef.Name
+	secret, err := r.KubeClient.CoreV1().Secrets(r.ControllerNamespace).Get(ctx, secretName, metav1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil, fmt.Errorf("secret %s/%s not found", r.ControllerNamespace, secretName)
+		}
+		return nil, err
+	}
+	kubeconfig, ok := secret.Data["kubeconfig"]
+	if !ok {
+		return nil, fmt.Errorf("secret %s/%s missing kubeconfig data", r.ControllerNamespace, secretName)
+	}
+	config, err := clientcmd.RESTConfigFromKubeConfig(kubeconfig)
+	if err != nil {
+		return nil, err
+	}
+	config.Timeout = hubTimeout
+	return config, nil
+}
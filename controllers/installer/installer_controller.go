@@ -4,7 +4,7 @@ package installer
 
 import (
 	"context"
-	"os"
+	"fmt"
 
 	// "fmt"
 	// "os"
@@ -18,6 +18,8 @@ import (
 	rbacv1 "k8s.io/api/rbac/v1"
 	apiextensionsclient "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/dynamic"
@@ -27,19 +29,14 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 
-	// "sigs.k8s.io/controller-runtime/pkg/handler"
-
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
-	// "sigs.k8s.io/controller-runtime/pkg/source"
-
 	"github.com/go-logr/logr"
 	"github.com/stolostron/applier/pkg/apply"
 	"github.com/stolostron/compute-operator/pkg/helpers"
 
 	"github.com/stolostron/applier/pkg/asset"
 	singaporev1alpha1 "github.com/stolostron/compute-operator/api/singapore/v1alpha1"
-	clusterregistrarconfig "github.com/stolostron/compute-operator/config"
 	"github.com/stolostron/compute-operator/deploy"
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
@@ -58,22 +55,96 @@ type ClusterRegistrarReconciler struct {
 	ControllerImage     string
 }
 
+// The files of the installed objects, the uninstall deletes the objects of the same files
+var (
+	managerFiles = []string{
+		"compute-operator/service_account.yaml",
+		"compute-operator/leader_election_role.yaml",
+		"compute-operator/leader_election_role_binding.yaml",
+		"compute-operator/clusterrole.yaml",
+		"compute-operator/clusterrole_binding.yaml",
+	}
+	managerDeploymentFiles = []string{
+		"compute-operator/manager.yaml",
+	}
+	webhookFiles = []string{
+		"webhook/service_account.yaml",
+		"webhook/webhook_clusterrole.yaml",
+		"webhook/webhook_clusterrolebinding.yaml",
+		"webhook/webhook_service.yaml",
+	}
+	webhookDeploymentFiles = []string{
+		"webhook/webhook.yaml",
+	}
+	webhookValidatingConfigFile = "webhook/webhook_validating_config.yaml"
+	webhookAPIServiceFile       = "webhook/webhook_apiservice.yaml"
+)
+
+const (
+	defaultReplicas              int32 = 2
+	defaultManagerLogVerbosity   int32 = 6
+	defaultWebhookLogVerbosity   int32 = 0
+	defaultWebhookTimeoutSeconds int32 = 10
+	defaultManagerShards         int32 = 8
+)
+
+// templateValues are the values of the installed files templates
+type templateValues struct {
+	Image     string
+	Namespace string
+	Manager   managerValues
+	Webhook   webhookValues
+	// InstallerManagedCertificates removes the annotations requesting the certificates from the OpenShift service-ca operator
+	InstallerManagedCertificates bool
+	// HubsHash restarts the manager when spec.hubs changes
+	HubsHash string
+	// DefaultDeletionPolicy restarts the manager when spec.defaultDeletionPolicy changes
+	DefaultDeletionPolicy singaporev1alpha1.DeletionPolicy
+}
+
+// managerValues are the values of the manager templates
+type managerValues struct {
+	operandValues
+	Shards int32
+}
+
+// webhookValues are the values of the webhook templates
+type webhookValues struct {
+	operandValues
+	FailurePolicy  admissionregistration.FailurePolicyType
+	TimeoutSeconds int32
+}
+
+// operandValues are the values of a deployment template, the templates set the defaults of the empty fields
+type operandValues struct {
+	Replicas     int32
+	LogVerbosity int32
+	Resources    *corev1.ResourceRequirements
+	NodeSelector map[string]string
+	Tolerations  []corev1.Toleration
+	Affinity     *corev1.Affinity
+	Env          []corev1.EnvVar
+}
+
 // +kubebuilder:rbac:groups="",resources={namespaces, pods},verbs=get;list;watch
 // +kubebuilder:rbac:groups="",resources={services,serviceaccounts,configmaps},verbs=get;create;update;list;watch;delete
 
 // +kubebuilder:rbac:groups="apps",resources={deployments},verbs=get;create;update;list;watch;delete
 
-// +kubebuilder:rbac:groups="rbac.authorization.k8s.io",resources={clusterroles},verbs=escalate;get;create;update;delete;bind;list
+// +kubebuilder:rbac:groups="rbac.authorization.k8s.io",resources={clusterroles},verbs=escalate;get;create;update;delete;bind;list;watch
 // +kubebuilder:rbac:groups="rbac.authorization.k8s.io",resources={clusterrolebindings},verbs=get;create;update;delete;list;watch
 // +kubebuilder:rbac:groups="rbac.authorization.k8s.io",resources={roles},verbs=get;create;update;delete;escalate;bind;list;watch
 // +kubebuilder:rbac:groups="rbac.authorization.k8s.io",resources={rolebindings},verbs=get;create;update;delete;list;watch
 
 // +kubebuilder:rbac:groups="apiextensions.k8s.io",resources={customresourcedefinitions},verbs=get;create;update;delete
+// +kubebuilder:rbac:groups="apiextensions.k8s.io",resources={customresourcedefinitions/status},verbs=update
+// +kubebuilder:rbac:groups="singapore.open-cluster-management.io",resources={hubconfigs},verbs=get;create;update;patch;list;watch;delete
 
 // +kubebuilder:rbac:groups="admissionregistration.k8s.io",resources={validatingwebhookconfigurations},verbs=get;create;update;list;watch;delete
 // +kubebuilder:rbac:groups="apiregistration.k8s.io",resources={apiservices},verbs=get;create;update;list;watch;delete
 
 // +kubebuilder:rbac:groups="singapore.open-cluster-management.io",resources={clusterregistrars},verbs=get;create;update;list;watch;delete
+// +kubebuilder:rbac:groups="singapore.open-cluster-management.io",resources={clusterregistrars/status},verbs=get;update;patch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -112,9 +183,21 @@ func (r *ClusterRegistrarReconciler) Reconcile(ctx context.Context, req ctrl.Req
 	logger.Info("Running Reconcile for Cluster Registrar")
 
 	if instance.DeletionTimestamp != nil {
-		if err := r.processClusterRegistrarDeletion(ctx, instance); err != nil {
+		// The manager must stay installed to process the finalizers of the RegisteredClusters
+		drained, err := r.drainRegisteredClusters(ctx, instance)
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+		if !drained {
+			return reconcile.Result{RequeueAfter: drainRequeueDelay}, nil
+		}
+		uninstalled, err := r.processClusterRegistrarDeletion(ctx, instance)
+		if err != nil {
 			return reconcile.Result{}, err
 		}
+		if !uninstalled {
+			return reconcile.Result{RequeueAfter: uninstallRequeueDelay}, nil
+		}
 		logger.Info("remove finalizer", "Finalizer:", helpers.ClusterRegistrarFinalizer)
 		controllerutil.RemoveFinalizer(instance, helpers.ClusterRegistrarFinalizer)
 		if err := r.Client.Update(ctx, instance); err != nil {
@@ -130,276 +213,132 @@ func (r *ClusterRegistrarReconciler) Reconcile(ctx context.Context, req ctrl.Req
 		return ctrl.Result{}, giterrors.WithStack(err)
 	}
 
-	if err := r.processClusterRegistrarCreation(ctx, instance); err != nil {
+	installedVersion := instance.Status.InstalledVersion
+	if isDowngrade(installedVersion, helpers.Version) {
+		logger.Info("downgrade blocked", "installedVersion", installedVersion, "version", helpers.Version)
+		return r.updateStatus(ctx, instance,
+			newCondition(singaporev1alpha1.ClusterRegistrarConditionUpgraded, metav1.ConditionFalse,
+				singaporev1alpha1.ReasonDowngradeBlocked,
+				fmt.Sprintf("the installer version %s is older than the installed version %s", helpers.Version, installedVersion)))
+	}
+	upgrading := installedVersion != helpers.Version
+
+	crdsEstablished, err := r.upgradeCRDs(ctx, upgrading)
+	if err != nil {
+		r.reportInstallationError(ctx, instance, getUpgradedCondition(installedVersion, false, "upgrading the CRDs"))
 		return ctrl.Result{}, err
 	}
+	if !crdsEstablished {
+		if _, err := r.updateStatus(ctx, instance, getUpgradedCondition(installedVersion, false, "waiting for the CRDs to be established")); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{RequeueAfter: crdEstablishedRequeueDelay}, nil
+	}
+
+	rolledOut, applied, err := r.processClusterRegistrarCreation(ctx, instance, upgrading)
+	if err != nil {
+		r.reportInstallationError(ctx, instance, getUpgradedCondition(installedVersion, false, "rolling out the deployments"), applied...)
+		return ctrl.Result{}, err
+	}
+
+	return r.updateStatus(ctx, instance, getUpgradedCondition(installedVersion, !upgrading || rolledOut, "rolling out the deployments"), applied...)
+}
 
-	return ctrl.Result{}, nil
+// reportInstallationError reports the state of the components installed so far
+func (r *ClusterRegistrarReconciler) reportInstallationError(ctx context.Context,
+	clusterRegistrar *singaporev1alpha1.ClusterRegistrar,
+	upgraded metav1.Condition,
+	applied ...metav1.Condition) {
+	if _, err := r.updateStatus(ctx, clusterRegistrar, upgraded, applied...); err != nil {
+		r.Log.Error(err, "failed to update the clusterregistrar status")
+	}
 }
 
-func (r *ClusterRegistrarReconciler) processClusterRegistrarCreation(ctx context.Context, clusterRegistrar *singaporev1alpha1.ClusterRegistrar) error {
+// processClusterRegistrarCreation applies the manager and the webhook and returns true when their deployments are rolled out,
+// during an upgrade the webhook is rolled out after the manager.
+// The compute service bootstrap and the prerequisites of the hubs are applied before the manager deployment,
+// their conditions are returned to be recorded by the status.
+func (r *ClusterRegistrarReconciler) processClusterRegistrarCreation(ctx context.Context,
+	clusterRegistrar *singaporev1alpha1.ClusterRegistrar,
+	upgrading bool) (bool, []metav1.Condition, error) {
 	r.Log.Info("processClusterRegistrarCreation", "Name", clusterRegistrar.Name)
 
-	applierBuilder := &apply.ApplierBuilder{}
-	applier := applierBuilder.WithClient(r.KubeClient, r.APIExtensionClient, r.DynamicClient).Build()
+	// The ClusterRegistrar owns the applied objects, their changes are watched and reverted
+	applier := apply.NewApplierBuilder().
+		WithClient(r.KubeClient, r.APIExtensionClient, r.DynamicClient).
+		WithOwner(clusterRegistrar, false, true, r.Scheme).
+		WithContext(ctx).
+		Build()
 	readerDeploy := deploy.GetScenarioResourcesReader()
 
-	//Deploy dex operator
-	files := []string{
-		"compute-operator/service_account.yaml",
-		"compute-operator/leader_election_role.yaml",
-		"compute-operator/leader_election_role_binding.yaml",
-		"compute-operator/clusterrole.yaml",
-		"compute-operator/clusterrole_binding.yaml",
+	// The objects are recorded before they are applied, so the uninstall deletes the partially applied ones
+	if err := r.recordInstalledObjects(ctx, clusterRegistrar); err != nil {
+		return false, nil, err
 	}
 
-	values := struct {
-		Image     string
-		Namespace string
-	}{
-		Image:     r.ControllerImage,
-		Namespace: r.ControllerNamespace,
-	}
+	//Deploy dex operator
+	values := r.getTemplateValues(clusterRegistrar)
 
-	_, err := applier.ApplyDirectly(readerDeploy, values, false, "", files...)
+	_, err := applier.ApplyDirectly(readerDeploy, values, false, "", managerFiles...)
 	if err != nil {
-		return giterrors.WithStack(err)
+		return false, nil, giterrors.WithStack(err)
 	}
 
-	files = []string{
-		"compute-operator/manager.yaml",
+	// The manager reads the HubConfigs at startup
+	if err := r.applyHubConfigs(clusterRegistrar, applier, readerDeploy); err != nil {
+		return false, nil, err
 	}
 
-	_, err = applier.ApplyDeployments(readerDeploy, values, false, "", files...)
-	if err != nil {
-		return giterrors.WithStack(err)
+	// The manager needs the compute-apis APIExport and the hub-side RBAC at startup
+	applied := make([]metav1.Condition, 0)
+	if computeBootstrapEnabled(clusterRegistrar) &&
+		needsApply(clusterRegistrar, singaporev1alpha1.ClusterRegistrarConditionComputeServiceBootstrapped, upgrading) {
+		applied = append(applied, r.getComputeBootstrapCondition(ctx, clusterRegistrar))
 	}
-
-	//Deploy webhook
-	r.Log.Info("checking SKIP_WEBHOOK", "SKIP_WEBHOOK", os.Getenv("SKIP_WEBHOOK"))
-	if os.Getenv("SKIP_WEBHOOK") != "true" {
-		r.Log.Info("deploying webhook")
-		return r.deployWebhook(ctx, applier, readerDeploy, values)
-	} else {
-		r.Log.Info("skipping webhook deployment")
-		return nil
+	if len(clusterRegistrar.Spec.Hubs) > 0 &&
+		needsApply(clusterRegistrar, singaporev1alpha1.ClusterRegistrarConditionHubsReachable, upgrading) {
+		applied = append(applied, r.getHubsCondition(ctx, clusterRegistrar))
 	}
-}
 
-func (r *ClusterRegistrarReconciler) processClusterRegistrarDeletion(ctx context.Context, clusterRegistrar *singaporev1alpha1.ClusterRegistrar) error {
-	r.Log.Info("processClusterRegistrarDeletion", "Name", clusterRegistrar.Name)
-	//Delete operator deployment
-	r.Log.Info("Delete deployment", "name", "compute-operator-manager", "namespace", r.ControllerNamespace)
-	clusterRegOperatorDeployment := &appsv1.Deployment{}
-	err := r.Client.Get(ctx,
-		types.NamespacedName{
-			Name:      "compute-operator-manager",
-			Namespace: r.ControllerNamespace,
-		}, clusterRegOperatorDeployment)
-	switch {
-	case errors.IsNotFound(err):
-	case err == nil:
-		if err := r.Client.Delete(ctx, clusterRegOperatorDeployment, &client.DeleteOptions{}); err != nil {
-			return giterrors.WithStack(err)
-		}
-	default:
-		return giterrors.WithStack(err)
+	_, err = applier.ApplyDeployments(readerDeploy, values, false, "", managerDeploymentFiles...)
+	if err != nil {
+		return false, applied, giterrors.WithStack(err)
 	}
-
-	r.Log.Info("Delete roleBinding", "name", "compute-operator-leader-election-rolebinding", "namespace", r.ControllerNamespace)
-	clusterRegOperatorLeaderElectionRoleBinding := &rbacv1.RoleBinding{}
-	err = r.Client.Get(ctx,
-		types.NamespacedName{Name: "compute-operator-leader-election-rolebinding", Namespace: r.ControllerNamespace},
-		clusterRegOperatorLeaderElectionRoleBinding)
-	switch {
-	case errors.IsNotFound(err):
-	case err == nil:
-		if err := r.Client.Delete(ctx, clusterRegOperatorLeaderElectionRoleBinding, &client.DeleteOptions{}); err != nil {
-			return giterrors.WithStack(err)
-		}
-	default:
-		return giterrors.WithStack(err)
+	rolledOut, err := r.isRolledOut(ctx, managerDeploymentName)
+	if err != nil {
+		return false, applied, err
 	}
 
-	r.Log.Info("Delete ClusterRoleBinding", "name", "compute-operator-manager-rolebinding", "namespace", r.ControllerNamespace)
-	clusterRegOperatorClusterRoleBinding := &rbacv1.ClusterRoleBinding{}
-	err = r.Client.Get(ctx,
-		types.NamespacedName{Name: "compute-operator-manager-rolebinding", Namespace: r.ControllerNamespace},
-		clusterRegOperatorClusterRoleBinding)
-	switch {
-	case errors.IsNotFound(err):
-	case err == nil:
-		if err := r.Client.Delete(ctx, clusterRegOperatorClusterRoleBinding, &client.DeleteOptions{}); err != nil {
-			return giterrors.WithStack(err)
-		}
-	default:
-		return giterrors.WithStack(err)
+	//Deploy webhook
+	if !webhookEnabled(clusterRegistrar) {
+		r.Log.Info("skipping webhook deployment")
+		return rolledOut, applied, nil
 	}
-
-	r.Log.Info("Delete serviceAccount", "name", "compute-operator-manager", "namespace", r.ControllerNamespace)
-	clusterRegOperatorServiceAccount := &corev1.ServiceAccount{}
-	err = r.Client.Get(ctx,
-		types.NamespacedName{Name: "compute-operator-manager", Namespace: r.ControllerNamespace},
-		clusterRegOperatorServiceAccount)
-	switch {
-	case errors.IsNotFound(err):
-	case err == nil:
-		if err := r.Client.Delete(ctx, clusterRegOperatorServiceAccount, &client.DeleteOptions{}); err != nil {
-			return giterrors.WithStack(err)
-		}
-	default:
-		return giterrors.WithStack(err)
+	if upgrading && !rolledOut {
+		r.Log.Info("waiting for the manager rollout before deploying the webhook")
+		return false, applied, nil
 	}
-
-	r.Log.Info("Delete ClusterRole", "name", "compute-operator-manager-role", "namespace", r.ControllerNamespace)
-	clusterRegOperatorClusterRole := &rbacv1.ClusterRole{}
-	err = r.Client.Get(ctx,
-		types.NamespacedName{Name: "compute-operator-manager-role"},
-		clusterRegOperatorClusterRole)
-	switch {
-	case errors.IsNotFound(err):
-	case err == nil:
-		if err := r.Client.Delete(ctx, clusterRegOperatorClusterRole, &client.DeleteOptions{}); err != nil {
-			return giterrors.WithStack(err)
-		}
-	default:
-		return giterrors.WithStack(err)
+	r.Log.Info("deploying webhook")
+	if err := r.deployWebhook(ctx, clusterRegistrar, applier, readerDeploy, values); err != nil {
+		return false, applied, err
 	}
-
-	r.Log.Info("Delete Role", "name", "leader-election-operator-role", "namespace", r.ControllerNamespace)
-	clusterRegOperatorRole := &rbacv1.Role{}
-	err = r.Client.Get(ctx,
-		types.NamespacedName{Name: "leader-election-operator-role", Namespace: r.ControllerNamespace},
-		clusterRegOperatorRole)
-	switch {
-	case errors.IsNotFound(err):
-	case err == nil:
-		if err := r.Client.Delete(ctx, clusterRegOperatorRole, &client.DeleteOptions{}); err != nil {
-			return giterrors.WithStack(err)
-		}
-	default:
-		return giterrors.WithStack(err)
+	webhookRolledOut, err := r.isRolledOut(ctx, webhookDeploymentName)
+	if err != nil {
+		return false, applied, err
 	}
+	return rolledOut && webhookRolledOut, applied, nil
+}
 
-	// // Do not delete webhook on functional test as it is not installed
-	// pod := &corev1.Pod{}
-	// if err := r.Client.Get(ctx, types.NamespacedName{Name: podName, Namespace: r.ControllerNamespace}, pod); err != nil {
-	// 	return err
-	// }
-	// r.Log.Info("Pod", "Name", pod.Name, "Namespace", pod.Namespace, "deletiontimeStamp", pod.DeletionTimestamp)
-	// if strings.Contains(pod.Spec.Containers[0].Image, "coverage") {
-	// 	return nil
-	// }
-
-	if os.Getenv("SKIP_WEBHOOK") != "true" {
-		//Delete webhook
-		r.Log.Info("Delete Deployment", "name", "compute-webhook-service", "namespace", r.ControllerNamespace)
-		webhookDeployment := &appsv1.Deployment{}
-		err = r.Client.Get(ctx,
-			types.NamespacedName{Name: "compute-webhook-service", Namespace: r.ControllerNamespace},
-			webhookDeployment)
-		switch {
-		case errors.IsNotFound(err):
-		case err == nil:
-			if err := r.Client.Delete(ctx, webhookDeployment, &client.DeleteOptions{}); err != nil {
-				return giterrors.WithStack(err)
-			}
-		default:
-			return giterrors.WithStack(err)
-		}
-
-		r.Log.Info("Delete APIService", "name", "v1alpha1.admission.singapore.open-cluster-management.io")
-		apiService := &apiregistrationv1.APIService{}
-		err = r.Client.Get(ctx,
-			types.NamespacedName{Name: "v1alpha1.admission.singapore.open-cluster-management.io"},
-			apiService)
-		switch {
-		case errors.IsNotFound(err):
-		case err == nil:
-			if err := r.Client.Delete(ctx, apiService, &client.DeleteOptions{}); err != nil {
-				return giterrors.WithStack(err)
-			}
-		default:
-			return giterrors.WithStack(err)
-		}
-
-		r.Log.Info("Delete ClusterRoleBinding", "name", "compute-webhook-service")
-		webHookClusterRoleBinding := &rbacv1.ClusterRoleBinding{}
-		err = r.Client.Get(ctx,
-			types.NamespacedName{Name: "compute-webhook-service"},
-			webHookClusterRoleBinding)
-		switch {
-		case errors.IsNotFound(err):
-		case err == nil:
-			if err := r.Client.Delete(ctx, webHookClusterRoleBinding, &client.DeleteOptions{}); err != nil {
-				return giterrors.WithStack(err)
-			}
-		default:
-			return giterrors.WithStack(err)
-		}
-
-		r.Log.Info("Delete ClusterRole", "name", "compute-webhook-service")
-		webHookClusterRole := &rbacv1.ClusterRole{}
-		err = r.Client.Get(ctx,
-			types.NamespacedName{Name: "compute-webhook-service"},
-			webHookClusterRole)
-		switch {
-		case errors.IsNotFound(err):
-		case err == nil:
-			if err := r.Client.Delete(ctx, webHookClusterRole, &client.DeleteOptions{}); err != nil {
-				return giterrors.WithStack(err)
-			}
-		default:
-			return giterrors.WithStack(err)
-		}
-
-		r.Log.Info("Delete serviceAccount", "name", "compute-webhook-service", "namespace", r.ControllerNamespace)
-		webHookServiceAccount := &corev1.ServiceAccount{}
-		err = r.Client.Get(ctx,
-			types.NamespacedName{Name: "compute-webhook-service", Namespace: r.ControllerNamespace},
-			webHookServiceAccount)
-		switch {
-		case errors.IsNotFound(err):
-		case err == nil:
-			if err := r.Client.Delete(ctx, webHookServiceAccount, &client.DeleteOptions{}); err != nil {
-				return giterrors.WithStack(err)
-			}
-		default:
-			return giterrors.WithStack(err)
-		}
-
-		r.Log.Info("Delete Service", "name", "compute-webhook-service", "namespace", r.ControllerNamespace)
-		service := &corev1.Service{}
-		err = r.Client.Get(ctx,
-			types.NamespacedName{Name: "compute-webhook-service", Namespace: r.ControllerNamespace},
-			service)
-		switch {
-		case errors.IsNotFound(err):
-		case err == nil:
-			if err := r.Client.Delete(ctx, service, &client.DeleteOptions{}); err != nil {
-				return giterrors.WithStack(err)
-			}
-		default:
-			return giterrors.WithStack(err)
-		}
-
-		r.Log.Info("Delete ValidatingWebhookConfiguration", "name", "compute-webhook-service", "namespace", r.ControllerNamespace)
-		validationWebhook := &admissionregistration.ValidatingWebhookConfiguration{}
-		err = r.Client.Get(ctx,
-			types.NamespacedName{Name: "compute-webhook-service", Namespace: r.ControllerNamespace},
-			validationWebhook)
-		switch {
-		case errors.IsNotFound(err):
-		case err == nil:
-			if err := r.Client.Delete(ctx, validationWebhook, &client.DeleteOptions{}); err != nil {
-				return giterrors.WithStack(err)
-			}
-		default:
-			return giterrors.WithStack(err)
-		}
+// needsApply returns true when the step of the condition must be applied, it is skipped once it succeeded
+// for the current generation unless an upgrade is in progress.
+func needsApply(clusterRegistrar *singaporev1alpha1.ClusterRegistrar, conditionType string, upgrading bool) bool {
+	if upgrading {
+		return true
 	}
-
-	return nil
+	condition := meta.FindStatusCondition(clusterRegistrar.Status.Conditions, conditionType)
+	return condition == nil ||
+		condition.Status != metav1.ConditionTrue ||
+		condition.ObservedGeneration != clusterRegistrar.Generation
 }
 
 // SetupWithManager sets up the controller with the Manager.
@@ -417,55 +356,122 @@ func (r *ClusterRegistrarReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		return giterrors.WithStack(err)
 	}
 
-	//Install CRD
-	applierBuilder := &apply.ApplierBuilder{}
-	applier := applierBuilder.WithClient(r.KubeClient, r.APIExtensionClient, r.DynamicClient).Build()
-
-	readerClusterRegOperator := clusterregistrarconfig.GetScenarioResourcesReader()
-
-	files := []string{
-		"crd/singapore.open-cluster-management.io_clusterregistrars.yaml",
-		"crd/singapore.open-cluster-management.io_registeredclusters.yaml",
-		"crd/singapore.open-cluster-management.io_hubconfigs.yaml",
-	}
-	if _, err := applier.ApplyDirectly(readerClusterRegOperator, nil, false, "", files...); err != nil {
-		return giterrors.WithStack(err)
+	// Install the missing CRDs, the ClusterRegistrar CRD is required to start the controller
+	if err := r.createMissingCRDs(context.TODO()); err != nil {
+		return err
 	}
 
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&singaporev1alpha1.ClusterRegistrar{}).
+		// The deployment status reflects the pods availability
+		Owns(&appsv1.Deployment{}).
+		Owns(&corev1.ServiceAccount{}).
+		Owns(&corev1.Service{}).
+		Owns(&rbacv1.ClusterRole{}).
+		Owns(&rbacv1.ClusterRoleBinding{}).
+		Owns(&rbacv1.Role{}).
+		Owns(&rbacv1.RoleBinding{}).
+		Owns(&admissionregistration.ValidatingWebhookConfiguration{}).
+		Owns(&apiregistrationv1.APIService{}).
+		Owns(&singaporev1alpha1.HubConfig{}).
 		Complete(r)
 }
 
+// getTemplateValues returns the values of the installed files templates
+func (r *ClusterRegistrarReconciler) getTemplateValues(clusterRegistrar *singaporev1alpha1.ClusterRegistrar) templateValues {
+	return templateValues{
+		Image:     r.ControllerImage,
+		Namespace: r.ControllerNamespace,
+		Manager:   getManagerValues(clusterRegistrar.Spec.Manager),
+		Webhook:   getWebhookValues(clusterRegistrar.Spec.Webhook),
+
+		InstallerManagedCertificates: installerManagedCertificates(clusterRegistrar),
+		HubsHash:                     getHubsHash(clusterRegistrar),
+		DefaultDeletionPolicy:        clusterRegistrar.Spec.DefaultDeletionPolicy,
+	}
+}
+
+func getManagerValues(manager singaporev1alpha1.ManagerSpec) managerValues {
+	values := managerValues{
+		operandValues: getOperandValues(manager.OperandSpec, defaultManagerLogVerbosity),
+		Shards:        defaultManagerShards,
+	}
+	if manager.Shards != nil {
+		values.Shards = *manager.Shards
+	}
+	return values
+}
+
+func getWebhookValues(webhook singaporev1alpha1.WebhookSpec) webhookValues {
+	values := webhookValues{
+		operandValues:  getOperandValues(webhook.OperandSpec, defaultWebhookLogVerbosity),
+		FailurePolicy:  admissionregistration.Fail,
+		TimeoutSeconds: defaultWebhookTimeoutSeconds,
+	}
+	if webhook.FailurePolicy != nil {
+		values.FailurePolicy = *webhook.FailurePolicy
+	}
+	if webhook.TimeoutSeconds != nil {
+		values.TimeoutSeconds = *webhook.TimeoutSeconds
+	}
+	return values
+}
+
+func getOperandValues(operand singaporev1alpha1.OperandSpec, defaultLogVerbosity int32) operandValues {
+	values := operandValues{
+		Replicas:     defaultReplicas,
+		LogVerbosity: defaultLogVerbosity,
+		Resources:    operand.Resources,
+		NodeSelector: operand.NodeSelector,
+		Tolerations:  operand.Tolerations,
+		Affinity:     operand.Affinity,
+		Env:          operand.Env,
+	}
+	if operand.Replicas != nil {
+		values.Replicas = *operand.Replicas
+	}
+	if operand.LogVerbosity != nil {
+		values.LogVerbosity = *operand.LogVerbosity
+	}
+	return values
+}
+
+// webhookEnabled returns false when the webhook is disabled in the ClusterRegistrar
+func webhookEnabled(clusterRegistrar *singaporev1alpha1.ClusterRegistrar) bool {
+	return clusterRegistrar.Spec.Webhook.Enabled == nil || *clusterRegistrar.Spec.Webhook.Enabled
+}
+
+// deployWebhook applies the webhook, the certificates are generated before the webhook deployment
+// when the installer manages them.
 func (r *ClusterRegistrarReconciler) deployWebhook(ctx context.Context,
+	clusterRegistrar *singaporev1alpha1.ClusterRegistrar,
 	applier apply.Applier,
 	readerDeploy *asset.ScenarioResourcesReader,
-	values struct {
-		Image     string
-		Namespace string
-	}) error {
-	files := []string{
-		"webhook/service_account.yaml",
-		"webhook/webhook_clusterrole.yaml",
-		"webhook/webhook_clusterrolebinding.yaml",
-		"webhook/webhook_service.yaml",
-	}
-
-	_, err := applier.ApplyDirectly(readerDeploy, values, false, "", files...)
+	values templateValues) error {
+	_, err := applier.ApplyDirectly(readerDeploy, values, false, "", webhookFiles...)
 	if err != nil {
 		return giterrors.WithStack(err)
 	}
 
-	files = []string{
-		"webhook/webhook.yaml",
+	var apiServiceCABundle, webhookCABundle []byte
+	if values.InstallerManagedCertificates {
+		if err := r.removeServiceCAAnnotations(ctx, &corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: webhookServiceName, Namespace: r.ControllerNamespace},
+		}); err != nil {
+			return err
+		}
+		apiServiceCABundle, webhookCABundle, err = r.ensureWebhookCertificates(ctx, clusterRegistrar)
+		if err != nil {
+			return err
+		}
 	}
 
-	_, err = applier.ApplyDeployments(readerDeploy, values, false, "", files...)
+	_, err = applier.ApplyDeployments(readerDeploy, values, false, "", webhookDeploymentFiles...)
 	if err != nil {
 		return giterrors.WithStack(err)
 	}
 
-	b, err := applier.MustTemplateAsset(readerDeploy, values, "", "webhook/webhook_validating_config.yaml")
+	b, err := applier.MustTemplateAsset(readerDeploy, values, "", webhookValidatingConfigFile)
 	if err != nil {
 		return giterrors.WithStack(err)
 	}
@@ -476,13 +482,17 @@ func (r *ClusterRegistrarReconciler) deployWebhook(ctx context.Context,
 		return giterrors.WithStack(err)
 	}
 
-	if err := r.Client.Create(ctx, validationWebhookConfiguration, &client.CreateOptions{}); err != nil {
-		if !errors.IsAlreadyExists(err) {
-			return giterrors.WithStack(err)
+	for i := range validationWebhookConfiguration.Webhooks {
+		if len(webhookCABundle) != 0 {
+			validationWebhookConfiguration.Webhooks[i].ClientConfig.CABundle = webhookCABundle
 		}
 	}
 
-	b, err = applier.MustTemplateAsset(readerDeploy, values, "", "webhook/webhook_apiservice.yaml")
+	if err := r.applyValidatingWebhookConfiguration(ctx, clusterRegistrar, validationWebhookConfiguration); err != nil {
+		return err
+	}
+
+	b, err = applier.MustTemplateAsset(readerDeploy, values, "", webhookAPIServiceFile)
 	if err != nil {
 		return giterrors.WithStack(err)
 	}
@@ -492,10 +502,16 @@ func (r *ClusterRegistrarReconciler) deployWebhook(ctx context.Context,
 	if err != nil {
 		return giterrors.WithStack(err)
 	}
-	if err := r.Client.Create(ctx, apiService, &client.CreateOptions{}); err != nil {
-		if !errors.IsAlreadyExists(err) {
-			return giterrors.WithStack(err)
-		}
+	if len(apiServiceCABundle) != 0 {
+		apiService.Spec.CABundle = apiServiceCABundle
+	}
+	if err := r.applyAPIService(ctx, clusterRegistrar, apiService); err != nil {
+		return err
+	}
+	if values.InstallerManagedCertificates {
+		return r.removeServiceCAAnnotations(ctx, &apiregistrationv1.APIService{
+			ObjectMeta: metav1.ObjectMeta{Name: webhookAPIServiceName},
+		})
 	}
 	return nil
 }
@@ -0,0 +1,65 @@
+// Copyright Red Hat
+
+package installer
+
+import (
+	"testing"
+
+	singaporev1alpha1 "github.com/stolostron/compute-operator/api/singapore/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestNeedsApply(t *testing.T) {
+	conditionType := singaporev1alpha1.ClusterRegistrarConditionHubsReachable
+	tests := []struct {
+		name       string
+		conditions []metav1.Condition
+		upgrading  bool
+		want       bool
+	}{
+		{
+			name: "never applied",
+			want: true,
+		},
+		{
+			name: "applied for the generation",
+			conditions: []metav1.Condition{
+				{Type: conditionType, Status: metav1.ConditionTrue, ObservedGeneration: 2},
+			},
+			want: false,
+		},
+		{
+			name: "applied for the generation during an upgrade",
+			conditions: []metav1.Condition{
+				{Type: conditionType, Status: metav1.ConditionTrue, ObservedGeneration: 2},
+			},
+			upgrading: true,
+			want:      true,
+		},
+		{
+			name: "applied for a previous generation",
+			conditions: []metav1.Condition{
+				{Type: conditionType, Status: metav1.ConditionTrue, ObservedGeneration: 1},
+			},
+			want: true,
+		},
+		{
+			name: "failed",
+			conditions: []metav1.Condition{
+				{Type: conditionType, Status: metav1.ConditionFalse, ObservedGeneration: 2},
+			},
+			want: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clusterRegistrar := &singaporev1alpha1.ClusterRegistrar{
+				ObjectMeta: metav1.ObjectMeta{Name: "cluster-registrar", Generation: 2},
+				Status:     singaporev1alpha1.ClusterRegistrarStatus{Conditions: tt.conditions},
+			}
+			if got := needsApply(clusterRegistrar, conditionType, tt.upgrading); got != tt.want {
+				t.Fatalf("Apply not as expected. Expected %t, actual %t", tt.want, got)
+			}
+		})
+	}
+}
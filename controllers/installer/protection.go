@@ -0,0 +1,193 @@
+// Copyright Red Hat
+
+package installer
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	giterrors "github.com/pkg/errors"
+
+	apimachineryclient "github.com/kcp-dev/apimachinery/pkg/client"
+	"github.com/kcp-dev/logicalcluster/v2"
+	singaporev1alpha1 "github.com/stolostron/compute-operator/api/singapore/v1alpha1"
+	"github.com/stolostron/compute-operator/pkg/helpers"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// registeredClustersResyncPeriod is the delay between the counts of the RegisteredClusters once installed
+	registeredClustersResyncPeriod = 5 * time.Minute
+	// drainRequeueDelay is the delay between the checks of the RegisteredClusters while the deletion waits for them
+	drainRequeueDelay = 10 * time.Second
+	// drainPageSize is the page size of the RegisteredClusters listed by the Cascade deletion
+	drainPageSize = 100
+)
+
+// getRegisteredClusterResource returns the client of the RegisteredClusters of all the workspaces
+// through the compute-apis virtual workspace
+func (r *ClusterRegistrarReconciler) getRegisteredClusterResource(ctx context.Context,
+	clusterRegistrar *singaporev1alpha1.ClusterRegistrar) (dynamic.NamespaceableResourceInterface, error) {
+	computeConfig, err := r.getComputeConfig(ctx, clusterRegistrar)
+	if err != nil {
+		return nil, err
+	}
+	// The APIExport is read with its own scheme, the manager scheme is shared with the cache
+	config, err := helpers.RestConfigForAPIExport(ctx, computeConfig, "compute-apis", runtime.NewScheme())
+	if err != nil {
+		return nil, giterrors.WithStack(err)
+	}
+	dynamicClient, err := dynamic.NewForConfig(apimachineryclient.NewClusterConfig(config))
+	if err != nil {
+		return nil, giterrors.WithStack(err)
+	}
+	return dynamicClient.Resource(helpers.GvrRC), nil
+}
+
+// getRegisteredClusterCount lists a single RegisteredCluster as only whether any exists matters,
+// the count is exact when the server returns the remaining item count.
+func getRegisteredClusterCount(ctx context.Context, resource dynamic.NamespaceableResourceInterface) (int32, error) {
+	list, err := resource.List(logicalcluster.WithCluster(ctx, logicalcluster.Wildcard), metav1.ListOptions{Limit: 1})
+	if err != nil {
+		return 0, giterrors.WithStack(err)
+	}
+	count := int32(len(list.Items))
+	if remaining := list.GetRemainingItemCount(); remaining != nil {
+		count += int32(*remaining)
+	} else if len(list.GetContinue()) != 0 {
+		count++
+	}
+	return count, nil
+}
+
+// countRegisteredClusters records the number of RegisteredClusters in the ClusterRegistrar status for the webhook,
+// the previous count is kept when the compute service is unreachable.
+func (r *ClusterRegistrarReconciler) countRegisteredClusters(ctx context.Context,
+	clusterRegistrar *singaporev1alpha1.ClusterRegistrar) {
+	resource, err := r.getRegisteredClusterResource(ctx, clusterRegistrar)
+	if err != nil {
+		r.Log.Error(err, "failed to count the registeredclusters")
+		return
+	}
+	count, err := getRegisteredClusterCount(ctx, resource)
+	if err != nil {
+		r.Log.Error(err, "failed to count the registeredclusters")
+		return
+	}
+	clusterRegistrar.Status.RegisteredClusterCount = count
+}
+
+// drainRegisteredClusters returns true once there is no RegisteredCluster left and the operator can be uninstalled.
+// The deletion is blocked while RegisteredClusters exist, with the Cascade deletion mode they are deleted
+// and the manager processes their finalizers.
+// The webhook check of the deletion is best-effort as it relies on the last count,
+// this drain run by the finalizer is the guard of the RegisteredClusters.
+func (r *ClusterRegistrarReconciler) drainRegisteredClusters(ctx context.Context,
+	clusterRegistrar *singaporev1alpha1.ClusterRegistrar) (bool, error) {
+	// Without compute service kubeconfig the RegisteredClusters can't be listed,
+	// the deletion proceeds only if none was ever counted.
+	secretName := clusterRegistrar.Spec.ComputeService.ComputeKubeconfigSecretRef.Name
+	if len(secretName) == 0 {
+		return r.drainWithoutComputeService(ctx, clusterRegistrar, "the compute service kubeconfig secret is not set")
+	}
+	if _, err := r.KubeClient.CoreV1().Secrets(r.ControllerNamespace).Get(ctx, secretName, metav1.GetOptions{}); err != nil {
+		if errors.IsNotFound(err) {
+			return r.drainWithoutComputeService(ctx, clusterRegistrar,
+				fmt.Sprintf("the compute service kubeconfig secret %s is not found", secretName))
+		}
+		return false, giterrors.WithStack(err)
+	}
+
+	// The RegisteredClusters are counted at each check, the count of the status can be stale
+	resource, err := r.getRegisteredClusterResource(ctx, clusterRegistrar)
+	var count int32
+	if err == nil {
+		count, err = getRegisteredClusterCount(ctx, resource)
+	}
+	if err != nil {
+		if err := r.updateDrainStatus(ctx, clusterRegistrar, clusterRegistrar.Status.RegisteredClusterCount,
+			singaporev1alpha1.ReasonRegisteredClustersExist,
+			fmt.Sprintf("unable to list the registeredclusters: %s", err.Error())); err != nil {
+			r.Log.Error(err, "failed to update the clusterregistrar status")
+		}
+		return false, err
+	}
+	if count == 0 {
+		return true, nil
+	}
+
+	if clusterRegistrar.Spec.DeletionMode != singaporev1alpha1.ClusterRegistrarDeletionModeCascade {
+		r.Log.Info("deletion blocked, registeredclusters exist", "count", count)
+		return false, r.updateDrainStatus(ctx, clusterRegistrar, count,
+			singaporev1alpha1.ReasonRegisteredClustersExist,
+			fmt.Sprintf("%d RegisteredClusters exist, delete them or set spec.deletionMode to Cascade", count))
+	}
+
+	opts := metav1.ListOptions{Limit: drainPageSize}
+	for {
+		list, err := resource.List(logicalcluster.WithCluster(ctx, logicalcluster.Wildcard), opts)
+		if err != nil {
+			return false, giterrors.WithStack(err)
+		}
+		for i := range list.Items {
+			regCluster := &list.Items[i]
+			if regCluster.GetDeletionTimestamp() != nil {
+				continue
+			}
+			r.Log.Info("delete registeredcluster",
+				"workspace", logicalcluster.From(regCluster).String(),
+				"namespace", regCluster.GetNamespace(),
+				"name", regCluster.GetName())
+			workspaceContext := logicalcluster.WithCluster(ctx, logicalcluster.From(regCluster))
+			if err := resource.Namespace(regCluster.GetNamespace()).Delete(workspaceContext, regCluster.GetName(), metav1.DeleteOptions{}); err != nil &&
+				!errors.IsNotFound(err) {
+				return false, giterrors.WithStack(err)
+			}
+		}
+		if len(list.GetContinue()) == 0 {
+			break
+		}
+		opts.Continue = list.GetContinue()
+	}
+	return false, r.updateDrainStatus(ctx, clusterRegistrar, count,
+		singaporev1alpha1.ReasonDrainingRegisteredClusters,
+		fmt.Sprintf("waiting for the deletion of %d RegisteredClusters", count))
+}
+
+// drainWithoutComputeService returns true when no RegisteredCluster was counted,
+// otherwise the deletion is blocked until the compute service kubeconfig is restored.
+func (r *ClusterRegistrarReconciler) drainWithoutComputeService(ctx context.Context,
+	clusterRegistrar *singaporev1alpha1.ClusterRegistrar,
+	cause string) (bool, error) {
+	count := clusterRegistrar.Status.RegisteredClusterCount
+	if count == 0 {
+		return true, nil
+	}
+	r.Log.Info("deletion blocked, the registeredclusters can't be checked", "count", count, "cause", cause)
+	return false, r.updateDrainStatus(ctx, clusterRegistrar, count,
+		singaporev1alpha1.ReasonRegisteredClustersExist,
+		fmt.Sprintf("%d RegisteredClusters were counted and %s, restore it to check them", count, cause))
+}
+
+// updateDrainStatus patches the RegisteredCluster count and the Uninstalled condition while the deletion waits
+func (r *ClusterRegistrarReconciler) updateDrainStatus(ctx context.Context,
+	clusterRegistrar *singaporev1alpha1.ClusterRegistrar,
+	count int32,
+	reason, message string) error {
+	original := clusterRegistrar.DeepCopy()
+	clusterRegistrar.Status.RegisteredClusterCount = count
+	condition := newCondition(singaporev1alpha1.ClusterRegistrarConditionUninstalled, metav1.ConditionFalse, reason, message)
+	condition.ObservedGeneration = clusterRegistrar.Generation
+	meta.SetStatusCondition(&clusterRegistrar.Status.Conditions, condition)
+	if equality.Semantic.DeepEqual(original.Status, clusterRegistrar.Status) {
+		return nil
+	}
+	return giterrors.WithStack(r.Client.Status().Patch(ctx, clusterRegistrar, client.MergeFrom(original)))
+}
@@ -0,0 +1,271 @@
+// Copyright Red Hat
+
+package installer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	giterrors "github.com/pkg/errors"
+
+	singaporev1alpha1 "github.com/stolostron/compute-operator/api/singapore/v1alpha1"
+	"github.com/stolostron/compute-operator/pkg/helpers"
+	admissionregistration "k8s.io/api/admissionregistration/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	apiregistrationv1 "k8s.io/kube-aggregator/pkg/apis/apiregistration/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	managerDeploymentName              = "compute-operator-manager"
+	webhookDeploymentName              = "compute-operator-webhook-service"
+	webhookAPIServiceName              = "v1alpha1.admission.singapore.open-cluster-management.io"
+	validatingWebhookConfigurationName = "compute-operator-webhook-service"
+	// computeServiceTimeout bounds the check of the compute service
+	computeServiceTimeout = 10 * time.Second
+	// notReadyRequeueDelay is the delay between the status checks while the installation is not ready
+	notReadyRequeueDelay = 30 * time.Second
+)
+
+// installedCRDs are the CRDs installed by the installer
+var installedCRDs = []string{
+	"clusterregistrars.singapore.open-cluster-management.io",
+	"registeredclusters.singapore.open-cluster-management.io",
+	"hubconfigs.singapore.open-cluster-management.io",
+}
+
+// webhookConditionTypes are the conditions only set when the webhook is deployed
+var webhookConditionTypes = []string{
+	singaporev1alpha1.ClusterRegistrarConditionWebhookAvailable,
+	singaporev1alpha1.ClusterRegistrarConditionAPIServiceAvailable,
+	singaporev1alpha1.ClusterRegistrarConditionWebhookRegistered,
+}
+
+// updateStatus checks the installed components, patches the ClusterRegistrar conditions if they changed
+// and returns the result requeueing the check while the installation is not ready.
+// The installed version is recorded once the upgrade is completed and the RegisteredClusters are counted
+// when the compute service is reachable. The applied conditions are the results of the compute service bootstrap
+// and of the prerequisites of the hubs, when they are not applied their last recorded conditions are kept.
+func (r *ClusterRegistrarReconciler) updateStatus(ctx context.Context,
+	clusterRegistrar *singaporev1alpha1.ClusterRegistrar,
+	upgraded metav1.Condition,
+	applied ...metav1.Condition) (reconcile.Result, error) {
+	conditions := []metav1.Condition{
+		upgraded,
+		r.getCRDsCondition(ctx),
+		r.getDeploymentCondition(ctx, singaporev1alpha1.ClusterRegistrarConditionManagerAvailable, managerDeploymentName),
+		r.getComputeServiceCondition(ctx, clusterRegistrar),
+	}
+	if webhookEnabled(clusterRegistrar) {
+		conditions = append(conditions,
+			r.getDeploymentCondition(ctx, singaporev1alpha1.ClusterRegistrarConditionWebhookAvailable, webhookDeploymentName),
+			r.getAPIServiceCondition(ctx),
+			r.getWebhookRegisteredCondition(ctx))
+	}
+	conditions = append(conditions, applied...)
+
+	original := clusterRegistrar.DeepCopy()
+	if !webhookEnabled(clusterRegistrar) {
+		for _, conditionType := range webhookConditionTypes {
+			meta.RemoveStatusCondition(&clusterRegistrar.Status.Conditions, conditionType)
+		}
+	}
+	if !computeBootstrapEnabled(clusterRegistrar) {
+		meta.RemoveStatusCondition(&clusterRegistrar.Status.Conditions, singaporev1alpha1.ClusterRegistrarConditionComputeServiceBootstrapped)
+	}
+	if len(clusterRegistrar.Spec.Hubs) == 0 {
+		meta.RemoveStatusCondition(&clusterRegistrar.Status.Conditions, singaporev1alpha1.ClusterRegistrarConditionHubsReachable)
+	}
+	readinessTypes := make([]string, 0, len(conditions)+2)
+	for _, condition := range conditions {
+		condition.ObservedGeneration = clusterRegistrar.Generation
+		meta.SetStatusCondition(&clusterRegistrar.Status.Conditions, condition)
+		readinessTypes = append(readinessTypes, condition.Type)
+	}
+	if computeBootstrapEnabled(clusterRegistrar) && meta.FindStatusCondition(applied, singaporev1alpha1.ClusterRegistrarConditionComputeServiceBootstrapped) == nil {
+		readinessTypes = append(readinessTypes, singaporev1alpha1.ClusterRegistrarConditionComputeServiceBootstrapped)
+	}
+	if len(clusterRegistrar.Spec.Hubs) > 0 && meta.FindStatusCondition(applied, singaporev1alpha1.ClusterRegistrarConditionHubsReachable) == nil {
+		readinessTypes = append(readinessTypes, singaporev1alpha1.ClusterRegistrarConditionHubsReachable)
+	}
+	notReady := make([]string, 0)
+	for _, conditionType := range readinessTypes {
+		if !meta.IsStatusConditionTrue(clusterRegistrar.Status.Conditions, conditionType) {
+			notReady = append(notReady, conditionType)
+		}
+	}
+	readyCondition := metav1.Condition{
+		Type:               singaporev1alpha1.ClusterRegistrarConditionReady,
+		Status:             metav1.ConditionTrue,
+		Reason:             singaporev1alpha1.ReasonReady,
+		Message:            "the compute operator is installed and available",
+		ObservedGeneration: clusterRegistrar.Generation,
+	}
+	if len(notReady) > 0 {
+		readyCondition.Status = metav1.ConditionFalse
+		readyCondition.Reason = singaporev1alpha1.ReasonNotReady
+		readyCondition.Message = fmt.Sprintf("conditions not true: %s", strings.Join(notReady, ", "))
+	}
+	meta.SetStatusCondition(&clusterRegistrar.Status.Conditions, readyCondition)
+	if upgraded.Status == metav1.ConditionTrue {
+		clusterRegistrar.Status.InstalledVersion = helpers.Version
+	}
+	if meta.IsStatusConditionTrue(clusterRegistrar.Status.Conditions, singaporev1alpha1.ClusterRegistrarConditionComputeServiceReachable) {
+		r.countRegisteredClusters(ctx, clusterRegistrar)
+	}
+
+	if !equality.Semantic.DeepEqual(original.Status, clusterRegistrar.Status) {
+		if err := r.Client.Status().Patch(ctx, clusterRegistrar, client.MergeFrom(original)); err != nil {
+			return reconcile.Result{}, giterrors.WithStack(err)
+		}
+	}
+	// The compute service, the hubs, the CRDs and the RegisteredClusters are not watched
+	if len(notReady) > 0 {
+		return reconcile.Result{RequeueAfter: notReadyRequeueDelay}, nil
+	}
+	return reconcile.Result{RequeueAfter: registeredClustersResyncPeriod}, nil
+}
+
+func (r *ClusterRegistrarReconciler) getCRDsCondition(ctx context.Context) metav1.Condition {
+	notEstablished := make([]string, 0)
+	for _, name := range installedCRDs {
+		crd, err := r.APIExtensionClient.ApiextensionsV1().CustomResourceDefinitions().Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			r.Log.V(1).Info("failed to get crd", "name", name, "error", err.Error())
+			notEstablished = append(notEstablished, name)
+			continue
+		}
+		established := false
+		for _, condition := range crd.Status.Conditions {
+			if condition.Type == apiextensionsv1.Established && condition.Status == apiextensionsv1.ConditionTrue {
+				established = true
+			}
+		}
+		if !established {
+			notEstablished = append(notEstablished, name)
+		}
+	}
+	if len(notEstablished) > 0 {
+		return newCondition(singaporev1alpha1.ClusterRegistrarConditionCRDsInstalled, metav1.ConditionFalse,
+			singaporev1alpha1.ReasonCRDNotEstablished, fmt.Sprintf("CRDs not established: %s", strings.Join(notEstablished, ", ")))
+	}
+	return newCondition(singaporev1alpha1.ClusterRegistrarConditionCRDsInstalled, metav1.ConditionTrue,
+		singaporev1alpha1.ReasonCRDsEstablished, "all CRDs are established")
+}
+
+func (r *ClusterRegistrarReconciler) getDeploymentCondition(ctx context.Context, conditionType, name string) metav1.Condition {
+	deployment := &appsv1.Deployment{}
+	if err := r.Client.Get(ctx, types.NamespacedName{Namespace: r.ControllerNamespace, Name: name}, deployment); err != nil {
+		return newCondition(conditionType, metav1.ConditionFalse,
+			singaporev1alpha1.ReasonDeploymentNotFound, fmt.Sprintf("deployment %s/%s: %s", r.ControllerNamespace, name, err.Error()))
+	}
+	for _, condition := range deployment.Status.Conditions {
+		if condition.Type == appsv1.DeploymentAvailable && condition.Status == corev1.ConditionTrue {
+			return newCondition(conditionType, metav1.ConditionTrue,
+				singaporev1alpha1.ReasonDeploymentAvailable, fmt.Sprintf("deployment %s/%s available, %d/%d replicas ready",
+					r.ControllerNamespace, name, deployment.Status.ReadyReplicas, deployment.Status.Replicas))
+		}
+	}
+	return newCondition(conditionType, metav1.ConditionFalse,
+		singaporev1alpha1.ReasonDeploymentNotAvailable, fmt.Sprintf("deployment %s/%s not available, %d/%d replicas ready",
+			r.ControllerNamespace, name, deployment.Status.ReadyReplicas, deployment.Status.Replicas))
+}
+
+func (r *ClusterRegistrarReconciler) getAPIServiceCondition(ctx context.Context) metav1.Condition {
+	apiService := &apiregistrationv1.APIService{}
+	if err := r.Client.Get(ctx, types.NamespacedName{Name: webhookAPIServiceName}, apiService); err != nil {
+		return newCondition(singaporev1alpha1.ClusterRegistrarConditionAPIServiceAvailable, metav1.ConditionFalse,
+			singaporev1alpha1.ReasonAPIServiceNotAvailable, fmt.Sprintf("apiservice %s: %s", webhookAPIServiceName, err.Error()))
+	}
+	for _, condition := range apiService.Status.Conditions {
+		if condition.Type != apiregistrationv1.Available {
+			continue
+		}
+		if condition.Status == apiregistrationv1.ConditionTrue {
+			return newCondition(singaporev1alpha1.ClusterRegistrarConditionAPIServiceAvailable, metav1.ConditionTrue,
+				singaporev1alpha1.ReasonAPIServiceAvailable, fmt.Sprintf("apiservice %s available", webhookAPIServiceName))
+		}
+		return newCondition(singaporev1alpha1.ClusterRegistrarConditionAPIServiceAvailable, metav1.ConditionFalse,
+			singaporev1alpha1.ReasonAPIServiceNotAvailable, fmt.Sprintf("apiservice %s not available: %s", webhookAPIServiceName, condition.Message))
+	}
+	return newCondition(singaporev1alpha1.ClusterRegistrarConditionAPIServiceAvailable, metav1.ConditionFalse,
+		singaporev1alpha1.ReasonAPIServiceNotAvailable, fmt.Sprintf("apiservice %s has no availability yet", webhookAPIServiceName))
+}
+
+func (r *ClusterRegistrarReconciler) getWebhookRegisteredCondition(ctx context.Context) metav1.Condition {
+	validatingWebhookConfiguration := &admissionregistration.ValidatingWebhookConfiguration{}
+	if err := r.Client.Get(ctx, types.NamespacedName{Name: validatingWebhookConfigurationName}, validatingWebhookConfiguration); err != nil {
+		return newCondition(singaporev1alpha1.ClusterRegistrarConditionWebhookRegistered, metav1.ConditionFalse,
+			singaporev1alpha1.ReasonWebhookNotRegistered, fmt.Sprintf("validatingwebhookconfiguration %s: %s", validatingWebhookConfigurationName, err.Error()))
+	}
+	return newCondition(singaporev1alpha1.ClusterRegistrarConditionWebhookRegistered, metav1.ConditionTrue,
+		singaporev1alpha1.ReasonWebhookRegistered, fmt.Sprintf("validatingwebhookconfiguration %s registered", validatingWebhookConfigurationName))
+}
+
+// getComputeServiceCondition checks the compute service answers with the kubeconfig of the ClusterRegistrar
+func (r *ClusterRegistrarReconciler) getComputeServiceCondition(ctx context.Context, clusterRegistrar *singaporev1alpha1.ClusterRegistrar) metav1.Condition {
+	config, err := r.getComputeConfig(ctx, clusterRegistrar)
+	if err != nil {
+		return newCondition(singaporev1alpha1.ClusterRegistrarConditionComputeServiceReachable, metav1.ConditionFalse,
+			singaporev1alpha1.ReasonComputeServiceUnreachable, err.Error())
+	}
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(config)
+	if err != nil {
+		return newCondition(singaporev1alpha1.ClusterRegistrarConditionComputeServiceReachable, metav1.ConditionFalse,
+			singaporev1alpha1.ReasonComputeServiceUnreachable, err.Error())
+	}
+	if _, err := discoveryClient.ServerVersion(); err != nil {
+		return newCondition(singaporev1alpha1.ClusterRegistrarConditionComputeServiceReachable, metav1.ConditionFalse,
+			singaporev1alpha1.ReasonComputeServiceUnreachable, fmt.Sprintf("compute service %s: %s", config.Host, err.Error()))
+	}
+	return newCondition(singaporev1alpha1.ClusterRegistrarConditionComputeServiceReachable, metav1.ConditionTrue,
+		singaporev1alpha1.ReasonComputeServiceReachable, fmt.Sprintf("compute service %s reachable", config.Host))
+}
+
+// getComputeConfig returns the config of the compute service kubeconfig of the ClusterRegistrar
+func (r *ClusterRegistrarReconciler) getComputeConfig(ctx context.Context, clusterRegistrar *singaporev1alpha1.ClusterRegistrar) (*rest.Config, error) {
+	secretName := clusterRegistrar.Spec.ComputeService.ComputeKubeconfigSecretRef.Name
+	if len(secretName) == 0 {
+		return nil, fmt.Errorf("spec.computeService.computeKubeconfigSecretRef is not set")
+	}
+	// The secrets are read directly, the installer doesn't cache them
+	secret, err := r.KubeClient.CoreV1().Secrets(r.ControllerNamespace).Get(ctx, secretName, metav1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil, fmt.Errorf("secret %s/%s not found", r.ControllerNamespace, secretName)
+		}
+		return nil, err
+	}
+	kubeconfig, ok := secret.Data["kubeconfig"]
+	if !ok {
+		return nil, fmt.Errorf("secret %s/%s missing kubeconfig data", r.ControllerNamespace, secretName)
+	}
+	config, err := clientcmd.RESTConfigFromKubeConfig(kubeconfig)
+	if err != nil {
+		return nil, err
+	}
+	config.Timeout = computeServiceTimeout
+	return config, nil
+}
+
+func newCondition(conditionType string, status metav1.ConditionStatus, reason, message string) metav1.Condition {
+	return metav1.Condition{
+		Type:    conditionType,
+		Status:  status,
+		Reason:  reason,
+		Message: message,
+	}
+}
@@ -3,6 +3,7 @@ package installer
 
 import (
 	"context"
+	"crypto/x509"
 	"fmt"
 	"testing"
 	"time"
@@ -11,11 +12,13 @@ import (
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	admissionregistration "k8s.io/api/admissionregistration/v1"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	apiextensionsclient "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/dynamic"
@@ -28,9 +31,11 @@ import (
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 
+	"github.com/stolostron/applier/pkg/apply"
 	clusteradmasset "github.com/stolostron/applier/pkg/asset"
 
 	croconfig "github.com/stolostron/compute-operator/config"
+	"github.com/stolostron/compute-operator/deploy"
 
 	singaporev1alpha1 "github.com/stolostron/compute-operator/api/singapore/v1alpha1"
 )
@@ -199,6 +204,253 @@ var _ = Describe("Process installation: ", func() {
 				return nil
 			}, 30, 1).Should(BeNil())
 		})
+		By("Checking the ClusterRegistrar status", func() {
+			Eventually(func() error {
+				clusterRegistrar := &singaporev1alpha1.ClusterRegistrar{}
+				if err := k8sClient.Get(context.TODO(),
+					types.NamespacedName{Name: "cluster-registrar"},
+					clusterRegistrar); err != nil {
+					return err
+				}
+				if !meta.IsStatusConditionTrue(clusterRegistrar.Status.Conditions, singaporev1alpha1.ClusterRegistrarConditionCRDsInstalled) {
+					return fmt.Errorf("CRDs not installed: %v", clusterRegistrar.Status.Conditions)
+				}
+				// No pod runs in the test environment and the compute service is not set
+				for _, conditionType := range []string{
+					singaporev1alpha1.ClusterRegistrarConditionManagerAvailable,
+					// The manager is never rolled out
+					singaporev1alpha1.ClusterRegistrarConditionUpgraded,
+					singaporev1alpha1.ClusterRegistrarConditionComputeServiceReachable,
+					singaporev1alpha1.ClusterRegistrarConditionReady,
+				} {
+					if !meta.IsStatusConditionFalse(clusterRegistrar.Status.Conditions, conditionType) {
+						return fmt.Errorf("condition %s not false: %v", conditionType, clusterRegistrar.Status.Conditions)
+					}
+				}
+				if len(clusterRegistrar.Status.InstalledObjects) == 0 {
+					return fmt.Errorf("installed objects not recorded")
+				}
+				return nil
+			}, 30, 1).Should(BeNil())
+		})
+		By("Checking the deleted manager deployment is restored", func() {
+			deployment := &appsv1.Deployment{}
+			err := k8sClient.Get(context.TODO(),
+				types.NamespacedName{
+					Name:      "compute-operator-manager",
+					Namespace: installationNamespace,
+				},
+				deployment)
+			Expect(err).To(BeNil())
+			Expect(len(deployment.OwnerReferences)).To(Equal(1))
+			Expect(deployment.OwnerReferences[0].Kind).To(Equal("ClusterRegistrar"))
+			uid := deployment.UID
+			err = k8sClient.Delete(context.TODO(), deployment)
+			Expect(err).To(BeNil())
+			Eventually(func() error {
+				deployment := &appsv1.Deployment{}
+				if err := k8sClient.Get(context.TODO(),
+					types.NamespacedName{
+						Name:      "compute-operator-manager",
+						Namespace: installationNamespace,
+					},
+					deployment); err != nil {
+					return err
+				}
+				if deployment.UID == uid {
+					return fmt.Errorf("deployment not deleted yet")
+				}
+				return nil
+			}, 30, 1).Should(BeNil())
+		})
+		By("Checking the edited ValidatingWebhookConfiguration is restored", func() {
+			vwc := &admissionregistration.ValidatingWebhookConfiguration{}
+			Eventually(func() error {
+				return k8sClient.Get(context.TODO(), types.NamespacedName{Name: validatingWebhookConfigurationName}, vwc)
+			}, 30, 1).Should(BeNil())
+			Expect(len(vwc.OwnerReferences)).To(Equal(1))
+			Expect(vwc.OwnerReferences[0].Kind).To(Equal("ClusterRegistrar"))
+			Expect(vwc.OwnerReferences[0].Controller).ToNot(BeNil())
+			Expect(*vwc.OwnerReferences[0].Controller).To(BeTrue())
+			webhooks := vwc.Webhooks
+			vwc.Webhooks = nil
+			Expect(k8sClient.Update(context.TODO(), vwc)).To(BeNil())
+			Eventually(func() error {
+				vwc := &admissionregistration.ValidatingWebhookConfiguration{}
+				if err := k8sClient.Get(context.TODO(), types.NamespacedName{Name: validatingWebhookConfigurationName}, vwc); err != nil {
+					return err
+				}
+				if len(vwc.Webhooks) != len(webhooks) {
+					return fmt.Errorf("webhooks not restored yet")
+				}
+				return nil
+			}, 30, 1).Should(BeNil())
+		})
+		By("Checking the deleted ValidatingWebhookConfiguration is restored", func() {
+			vwc := &admissionregistration.ValidatingWebhookConfiguration{}
+			Expect(k8sClient.Get(context.TODO(), types.NamespacedName{Name: validatingWebhookConfigurationName}, vwc)).To(BeNil())
+			uid := vwc.UID
+			Expect(k8sClient.Delete(context.TODO(), vwc)).To(BeNil())
+			Eventually(func() error {
+				vwc := &admissionregistration.ValidatingWebhookConfiguration{}
+				if err := k8sClient.Get(context.TODO(), types.NamespacedName{Name: validatingWebhookConfigurationName}, vwc); err != nil {
+					return err
+				}
+				if vwc.UID == uid {
+					return fmt.Errorf("validatingwebhookconfiguration not deleted yet")
+				}
+				return nil
+			}, 30, 1).Should(BeNil())
+		})
+	})
+
+	It("Detects downgrades", func() {
+		Expect(isDowngrade("", "0.0.1")).To(BeFalse())
+		Expect(isDowngrade("0.0.1", "0.0.1")).To(BeFalse())
+		Expect(isDowngrade("0.0.1", "0.1.0")).To(BeFalse())
+		Expect(isDowngrade("0.2.0", "v0.1.0")).To(BeTrue())
+		Expect(isDowngrade("latest", "0.1.0")).To(BeFalse())
+	})
+
+	It("Sets the operand template values", func() {
+		values := r.getTemplateValues(&singaporev1alpha1.ClusterRegistrar{})
+		Expect(values.Manager.Replicas).To(Equal(defaultReplicas))
+		Expect(values.Manager.LogVerbosity).To(Equal(defaultManagerLogVerbosity))
+		Expect(values.Webhook.LogVerbosity).To(Equal(defaultWebhookLogVerbosity))
+		Expect(values.Manager.Tolerations).To(BeNil())
+		Expect(values.Manager.Shards).To(Equal(defaultManagerShards))
+
+		replicas := int32(3)
+		logVerbosity := int32(2)
+		shards := int32(4)
+		values = r.getTemplateValues(&singaporev1alpha1.ClusterRegistrar{
+			Spec: singaporev1alpha1.ClusterRegistrarSpec{
+				Manager: singaporev1alpha1.ManagerSpec{
+					OperandSpec: singaporev1alpha1.OperandSpec{
+						Replicas:     &replicas,
+						LogVerbosity: &logVerbosity,
+						NodeSelector: map[string]string{"node-role.kubernetes.io/infra": ""},
+					},
+					Shards: &shards,
+				},
+			},
+		})
+		Expect(values.Manager.Replicas).To(Equal(replicas))
+		Expect(values.Manager.LogVerbosity).To(Equal(logVerbosity))
+		Expect(values.Manager.NodeSelector).To(HaveKey("node-role.kubernetes.io/infra"))
+		Expect(values.Manager.Shards).To(Equal(shards))
+		Expect(values.Webhook.Replicas).To(Equal(defaultReplicas))
+
+		applier := apply.NewApplierBuilder().Build()
+		_, err := applier.MustTemplateAsset(deploy.GetScenarioResourcesReader(), values, "", "compute-operator/manager.yaml")
+		Expect(err).To(BeNil())
+	})
+
+	It("Generates the webhook certificates", func() {
+		now := time.Now()
+		Expect(needsRenewal(&x509.Certificate{NotBefore: now.Add(-time.Hour), NotAfter: now.Add(9 * time.Hour)}, now)).To(BeFalse())
+		Expect(needsRenewal(&x509.Certificate{NotBefore: now.Add(-9 * time.Hour), NotAfter: now.Add(time.Hour)}, now)).To(BeTrue())
+
+		clusterRegistrar := &singaporev1alpha1.ClusterRegistrar{}
+		Expect(k8sClient.Get(context.TODO(), types.NamespacedName{Name: "cluster-registrar"}, clusterRegistrar)).To(BeNil())
+		ca, caBundle, err := r.ensureWebhookCA(context.TODO(), clusterRegistrar)
+		Expect(err).To(BeNil())
+		Expect(r.ensureWebhookServingCertificate(context.TODO(), clusterRegistrar, ca)).To(BeNil())
+
+		By("Keeping the valid certificates", func() {
+			servingSecret := &corev1.Secret{}
+			Expect(k8sClient.Get(context.TODO(),
+				types.NamespacedName{Namespace: installationNamespace, Name: webhookServingSecretName}, servingSecret)).To(BeNil())
+			sameCA, sameCABundle, err := r.ensureWebhookCA(context.TODO(), clusterRegistrar)
+			Expect(err).To(BeNil())
+			Expect(sameCA.Config.Certs[0].Equal(ca.Config.Certs[0])).To(BeTrue())
+			Expect(sameCABundle).To(Equal(caBundle))
+			Expect(r.ensureWebhookServingCertificate(context.TODO(), clusterRegistrar, sameCA)).To(BeNil())
+			sameServingSecret := &corev1.Secret{}
+			Expect(k8sClient.Get(context.TODO(),
+				types.NamespacedName{Namespace: installationNamespace, Name: webhookServingSecretName}, sameServingSecret)).To(BeNil())
+			Expect(sameServingSecret.Data).To(Equal(servingSecret.Data))
+			Expect(r.isServingCertificateValid(servingSecret, ca, []string{
+				fmt.Sprintf("%s.%s.svc", webhookServiceName, installationNamespace)})).To(BeTrue())
+		})
+	})
+
+	It("Lists the installed objects", func() {
+		objects, err := r.getInstalledObjects(context.TODO(), &singaporev1alpha1.ClusterRegistrar{}, true)
+		Expect(err).To(BeNil())
+		Expect(len(objects)).To(Equal(len(managerFiles) + len(managerDeploymentFiles) +
+			len(webhookFiles) + len(webhookDeploymentFiles) + 2))
+		for _, object := range objects {
+			switch object.GetKind() {
+			case "ClusterRole", "ClusterRoleBinding", "ValidatingWebhookConfiguration", "APIService":
+				Expect(object.GetNamespace()).To(BeEmpty())
+			default:
+				Expect(object.GetNamespace()).To(Equal(installationNamespace))
+			}
+		}
+		Expect(objects[0].GetName()).To(Equal(webhookDeploymentName))
+		Expect(objects[1].GetName()).To(Equal(managerDeploymentName))
+
+		objects, err = r.getInstalledObjects(context.TODO(), &singaporev1alpha1.ClusterRegistrar{}, false)
+		Expect(err).To(BeNil())
+		Expect(len(objects)).To(Equal(len(managerFiles) + len(managerDeploymentFiles)))
+
+		By("Listing the HubConfigs of the hubs", func() {
+			clusterRegistrar := &singaporev1alpha1.ClusterRegistrar{
+				Spec: singaporev1alpha1.ClusterRegistrarSpec{
+					Hubs: []singaporev1alpha1.HubSpec{
+						{
+							Name:                "hub1",
+							KubeconfigSecretRef: corev1.LocalObjectReference{Name: "hub1-kubeconfig"},
+							MaxManagedCluster:   10,
+						},
+					},
+				},
+			}
+			objects, err := r.getInstalledObjects(context.TODO(), clusterRegistrar, false)
+			Expect(err).To(BeNil())
+			Expect(len(objects)).To(Equal(len(managerFiles) + len(managerDeploymentFiles) + 1))
+			hubConfig := objects[len(objects)-1]
+			Expect(hubConfig.GetKind()).To(Equal("HubConfig"))
+			Expect(hubConfig.GetName()).To(Equal("hub1"))
+			Expect(hubConfig.GetNamespace()).To(Equal(installationNamespace))
+		})
+	})
+
+	It("Reports the unreachable hubs", func() {
+		clusterRegistrar := &singaporev1alpha1.ClusterRegistrar{
+			Spec: singaporev1alpha1.ClusterRegistrarSpec{
+				Hubs: []singaporev1alpha1.HubSpec{
+					{
+						Name:                "hub1",
+						KubeconfigSecretRef: corev1.LocalObjectReference{Name: "missing-kubeconfig"},
+					},
+				},
+			},
+		}
+		condition := r.getHubsCondition(context.TODO(), clusterRegistrar)
+		Expect(condition.Type).To(Equal(singaporev1alpha1.ClusterRegistrarConditionHubsReachable))
+		Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+		Expect(condition.Reason).To(Equal(singaporev1alpha1.ReasonHubUnreachable))
+		Expect(condition.Message).To(ContainSubstring("hub hub1"))
+		Expect(condition.Message).To(ContainSubstring("missing-kubeconfig not found"))
+	})
+
+	It("Reports the compute service bootstrap failure", func() {
+		clusterRegistrar := &singaporev1alpha1.ClusterRegistrar{
+			Spec: singaporev1alpha1.ClusterRegistrarSpec{
+				ComputeService: singaporev1alpha1.ComputeService{
+					ComputeKubeconfigSecretRef: corev1.LocalObjectReference{Name: "missing-compute-kubeconfig"},
+					Bootstrap:                  &singaporev1alpha1.ComputeBootstrap{},
+				},
+			},
+		}
+		Expect(computeBootstrapEnabled(clusterRegistrar)).To(BeTrue())
+		condition := r.getComputeBootstrapCondition(context.TODO(), clusterRegistrar)
+		Expect(condition.Type).To(Equal(singaporev1alpha1.ClusterRegistrarConditionComputeServiceBootstrapped))
+		Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+		Expect(condition.Reason).To(Equal(singaporev1alpha1.ReasonComputeBootstrapFailed))
+		Expect(condition.Message).To(ContainSubstring("missing-compute-kubeconfig not found"))
 	})
 
 	It("Proccess ClusterRegistrar deletion", func() {
@@ -231,6 +483,23 @@ var _ = Describe("Process installation: ", func() {
 				return fmt.Errorf("deployment still exists")
 			}, 30, 1).Should(BeNil())
 		})
+		By("Checking the installed objects are deleted", func() {
+			Eventually(func() error {
+				serviceAccount := &corev1.ServiceAccount{}
+				if err := k8sClient.Get(context.TODO(),
+					types.NamespacedName{
+						Name:      "compute-operator-manager",
+						Namespace: installationNamespace,
+					},
+					serviceAccount); err != nil {
+					if errors.IsNotFound(err) {
+						return nil
+					}
+					return err
+				}
+				return fmt.Errorf("serviceaccount still exists")
+			}, 30, 1).Should(BeNil())
+		})
 	})
 })
 
@@ -0,0 +1,359 @@
+// Copyright Red Hat
+
+package installer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ghodss/yaml"
+	giterrors "github.com/pkg/errors"
+
+	"github.com/stolostron/applier/pkg/apply"
+	"github.com/stolostron/applier/pkg/asset"
+	singaporev1alpha1 "github.com/stolostron/compute-operator/api/singapore/v1alpha1"
+	"github.com/stolostron/compute-operator/deploy"
+	"github.com/stolostron/compute-operator/pkg/helpers"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// uninstallRequeueDelay is the delay between the checks of the deleted objects
+	uninstallRequeueDelay = 5 * time.Second
+)
+
+// getInstalledObjects templates the installed files and the HubConfigs of spec.hubs
+// and returns their objects in the uninstall order, the deployments are deleted before the objects they use.
+func (r *ClusterRegistrarReconciler) getInstalledObjects(ctx context.Context,
+	clusterRegistrar *singaporev1alpha1.ClusterRegistrar,
+	withWebhook bool) ([]*unstructured.Unstructured, error) {
+	applier := apply.NewApplierBuilder().
+		WithClient(r.KubeClient, r.APIExtensionClient, r.DynamicClient).
+		WithContext(ctx).
+		Build()
+	readerDeploy := deploy.GetScenarioResourcesReader()
+
+	files := make([]string, 0)
+	if withWebhook {
+		files = append(files, webhookDeploymentFiles...)
+	}
+	files = append(files, managerDeploymentFiles...)
+	if withWebhook {
+		files = append(files, webhookValidatingConfigFile, webhookAPIServiceFile)
+		files = append(files, webhookFiles...)
+	}
+	files = append(files, managerFiles...)
+
+	objects := make([]*unstructured.Unstructured, 0, len(files))
+	for _, file := range files {
+		b, err := applier.MustTemplateAsset(readerDeploy, r.getTemplateValues(clusterRegistrar), "", file)
+		if err != nil {
+			return nil, giterrors.WithStack(err)
+		}
+		object := &unstructured.Unstructured{}
+		if err := yaml.Unmarshal(b, &object.Object); err != nil {
+			return nil, giterrors.Wrapf(err, "file %s", file)
+		}
+		objects = append(objects, object)
+	}
+
+	for _, hub := range clusterRegistrar.Spec.Hubs {
+		values := hubConfigValues{
+			Namespace: r.ControllerNamespace,
+			Hub:       hub,
+		}
+		b, err := applier.MustTemplateAsset(readerDeploy, values, "", hubConfigFile)
+		if err != nil {
+			return nil, giterrors.WithStack(err)
+		}
+		object := &unstructured.Unstructured{}
+		if err := yaml.Unmarshal(b, &object.Object); err != nil {
+			return nil, giterrors.Wrapf(err, "hub %s", hub.Name)
+		}
+		objects = append(objects, object)
+	}
+
+	if withWebhook && installerManagedCertificates(clusterRegistrar) {
+		for _, name := range []string{webhookServingSecretName, webhookCASecretName} {
+			objects = append(objects, newObject(singaporev1alpha1.InstalledObject{
+				APIVersion: "v1",
+				Kind:       "Secret",
+				Namespace:  r.ControllerNamespace,
+				Name:       name,
+			}))
+		}
+	}
+	return objects, nil
+}
+
+// getRemoteObjects returns the objects installed on the hubs and in the compute service,
+// the objects of the compute service remain recorded while its kubeconfig can't be read.
+func (r *ClusterRegistrarReconciler) getRemoteObjects(ctx context.Context,
+	clusterRegistrar *singaporev1alpha1.ClusterRegistrar) ([]singaporev1alpha1.InstalledObject, error) {
+	objects, err := r.getHubObjects(ctx, clusterRegistrar)
+	if err != nil {
+		return nil, err
+	}
+	if !computeBootstrapEnabled(clusterRegistrar) {
+		return objects, nil
+	}
+	computeObjects, err := r.getComputeObjects(ctx, clusterRegistrar)
+	if err != nil {
+		r.Log.Error(err, "failed to template the compute service objects, keep the recorded ones")
+		for _, recorded := range clusterRegistrar.Status.InstalledObjects {
+			if len(recorded.Workspace) != 0 {
+				objects = append(objects, recorded)
+			}
+		}
+		return objects, nil
+	}
+	return append(objects, computeObjects...), nil
+}
+
+// recordInstalledObjects records the objects to install in the ClusterRegistrar status
+// and deletes the recorded objects which are no longer installed, like the webhook once it is disabled
+// or the hub-side objects of a hub removed from spec.hubs.
+func (r *ClusterRegistrarReconciler) recordInstalledObjects(ctx context.Context,
+	clusterRegistrar *singaporev1alpha1.ClusterRegistrar) error {
+	objects, err := r.getInstalledObjects(ctx, clusterRegistrar, webhookEnabled(clusterRegistrar))
+	if err != nil {
+		return err
+	}
+	remoteObjects, err := r.getRemoteObjects(ctx, clusterRegistrar)
+	if err != nil {
+		return err
+	}
+	installedObjects := make([]singaporev1alpha1.InstalledObject, 0, len(objects)+len(remoteObjects))
+	for _, object := range objects {
+		installedObjects = append(installedObjects, newInstalledObject(object))
+	}
+	installedObjects = append(installedObjects, remoteObjects...)
+	installed := make(map[singaporev1alpha1.InstalledObject]bool, len(installedObjects))
+	for _, installedObject := range installedObjects {
+		installed[installedObject] = true
+	}
+
+	// The hub-side objects are deleted first, the HubConfig of a removed hub gives access to the hub.
+	// The compute service objects are left in place, removing the APIExport or an APIBinding removes
+	// the RegisteredClusters of the workspaces, they are deleted only by the uninstall.
+	removed := make([]singaporev1alpha1.InstalledObject, 0)
+	for _, recorded := range clusterRegistrar.Status.InstalledObjects {
+		if !installed[recorded] && len(recorded.Workspace) == 0 {
+			removed = append(removed, recorded)
+		}
+	}
+	r.deleteRemoteObjects(ctx, clusterRegistrar, removed)
+	for _, recorded := range removed {
+		if isRemoteObject(recorded) {
+			continue
+		}
+		r.Log.Info("delete the object no longer installed", "kind", recorded.Kind, "name", recorded.Name, "namespace", recorded.Namespace)
+		if err := r.Client.Delete(ctx, newObject(recorded), client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil &&
+			!errors.IsNotFound(err) {
+			return giterrors.WithStack(err)
+		}
+	}
+
+	if equality.Semantic.DeepEqual(clusterRegistrar.Status.InstalledObjects, installedObjects) {
+		return nil
+	}
+	patch := client.MergeFrom(clusterRegistrar.DeepCopy())
+	clusterRegistrar.Status.InstalledObjects = installedObjects
+	return giterrors.WithStack(r.Client.Status().Patch(ctx, clusterRegistrar, patch))
+}
+
+// deleteRemoteObjects deletes the objects installed on the hubs and in the compute service,
+// the deletion is best-effort and the objects of an unreachable hub or workspace are left in place.
+func (r *ClusterRegistrarReconciler) deleteRemoteObjects(ctx context.Context,
+	clusterRegistrar *singaporev1alpha1.ClusterRegistrar,
+	objects []singaporev1alpha1.InstalledObject) {
+	remoteClients := make(map[singaporev1alpha1.InstalledObject]client.Client)
+	for _, object := range objects {
+		if !isRemoteObject(object) {
+			continue
+		}
+		key := singaporev1alpha1.InstalledObject{Hub: object.Hub, Workspace: object.Workspace}
+		remoteClient, ok := remoteClients[key]
+		if !ok {
+			var err error
+			remoteClient, err = r.getRemoteClient(ctx, clusterRegistrar, object)
+			if err != nil {
+				r.Log.Error(err, "failed to connect, the objects are left in place", "hub", object.Hub, "workspace", object.Workspace)
+			}
+			remoteClients[key] = remoteClient
+		}
+		if remoteClient == nil {
+			continue
+		}
+		r.Log.Info("delete", "kind", object.Kind, "name", object.Name, "namespace", object.Namespace,
+			"hub", object.Hub, "workspace", object.Workspace)
+		if err := remoteClient.Delete(ctx, newObject(object), client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil &&
+			!errors.IsNotFound(err) {
+			r.Log.Error(err, "failed to delete, the object is left in place", "kind", object.Kind, "name", object.Name,
+				"hub", object.Hub, "workspace", object.Workspace)
+		}
+	}
+}
+
+// getRemoteClient returns a client of the hub or of the compute service workspace of the object
+func (r *ClusterRegistrarReconciler) getRemoteClient(ctx context.Context,
+	clusterRegistrar *singaporev1alpha1.ClusterRegistrar,
+	object singaporev1alpha1.InstalledObject) (client.Client, error) {
+	var config *rest.Config
+	if len(object.Hub) != 0 {
+		hubConfig, err := r.getHubConfigByName(ctx, clusterRegistrar, object.Hub)
+		if err != nil {
+			return nil, err
+		}
+		config = hubConfig
+	} else {
+		computeConfig, err := r.getComputeConfig(ctx, clusterRegistrar)
+		if err != nil {
+			return nil, err
+		}
+		workspace, err := helpers.WorkspaceFromHost(computeConfig.Host)
+		if err != nil {
+			return nil, err
+		}
+		config = getWorkspaceConfig(computeConfig, workspace, object.Workspace)
+	}
+	remoteClient, err := client.New(config, client.Options{})
+	if err != nil {
+		return nil, giterrors.WithStack(err)
+	}
+	return remoteClient, nil
+}
+
+// isRemoteObject returns true for the objects installed on a hub or in the compute service
+func isRemoteObject(object singaporev1alpha1.InstalledObject) bool {
+	return len(object.Hub) != 0 || len(object.Workspace) != 0
+}
+
+// templateInstalledObject templates a file and returns the reference of its object
+func templateInstalledObject(applier apply.Applier,
+	reader *asset.ScenarioResourcesReader,
+	values interface{},
+	file string) (singaporev1alpha1.InstalledObject, error) {
+	b, err := applier.MustTemplateAsset(reader, values, "", file)
+	if err != nil {
+		return singaporev1alpha1.InstalledObject{}, giterrors.WithStack(err)
+	}
+	object := &unstructured.Unstructured{}
+	if err := yaml.Unmarshal(b, &object.Object); err != nil {
+		return singaporev1alpha1.InstalledObject{}, giterrors.Wrapf(err, "file %s", file)
+	}
+	return newInstalledObject(object), nil
+}
+
+func newInstalledObject(object *unstructured.Unstructured) singaporev1alpha1.InstalledObject {
+	return singaporev1alpha1.InstalledObject{
+		APIVersion: object.GetAPIVersion(),
+		Kind:       object.GetKind(),
+		Namespace:  object.GetNamespace(),
+		Name:       object.GetName(),
+	}
+}
+
+func newObject(installedObject singaporev1alpha1.InstalledObject) *unstructured.Unstructured {
+	object := &unstructured.Unstructured{}
+	object.SetAPIVersion(installedObject.APIVersion)
+	object.SetKind(installedObject.Kind)
+	object.SetNamespace(installedObject.Namespace)
+	object.SetName(installedObject.Name)
+	return object
+}
+
+// processClusterRegistrarDeletion deletes the recorded installed objects and returns true once none of them exists,
+// the remaining objects are reported in the ClusterRegistrar status.
+// Without record, all the objects of the installed files are deleted.
+// The objects of the hubs and of the compute service are deleted first, on a best-effort basis.
+func (r *ClusterRegistrarReconciler) processClusterRegistrarDeletion(ctx context.Context,
+	clusterRegistrar *singaporev1alpha1.ClusterRegistrar) (bool, error) {
+	r.Log.Info("processClusterRegistrarDeletion", "Name", clusterRegistrar.Name)
+	remoteObjects := make([]singaporev1alpha1.InstalledObject, 0)
+	objects := make([]*unstructured.Unstructured, 0, len(clusterRegistrar.Status.InstalledObjects))
+	for _, installedObject := range clusterRegistrar.Status.InstalledObjects {
+		if isRemoteObject(installedObject) {
+			remoteObjects = append(remoteObjects, installedObject)
+			continue
+		}
+		objects = append(objects, newObject(installedObject))
+	}
+	if len(clusterRegistrar.Status.InstalledObjects) == 0 {
+		var err error
+		objects, err = r.getInstalledObjects(ctx, clusterRegistrar, true)
+		if err != nil {
+			return false, err
+		}
+		remoteObjects, err = r.getRemoteObjects(ctx, clusterRegistrar)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	r.deleteRemoteObjects(ctx, clusterRegistrar, remoteObjects)
+
+	for _, object := range objects {
+		r.Log.Info("delete", "kind", object.GetKind(), "name", object.GetName(), "namespace", object.GetNamespace())
+		if err := r.Client.Delete(ctx, object, client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil &&
+			!errors.IsNotFound(err) {
+			return false, giterrors.WithStack(err)
+		}
+	}
+
+	// The deleted objects can remain while their finalizers run
+	leftovers := make([]singaporev1alpha1.InstalledObject, 0)
+	for _, object := range objects {
+		existing := &unstructured.Unstructured{}
+		existing.SetGroupVersionKind(object.GroupVersionKind())
+		if err := r.Client.Get(ctx, client.ObjectKeyFromObject(object), existing); err != nil {
+			if errors.IsNotFound(err) {
+				continue
+			}
+			return false, giterrors.WithStack(err)
+		}
+		leftovers = append(leftovers, newInstalledObject(object))
+	}
+
+	if err := r.updateUninstallStatus(ctx, clusterRegistrar, leftovers); err != nil {
+		return false, err
+	}
+	return len(leftovers) == 0, nil
+}
+
+// updateUninstallStatus patches the leftovers and the Uninstalled condition while objects remain
+func (r *ClusterRegistrarReconciler) updateUninstallStatus(ctx context.Context,
+	clusterRegistrar *singaporev1alpha1.ClusterRegistrar,
+	leftovers []singaporev1alpha1.InstalledObject) error {
+	if len(leftovers) == 0 {
+		// The finalizer is removed, there is no need to report the completion
+		return nil
+	}
+	names := make([]string, len(leftovers))
+	for i, leftover := range leftovers {
+		names[i] = fmt.Sprintf("%s %s", leftover.Kind, leftover.Name)
+		if len(leftover.Namespace) != 0 {
+			names[i] = fmt.Sprintf("%s %s/%s", leftover.Kind, leftover.Namespace, leftover.Name)
+		}
+	}
+	r.Log.Info("waiting for the deletion of the installed objects", "leftovers", names)
+
+	original := clusterRegistrar.DeepCopy()
+	clusterRegistrar.Status.Leftovers = leftovers
+	condition := newCondition(singaporev1alpha1.ClusterRegistrarConditionUninstalled, metav1.ConditionFalse,
+		singaporev1alpha1.ReasonUninstallInProgress, fmt.Sprintf("waiting for the deletion of %s", strings.Join(names, ", ")))
+	condition.ObservedGeneration = clusterRegistrar.Generation
+	meta.SetStatusCondition(&clusterRegistrar.Status.Conditions, condition)
+	if equality.Semantic.DeepEqual(original.Status, clusterRegistrar.Status) {
+		return nil
+	}
+	return giterrors.WithStack(r.Client.Status().Patch(ctx, clusterRegistrar, client.MergeFrom(original)))
+}
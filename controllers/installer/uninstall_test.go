@@ -0,0 +1,148 @@
+// Copyright Red Hat
+
+package installer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+	singaporev1alpha1 "github.com/stolostron/compute-operator/api/singapore/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/rest"
+)
+
+const testComputeKubeconfig = `apiVersion: v1
+kind: Config
+clusters:
+- name: kcp
+  cluster:
+    server: https://kcp.example.com:6443/clusters/root:org:compute
+contexts:
+- name: kcp
+  context:
+    cluster: kcp
+    user: kcp
+current-context: kcp
+users:
+- name: kcp
+  user:
+    token: token
+`
+
+func TestGetRemoteObjects(t *testing.T) {
+	tests := []struct {
+		name       string
+		hubs       []singaporev1alpha1.HubSpec
+		bootstrap  *singaporev1alpha1.ComputeBootstrap
+		secret     bool
+		recorded   []singaporev1alpha1.InstalledObject
+		wantHubs   map[string]int
+		wantSpaces map[string]int
+	}{
+		{
+			name: "no hub and no bootstrap",
+		},
+		{
+			name: "hubs",
+			hubs: []singaporev1alpha1.HubSpec{
+				{Name: "hub1"},
+				{Name: "hub2"},
+			},
+			wantHubs: map[string]int{"hub1": 3, "hub2": 3},
+		},
+		{
+			name: "bootstrap",
+			bootstrap: &singaporev1alpha1.ComputeBootstrap{
+				Workspaces: []string{"root:org:ws1", "root:org:ws2"},
+			},
+			secret:     true,
+			wantSpaces: map[string]int{"root:org:compute": 4, "root:org:ws1": 1, "root:org:ws2": 1},
+		},
+		{
+			name: "bootstrap without kubeconfig keeps the recorded objects",
+			bootstrap: &singaporev1alpha1.ComputeBootstrap{
+				Workspaces: []string{"root:org:ws1"},
+			},
+			recorded: []singaporev1alpha1.InstalledObject{
+				{APIVersion: "apis.kcp.dev/v1alpha1", Kind: "APIBinding", Name: "compute-operator", Workspace: "root:org:ws1"},
+				{APIVersion: "v1", Kind: "Secret", Namespace: "compute-operator", Name: "webhook-serving-cert"},
+			},
+			wantSpaces: map[string]int{"root:org:ws1": 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kubeClient := kubefake.NewSimpleClientset()
+			if tt.secret {
+				kubeClient = kubefake.NewSimpleClientset(&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{Name: "kcp-kubeconfig", Namespace: "compute-operator"},
+					Data:       map[string][]byte{"kubeconfig": []byte(testComputeKubeconfig)},
+				})
+			}
+			r := &ClusterRegistrarReconciler{
+				KubeClient:          kubeClient,
+				Log:                 logr.Discard(),
+				ControllerNamespace: "compute-operator",
+			}
+			clusterRegistrar := &singaporev1alpha1.ClusterRegistrar{
+				Spec: singaporev1alpha1.ClusterRegistrarSpec{
+					Hubs: tt.hubs,
+					ComputeService: singaporev1alpha1.ComputeService{
+						ComputeKubeconfigSecretRef: corev1.LocalObjectReference{Name: "kcp-kubeconfig"},
+						Bootstrap:                  tt.bootstrap,
+					},
+				},
+				Status: singaporev1alpha1.ClusterRegistrarStatus{
+					InstalledObjects: tt.recorded,
+				},
+			}
+			objects, err := r.getRemoteObjects(context.TODO(), clusterRegistrar)
+			if err != nil {
+				t.Fatal(err)
+			}
+			hubs := make(map[string]int)
+			workspaces := make(map[string]int)
+			for _, object := range objects {
+				if !isRemoteObject(object) {
+					t.Fatalf("Expected only remote objects, actual %v", object)
+				}
+				if object.Kind == "Namespace" || object.Kind == "APIResourceSchema" {
+					t.Fatalf("The %s must be left in place", object.Kind)
+				}
+				if len(object.Hub) != 0 {
+					hubs[object.Hub]++
+				}
+				if len(object.Workspace) != 0 {
+					workspaces[object.Workspace]++
+				}
+			}
+			if len(hubs) != len(tt.wantHubs) || len(workspaces) != len(tt.wantSpaces) {
+				t.Fatalf("Objects not as expected. Expected hubs %v and workspaces %v, actual %v and %v", tt.wantHubs, tt.wantSpaces, hubs, workspaces)
+			}
+			for hub, count := range tt.wantHubs {
+				if hubs[hub] != count {
+					t.Fatalf("Objects of hub %s not as expected. Expected %d, actual %d", hub, count, hubs[hub])
+				}
+			}
+			for workspace, count := range tt.wantSpaces {
+				if workspaces[workspace] != count {
+					t.Fatalf("Objects of workspace %s not as expected. Expected %d, actual %d", workspace, count, workspaces[workspace])
+				}
+			}
+		})
+	}
+}
+
+func TestGetWorkspaceConfig(t *testing.T) {
+	config := &rest.Config{Host: "https://kcp.example.com:6443/clusters/root:org:compute"}
+	workspaceConfig := getWorkspaceConfig(config, "root:org:compute", "root:org:ws1")
+	if want := "https://kcp.example.com:6443/clusters/root:org:ws1"; workspaceConfig.Host != want {
+		t.Fatalf("Host not as expected. Expected %s, actual %s", want, workspaceConfig.Host)
+	}
+	if config.Host != "https://kcp.example.com:6443/clusters/root:org:compute" {
+		t.Fatalf("The config must not be modified, actual %s", config.Host)
+	}
+}
@@ -0,0 +1,236 @@
+// Copyright Red Hat
+
+package installer
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ghodss/yaml"
+	giterrors "github.com/pkg/errors"
+
+	"github.com/stolostron/applier/pkg/apply"
+	singaporev1alpha1 "github.com/stolostron/compute-operator/api/singapore/v1alpha1"
+	clusterregistrarconfig "github.com/stolostron/compute-operator/config"
+	"github.com/stolostron/compute-operator/pkg/helpers"
+	appsv1 "k8s.io/api/apps/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/version"
+	"k8s.io/client-go/dynamic"
+)
+
+const (
+	// crdEstablishedRequeueDelay is the delay between the checks of the upgraded CRDs
+	crdEstablishedRequeueDelay = 5 * time.Second
+	// migrationPageSize is the page size of the objects listed by the storage version migration
+	migrationPageSize = 100
+)
+
+// crdFiles are the CRDs of the operator in their upgrade order
+var crdFiles = []string{
+	"crd/singapore.open-cluster-management.io_clusterregistrars.yaml",
+	"crd/singapore.open-cluster-management.io_hubconfigs.yaml",
+	"crd/singapore.open-cluster-management.io_registeredclusters.yaml",
+}
+
+// isDowngrade returns true when the installer version is older than the installed version,
+// versions which are not semantic versions are not compared.
+func isDowngrade(installedVersion, installerVersion string) bool {
+	if len(installedVersion) == 0 {
+		return false
+	}
+	installed, err := version.ParseGeneric(installedVersion)
+	if err != nil {
+		return false
+	}
+	installer, err := version.ParseGeneric(installerVersion)
+	if err != nil {
+		return false
+	}
+	return installer.LessThan(installed)
+}
+
+// getCRDs returns the CRDs of the operator in their upgrade order
+func getCRDs() ([]*apiextensionsv1.CustomResourceDefinition, error) {
+	reader := clusterregistrarconfig.GetScenarioResourcesReader()
+	crds := make([]*apiextensionsv1.CustomResourceDefinition, 0, len(crdFiles))
+	for _, file := range crdFiles {
+		b, err := reader.Asset(file)
+		if err != nil {
+			return nil, giterrors.WithStack(err)
+		}
+		crd := &apiextensionsv1.CustomResourceDefinition{}
+		if err := yaml.Unmarshal(b, crd); err != nil {
+			return nil, giterrors.WithStack(err)
+		}
+		crds = append(crds, crd)
+	}
+	return crds, nil
+}
+
+// createMissingCRDs creates the CRDs which don't exist yet, the existing CRDs are only updated by the upgrade
+// once the ClusterRegistrar is checked against a downgrade.
+func (r *ClusterRegistrarReconciler) createMissingCRDs(ctx context.Context) error {
+	crds, err := getCRDs()
+	if err != nil {
+		return err
+	}
+	for _, crd := range crds {
+		if _, err := r.APIExtensionClient.ApiextensionsV1().CustomResourceDefinitions().Create(ctx, crd, metav1.CreateOptions{}); err != nil {
+			if errors.IsAlreadyExists(err) {
+				continue
+			}
+			return giterrors.WithStack(err)
+		}
+		r.Log.Info("crd created", "name", crd.Name)
+	}
+	return nil
+}
+
+// upgradeCRDs applies the CRDs one at a time in their upgrade order and migrates their objects to the storage version.
+// It returns false while a CRD is not established, the next CRDs are then applied by a later reconcile.
+func (r *ClusterRegistrarReconciler) upgradeCRDs(ctx context.Context, upgrading bool) (bool, error) {
+	applier := apply.NewApplierBuilder().
+		WithClient(r.KubeClient, r.APIExtensionClient, r.DynamicClient).
+		WithContext(ctx).
+		Build()
+	reader := clusterregistrarconfig.GetScenarioResourcesReader()
+	crds, err := getCRDs()
+	if err != nil {
+		return false, err
+	}
+	for i, file := range crdFiles {
+		if _, err := applier.ApplyDirectly(reader, nil, false, "", file); err != nil {
+			return false, giterrors.WithStack(err)
+		}
+		crd, err := r.APIExtensionClient.ApiextensionsV1().CustomResourceDefinitions().Get(ctx, crds[i].Name, metav1.GetOptions{})
+		if err != nil {
+			return false, giterrors.WithStack(err)
+		}
+		if !isCRDEstablished(crd) {
+			r.Log.Info("waiting for the crd to be established", "name", crd.Name)
+			return false, nil
+		}
+		if upgrading {
+			if err := r.migrateStorageVersion(ctx, crd); err != nil {
+				return false, err
+			}
+		}
+	}
+	return true, nil
+}
+
+func isCRDEstablished(crd *apiextensionsv1.CustomResourceDefinition) bool {
+	for _, condition := range crd.Status.Conditions {
+		if condition.Type == apiextensionsv1.Established && condition.Status == apiextensionsv1.ConditionTrue {
+			return true
+		}
+	}
+	return false
+}
+
+// migrateStorageVersion rewrites the objects of the CRD stored in a previous version in the storage version
+// and removes the previous versions from the CRD storedVersions.
+// The objects are listed by pages, an object modified since it was listed is already rewritten.
+func (r *ClusterRegistrarReconciler) migrateStorageVersion(ctx context.Context, crd *apiextensionsv1.CustomResourceDefinition) error {
+	storageVersion := ""
+	for _, crdVersion := range crd.Spec.Versions {
+		if crdVersion.Storage {
+			storageVersion = crdVersion.Name
+		}
+	}
+	if len(storageVersion) == 0 ||
+		(len(crd.Status.StoredVersions) == 1 && crd.Status.StoredVersions[0] == storageVersion) {
+		return nil
+	}
+
+	r.Log.Info("migrate the storage version", "crd", crd.Name, "storedVersions", crd.Status.StoredVersions, "storageVersion", storageVersion)
+	gvr := schema.GroupVersionResource{Group: crd.Spec.Group, Version: storageVersion, Resource: crd.Spec.Names.Plural}
+	opts := metav1.ListOptions{Limit: migrationPageSize}
+	for {
+		list, err := r.DynamicClient.Resource(gvr).List(ctx, opts)
+		if err != nil {
+			return giterrors.WithStack(err)
+		}
+		for i := range list.Items {
+			item := &list.Items[i]
+			if isWrittenInVersion(item, gvr.GroupVersion().String()) {
+				continue
+			}
+			var resource dynamic.ResourceInterface = r.DynamicClient.Resource(gvr)
+			if len(item.GetNamespace()) != 0 {
+				resource = r.DynamicClient.Resource(gvr).Namespace(item.GetNamespace())
+			}
+			// An update without change writes the object in the storage version
+			if _, err := resource.Update(ctx, item, metav1.UpdateOptions{}); err != nil &&
+				!errors.IsNotFound(err) && !errors.IsConflict(err) {
+				return giterrors.Wrapf(err, "migrate %s %s", crd.Name, item.GetName())
+			}
+		}
+		if len(list.GetContinue()) == 0 {
+			break
+		}
+		opts.Continue = list.GetContinue()
+	}
+
+	crd.Status.StoredVersions = []string{storageVersion}
+	if _, err := r.APIExtensionClient.ApiextensionsV1().CustomResourceDefinitions().UpdateStatus(ctx, crd, metav1.UpdateOptions{}); err != nil {
+		return giterrors.WithStack(err)
+	}
+	return nil
+}
+
+// isWrittenInVersion returns true when all the writes recorded in the managed fields of the object
+// were made through the API version. The versions of the CRDs are served and stored from the same release,
+// so an object only written through the storage version is stored in it.
+func isWrittenInVersion(object *unstructured.Unstructured, apiVersion string) bool {
+	managedFields := object.GetManagedFields()
+	if len(managedFields) == 0 {
+		return false
+	}
+	for _, managedField := range managedFields {
+		if managedField.APIVersion != apiVersion {
+			return false
+		}
+	}
+	return true
+}
+
+// isRolledOut returns true when all the replicas of the deployment run its latest spec and are available
+func (r *ClusterRegistrarReconciler) isRolledOut(ctx context.Context, name string) (bool, error) {
+	deployment := &appsv1.Deployment{}
+	if err := r.Client.Get(ctx, types.NamespacedName{Namespace: r.ControllerNamespace, Name: name}, deployment); err != nil {
+		if errors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, giterrors.WithStack(err)
+	}
+	replicas := int32(1)
+	if deployment.Spec.Replicas != nil {
+		replicas = *deployment.Spec.Replicas
+	}
+	return deployment.Status.ObservedGeneration >= deployment.Generation &&
+		deployment.Status.UpdatedReplicas >= replicas &&
+		deployment.Status.Replicas == deployment.Status.UpdatedReplicas &&
+		deployment.Status.AvailableReplicas >= deployment.Status.UpdatedReplicas, nil
+}
+
+// getUpgradedCondition returns the Upgraded condition of the installation step
+func getUpgradedCondition(installedVersion string, completed bool, step string) metav1.Condition {
+	if completed {
+		return newCondition(singaporev1alpha1.ClusterRegistrarConditionUpgraded, metav1.ConditionTrue,
+			singaporev1alpha1.ReasonUpgradeCompleted, fmt.Sprintf("version %s installed", helpers.Version))
+	}
+	from := installedVersion
+	if len(from) == 0 {
+		from = "none"
+	}
+	return newCondition(singaporev1alpha1.ClusterRegistrarConditionUpgraded, metav1.ConditionFalse,
+		singaporev1alpha1.ReasonUpgradeInProgress, fmt.Sprintf("upgrading from %s to %s: %s", from, helpers.Version, step))
+}
@@ -0,0 +1,75 @@
+// Copyright Red Hat
+
+package installer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apiextensionsfake "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset/fake"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+func newTestMigratedObject(name, apiVersion string) *unstructured.Unstructured {
+	object := &unstructured.Unstructured{}
+	object.SetAPIVersion("example.io/v1beta1")
+	object.SetKind("Example")
+	object.SetName(name)
+	object.SetNamespace("ns1")
+	object.SetManagedFields([]metav1.ManagedFieldsEntry{{Manager: "test", APIVersion: apiVersion}})
+	return object
+}
+
+func TestMigrateStorageVersion(t *testing.T) {
+	crd := &apiextensionsv1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: "examples.example.io"},
+		Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+			Group: "example.io",
+			Names: apiextensionsv1.CustomResourceDefinitionNames{Plural: "examples", ListKind: "ExampleList"},
+			Versions: []apiextensionsv1.CustomResourceDefinitionVersion{
+				{Name: "v1alpha1", Served: true},
+				{Name: "v1beta1", Served: true, Storage: true},
+			},
+		},
+		Status: apiextensionsv1.CustomResourceDefinitionStatus{
+			StoredVersions: []string{"v1alpha1", "v1beta1"},
+		},
+	}
+	gvr := schema.GroupVersionResource{Group: "example.io", Version: "v1beta1", Resource: "examples"}
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{gvr: "ExampleList"},
+		newTestMigratedObject("old", "example.io/v1alpha1"),
+		newTestMigratedObject("new", "example.io/v1beta1"))
+	r := &ClusterRegistrarReconciler{
+		DynamicClient:      dynamicClient,
+		APIExtensionClient: apiextensionsfake.NewSimpleClientset(crd),
+		Log:                logr.Discard(),
+	}
+
+	if err := r.migrateStorageVersion(context.TODO(), crd.DeepCopy()); err != nil {
+		t.Fatal(err)
+	}
+	updated := make([]string, 0)
+	for _, action := range dynamicClient.Actions() {
+		if action.GetVerb() == "update" {
+			updated = append(updated, action.(clienttesting.UpdateAction).GetObject().(*unstructured.Unstructured).GetName())
+		}
+	}
+	if len(updated) != 1 || updated[0] != "old" {
+		t.Fatalf("Rewritten objects not as expected. Expected [old], actual %v", updated)
+	}
+	migrated, err := r.APIExtensionClient.ApiextensionsV1().CustomResourceDefinitions().Get(context.TODO(), crd.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrated.Status.StoredVersions) != 1 || migrated.Status.StoredVersions[0] != "v1beta1" {
+		t.Fatalf("Stored versions not as expected. Expected [v1beta1], actual %v", migrated.Status.StoredVersions)
+	}
+}
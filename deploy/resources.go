@@ -7,7 +7,7 @@ import (
 	"github.com/stolostron/applier/pkg/asset"
 )
 
-//go:embed compute-operator webhook
+//go:embed compute-operator webhook hubconfig hub
 var files embed.FS
 
 func GetScenarioResourcesReader() *asset.ScenarioResourcesReader {
@@ -12,7 +12,9 @@ require (
 	github.com/onsi/ginkgo/v2 v2.1.3
 	github.com/onsi/gomega v1.19.0
 	github.com/openshift/generic-admission-server v1.14.1-0.20220220163846-6395b86cc87e
+	github.com/openshift/library-go v0.0.0-20220713145611-ca167a8bd342
 	github.com/pkg/errors v0.9.1
+	github.com/prometheus/client_golang v1.12.1
 	github.com/spf13/cobra v1.5.0
 	github.com/spf13/pflag v1.0.5
 	github.com/stolostron/applier v1.1.1-0.20220802153057-24eb6dde5781
@@ -79,8 +81,6 @@ require (
 	github.com/modern-go/reflect2 v1.0.2 // indirect
 	github.com/munnerz/goautoneg v0.0.0-20191010083416-a7dc8b61c822 // indirect
 	github.com/openshift/api v0.0.0-20220525145417-ee5b62754c68 // indirect
-	github.com/openshift/library-go v0.0.0-20220713145611-ca167a8bd342 // indirect
-	github.com/prometheus/client_golang v1.12.1 // indirect
 	github.com/prometheus/client_model v0.2.0 // indirect
 	github.com/prometheus/common v0.32.1 // indirect
 	github.com/prometheus/procfs v0.7.3 // indirect
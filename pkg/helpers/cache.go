@@ -3,10 +3,14 @@
 package helpers
 
 import (
+	"context"
+
 	kcpcache "github.com/kcp-dev/apimachinery/pkg/cache"
 	"github.com/kcp-dev/apimachinery/third_party/informers"
+	giterrors "github.com/pkg/errors"
 	"k8s.io/client-go/rest"
 	k8scache "k8s.io/client-go/tools/cache"
+	clusterapiv1 "open-cluster-management.io/api/cluster/v1"
 	"sigs.k8s.io/controller-runtime/pkg/cache"
 )
 
@@ -20,3 +24,20 @@ var (
 		return cache.New(config, opts)
 	}
 )
+
+// CountManagedClusters returns the number of managedclusters of the hub from the hub informer,
+// without copying the managedclusters as a list would do.
+func CountManagedClusters(ctx context.Context, hubInstance HubInstance) (int, error) {
+	informer, err := hubInstance.Cluster.GetCache().GetInformer(ctx, &clusterapiv1.ManagedCluster{})
+	if err != nil {
+		return 0, giterrors.WithStack(err)
+	}
+	if indexInformer, ok := informer.(k8scache.SharedIndexInformer); ok {
+		return len(indexInformer.GetStore().ListKeys()), nil
+	}
+	managedClusterList := &clusterapiv1.ManagedClusterList{}
+	if err := hubInstance.Client.List(ctx, managedClusterList); err != nil {
+		return 0, giterrors.WithStack(err)
+	}
+	return len(managedClusterList.Items), nil
+}
@@ -0,0 +1,91 @@
+// Copyright Red Hat
+
+package helpers
+
+import (
+	"errors"
+	"net"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// ErrorClass classifies the reconcile errors to report them and to select their backoff
+type ErrorClass string
+
+const (
+	// ErrorClassTransient is an error expected to disappear on the next attempt
+	ErrorClassTransient ErrorClass = "Transient"
+	// ErrorClassConfiguration is an error which requires a change of the spec or of the operator configuration
+	ErrorClassConfiguration ErrorClass = "Configuration"
+	// ErrorClassPermission is an error caused by missing permissions or invalid credentials
+	ErrorClassPermission ErrorClass = "Permission"
+	// ErrorClassHubUnreachable is an error caused by a hub API server which can not be reached
+	ErrorClassHubUnreachable ErrorClass = "HubUnreachable"
+)
+
+// ConfigurationError marks an error which can only be fixed by changing the configuration
+type ConfigurationError struct {
+	err error
+}
+
+// NewConfigurationError marks err as a configuration error
+func NewConfigurationError(err error) error {
+	return &ConfigurationError{err: err}
+}
+
+func (e *ConfigurationError) Error() string {
+	return e.err.Error()
+}
+
+func (e *ConfigurationError) Unwrap() error {
+	return e.err
+}
+
+// HubError marks an error returned while calling a hub
+type HubError struct {
+	Hub string
+	err error
+}
+
+// NewHubError marks err as returned by the hub, nil is returned if err is nil
+func NewHubError(hub string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &HubError{Hub: hub, err: err}
+}
+
+func (e *HubError) Error() string {
+	return "hub " + e.Hub + ": " + e.err.Error()
+}
+
+func (e *HubError) Unwrap() error {
+	return e.err
+}
+
+// ClassifyError returns the class of a reconcile error
+func ClassifyError(err error) ErrorClass {
+	var configurationError *ConfigurationError
+	if errors.As(err, &configurationError) {
+		return ErrorClassConfiguration
+	}
+	switch {
+	case k8serrors.IsForbidden(err), k8serrors.IsUnauthorized(err):
+		return ErrorClassPermission
+	case k8serrors.IsInvalid(err), k8serrors.IsBadRequest(err):
+		return ErrorClassConfiguration
+	}
+	var hubError *HubError
+	if errors.As(err, &hubError) && isUnreachable(err) {
+		return ErrorClassHubUnreachable
+	}
+	return ErrorClassTransient
+}
+
+func isUnreachable(err error) bool {
+	var netError net.Error
+	return errors.As(err, &netError) ||
+		k8serrors.IsServiceUnavailable(err) ||
+		k8serrors.IsTimeout(err) ||
+		k8serrors.IsServerTimeout(err)
+}
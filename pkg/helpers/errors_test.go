@@ -0,0 +1,81 @@
+// Copyright Red Hat
+
+package helpers
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	giterrors "github.com/pkg/errors"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestClassifyError(t *testing.T) {
+	gr := schema.GroupResource{Group: "cluster.open-cluster-management.io", Resource: "managedclusters"}
+	tests := []struct {
+		name string
+		err  error
+		want ErrorClass
+	}{
+		{
+			name: "generic error",
+			err:  errors.New("failed"),
+			want: ErrorClassTransient,
+		},
+		{
+			name: "conflict",
+			err:  giterrors.WithStack(k8serrors.NewConflict(gr, "cluster1", errors.New("conflict"))),
+			want: ErrorClassTransient,
+		},
+		{
+			name: "configuration error",
+			err:  giterrors.WithStack(NewConfigurationError(errors.New("hub hub1 is not configured"))),
+			want: ErrorClassConfiguration,
+		},
+		{
+			name: "invalid object",
+			err:  k8serrors.NewInvalid(schema.GroupKind{Kind: "ManagedCluster"}, "cluster1", nil),
+			want: ErrorClassConfiguration,
+		},
+		{
+			name: "forbidden",
+			err:  NewHubError("hub1", giterrors.WithStack(k8serrors.NewForbidden(gr, "cluster1", errors.New("denied")))),
+			want: ErrorClassPermission,
+		},
+		{
+			name: "unauthorized",
+			err:  k8serrors.NewUnauthorized("expired token"),
+			want: ErrorClassPermission,
+		},
+		{
+			name: "hub connection refused",
+			err:  NewHubError("hub1", giterrors.WithStack(&net.OpError{Op: "dial", Err: errors.New("connection refused")})),
+			want: ErrorClassHubUnreachable,
+		},
+		{
+			name: "hub unavailable",
+			err:  NewHubError("hub1", k8serrors.NewServiceUnavailable("unavailable")),
+			want: ErrorClassHubUnreachable,
+		},
+		{
+			name: "compute unavailable",
+			err:  k8serrors.NewServiceUnavailable("unavailable"),
+			want: ErrorClassTransient,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ClassifyError(tt.err); got != tt.want {
+				t.Errorf("ClassifyError() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewHubErrorNil(t *testing.T) {
+	if err := NewHubError("hub1", nil); err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+}
@@ -0,0 +1,69 @@
+// Copyright Red Hat
+
+package helpers
+
+import (
+	"context"
+
+	"github.com/kcp-dev/logicalcluster/v2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// EventRecorder records events in the logical cluster of the involved object
+type EventRecorder struct {
+	recorder record.EventRecorder
+}
+
+// clusterAwareEventSink creates the events in the logical cluster found in the event annotations
+type clusterAwareEventSink struct {
+	kubeClient kubernetes.Interface
+}
+
+var _ record.EventSink = &clusterAwareEventSink{}
+
+// NewEventRecorder returns an EventRecorder which creates the events with the kubeClient,
+// the kubeClient must be able to target a logical cluster through the context.
+func NewEventRecorder(kubeClient kubernetes.Interface, scheme *runtime.Scheme, component string) *EventRecorder {
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartRecordingToSink(&clusterAwareEventSink{kubeClient: kubeClient})
+	return &EventRecorder{
+		recorder: broadcaster.NewRecorder(scheme, corev1.EventSource{Component: component}),
+	}
+}
+
+// Event records an event on the object in its logical cluster
+func (e *EventRecorder) Event(object client.Object, eventtype, reason, message string) {
+	e.recorder.AnnotatedEventf(object, clusterAnnotations(object), eventtype, reason, "%s", message)
+}
+
+// Eventf is like Event, but with Sprintf for the message
+func (e *EventRecorder) Eventf(object client.Object, eventtype, reason, messageFmt string, args ...interface{}) {
+	e.recorder.AnnotatedEventf(object, clusterAnnotations(object), eventtype, reason, messageFmt, args...)
+}
+
+func clusterAnnotations(object client.Object) map[string]string {
+	return map[string]string{
+		logicalcluster.AnnotationKey: logicalcluster.From(object).String(),
+	}
+}
+
+func (s *clusterAwareEventSink) Create(event *corev1.Event) (*corev1.Event, error) {
+	ctx := logicalcluster.WithCluster(context.TODO(), logicalcluster.From(event))
+	return s.kubeClient.CoreV1().Events(event.Namespace).Create(ctx, event, metav1.CreateOptions{})
+}
+
+func (s *clusterAwareEventSink) Update(event *corev1.Event) (*corev1.Event, error) {
+	ctx := logicalcluster.WithCluster(context.TODO(), logicalcluster.From(event))
+	return s.kubeClient.CoreV1().Events(event.Namespace).Update(ctx, event, metav1.UpdateOptions{})
+}
+
+func (s *clusterAwareEventSink) Patch(event *corev1.Event, data []byte) (*corev1.Event, error) {
+	ctx := logicalcluster.WithCluster(context.TODO(), logicalcluster.From(event))
+	return s.kubeClient.CoreV1().Events(event.Namespace).Patch(ctx, event.Name, types.StrategicMergePatchType, data, metav1.PatchOptions{})
+}
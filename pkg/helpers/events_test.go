@@ -0,0 +1,51 @@
+// Copyright Red Hat
+
+package helpers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kcp-dev/logicalcluster/v2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/kubernetes/scheme"
+)
+
+func TestEventRecorder(t *testing.T) {
+	kubeClient := kubefake.NewSimpleClientset()
+	recorder := NewEventRecorder(kubeClient, scheme.Scheme, "test")
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "cm1",
+			Namespace:   "ns1",
+			Annotations: map[string]string{logicalcluster.AnnotationKey: "root:org:ws1"},
+		},
+	}
+	recorder.Eventf(configMap, corev1.EventTypeNormal, "Created", "ConfigMap %s created", configMap.Name)
+
+	var events *corev1.EventList
+	if err := wait.PollImmediate(10*time.Millisecond, 5*time.Second, func() (bool, error) {
+		var err error
+		events, err = kubeClient.CoreV1().Events("ns1").List(context.TODO(), metav1.ListOptions{})
+		if err != nil {
+			return false, err
+		}
+		return len(events.Items) > 0, nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	event := events.Items[0]
+	if event.Reason != "Created" || event.Message != "ConfigMap cm1 created" {
+		t.Fatalf("Event not as expected. Actual reason %s, message %s", event.Reason, event.Message)
+	}
+	if event.InvolvedObject.Name != configMap.Name {
+		t.Fatalf("Involved object not as expected. Expected %s, actual %s", configMap.Name, event.InvolvedObject.Name)
+	}
+	if cluster := logicalcluster.From(&event); cluster.String() != "root:org:ws1" {
+		t.Fatalf("Event logical cluster not as expected. Expected root:org:ws1, actual %s", cluster)
+	}
+}
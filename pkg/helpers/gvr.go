@@ -9,4 +9,12 @@ var (
 		Group:    "singapore.open-cluster-management.io",
 		Version:  "v1alpha1",
 		Resource: "clusterregistrars"}
+	GvrRC schema.GroupVersionResource = schema.GroupVersionResource{
+		Group:    "singapore.open-cluster-management.io",
+		Version:  "v1alpha1",
+		Resource: "registeredclusters"}
+	GvrAPIBinding schema.GroupVersionResource = schema.GroupVersionResource{
+		Group:    "apis.kcp.dev",
+		Version:  "v1alpha1",
+		Resource: "apibindings"}
 )
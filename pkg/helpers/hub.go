@@ -7,6 +7,7 @@ import (
 	"errors"
 	"os"
 	"strconv"
+	"sync"
 
 	"github.com/stolostron/applier/pkg/apply"
 	singaporev1alpha1 "github.com/stolostron/compute-operator/api/singapore/v1alpha1"
@@ -145,6 +146,8 @@ func getHubInstance(ctx context.Context, kubeConfigData []byte, mgr ctrl.Manager
 		hubKubeconfig.QPS = 100.0
 	}
 
+	hubKubeconfig.Wrap(newHubAPIErrorsRoundTripper(hubConfig.Name))
+
 	// Add MCE cluster
 	hubCluster, err := cluster.New(hubKubeconfig,
 		func(o *cluster.Options) {
@@ -199,10 +202,25 @@ func getHubInstance(ctx context.Context, kubeConfigData []byte, mgr ctrl.Manager
 	return &hubInstance, nil
 }
 
+// managedClusterSetNamesMutex protects the ManagedClusterSetNames of the hubs against the concurrent reconciles
+var managedClusterSetNamesMutex sync.RWMutex
+
 func AddManagedClusterSetName(hubInstance HubInstance, workspaceName string) {
+	managedClusterSetNamesMutex.Lock()
+	defer managedClusterSetNamesMutex.Unlock()
 	hubInstance.ManagedClusterSetNames[ComputeWorkspaceName(workspaceName)] = member
 }
 
 func RemoveManagedClusterSetName(hubInstance HubInstance, workspaceName string) {
+	managedClusterSetNamesMutex.Lock()
+	defer managedClusterSetNamesMutex.Unlock()
 	delete(hubInstance.ManagedClusterSetNames, ComputeWorkspaceName(workspaceName))
 }
+
+// HasManagedClusterSetName returns true if the workspace is assigned to the hub
+func HasManagedClusterSetName(hubInstance HubInstance, workspaceName string) bool {
+	managedClusterSetNamesMutex.RLock()
+	defer managedClusterSetNamesMutex.RUnlock()
+	_, ok := hubInstance.ManagedClusterSetNames[ComputeWorkspaceName(workspaceName)]
+	return ok
+}
@@ -5,10 +5,14 @@ package helpers
 import (
 	"context"
 	"fmt"
+	"net/url"
+	"strings"
 
 	apisv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/apis/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/rest"
 	"k8s.io/klog/v2"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -57,3 +61,36 @@ func RestConfigForAPIExport(ctx context.Context, cfg *rest.Config, apiExportName
 
 	return cfg, nil
 }
+
+// GetSyncTargetIdentityHash returns the identity hash of the synctargets bound in the workspace
+// by the workload.kcp.dev APIBinding, the permission claims on the synctargets require it.
+func GetSyncTargetIdentityHash(ctx context.Context, dynamicClient dynamic.Interface) (string, error) {
+	apiBindingU, err := dynamicClient.Resource(GvrAPIBinding).Get(ctx, "workload.kcp.dev", metav1.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("error getting APIBinding %q: %w", "workload.kcp.dev", err)
+	}
+	apiBinding := &apisv1alpha1.APIBinding{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(apiBindingU.Object, apiBinding); err != nil {
+		return "", err
+	}
+	for _, boundResource := range apiBinding.Status.BoundResources {
+		if boundResource.Resource == "synctargets" && len(boundResource.Schema.IdentityHash) != 0 {
+			return boundResource.Schema.IdentityHash, nil
+		}
+	}
+	return "", fmt.Errorf("APIBinding %q doesn't bind the synctargets", "workload.kcp.dev")
+}
+
+// WorkspaceFromHost returns the workspace of a kcp server URL ending with /clusters/<workspace>
+func WorkspaceFromHost(host string) (string, error) {
+	u, err := url.Parse(host)
+	if err != nil {
+		return "", err
+	}
+	path := strings.TrimSuffix(u.Path, "/")
+	i := strings.LastIndex(path, "/clusters/")
+	if i < 0 || len(path[i+len("/clusters/"):]) == 0 {
+		return "", fmt.Errorf("no workspace in the server URL %s", host)
+	}
+	return path[i+len("/clusters/"):], nil
+}
@@ -0,0 +1,81 @@
+// Copyright Red Hat
+
+package helpers
+
+import (
+	"context"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+func TestGetSyncTargetIdentityHash(t *testing.T) {
+	apiBinding := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "apis.kcp.dev/v1alpha1",
+			"kind":       "APIBinding",
+			"metadata": map[string]interface{}{
+				"name": "workload.kcp.dev",
+			},
+			"status": map[string]interface{}{
+				"boundResources": []interface{}{
+					map[string]interface{}{
+						"group":    "workload.kcp.dev",
+						"resource": "synctargets",
+						"schema": map[string]interface{}{
+							"name":         "v1alpha1.synctargets.workload.kcp.dev",
+							"UID":          "uid",
+							"identityHash": "hash",
+						},
+					},
+				},
+			},
+		},
+	}
+	gvrToListKind := map[schema.GroupVersionResource]string{GvrAPIBinding: "APIBindingList"}
+
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), gvrToListKind, apiBinding)
+	identityHash, err := GetSyncTargetIdentityHash(context.TODO(), dynamicClient)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if identityHash != "hash" {
+		t.Fatalf("Identity hash is not as expected. Expected hash, actual %s", identityHash)
+	}
+
+	dynamicClient = dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), gvrToListKind)
+	if _, err := GetSyncTargetIdentityHash(context.TODO(), dynamicClient); err == nil {
+		t.Fatalf("expected an error without APIBinding")
+	}
+}
+
+func TestWorkspaceFromHost(t *testing.T) {
+	tests := []struct {
+		host      string
+		workspace string
+		wantErr   bool
+	}{
+		{host: "https://kcp.example.com:6443/clusters/root:my-org", workspace: "root:my-org"},
+		{host: "https://kcp.example.com:6443/clusters/root:my-org/", workspace: "root:my-org"},
+		{host: "https://kcp.example.com:6443", wantErr: true},
+		{host: "https://kcp.example.com:6443/clusters/", wantErr: true},
+	}
+	for _, test := range tests {
+		workspace, err := WorkspaceFromHost(test.host)
+		if test.wantErr {
+			if err == nil {
+				t.Fatalf("expected an error for %s", test.host)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("unexpected error for %s: %s", test.host, err)
+		}
+		if workspace != test.workspace {
+			t.Fatalf("Workspace is not as expected. Expected %s, actual %s", test.workspace, workspace)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+// Copyright Red Hat
+
+package helpers
+
+import "sync"
+
+// KeyedMutex serializes the callers using the same key while callers using different keys run in parallel
+type KeyedMutex struct {
+	mu    sync.Mutex
+	locks map[string]*keyedLock
+}
+
+type keyedLock struct {
+	mu sync.Mutex
+	// waiters is the number of callers holding or waiting for the lock
+	waiters int
+}
+
+// NewKeyedMutex returns an empty KeyedMutex
+func NewKeyedMutex() *KeyedMutex {
+	return &KeyedMutex{
+		locks: make(map[string]*keyedLock),
+	}
+}
+
+// Lock locks the key and returns the function unlocking it
+func (m *KeyedMutex) Lock(key string) func() {
+	m.mu.Lock()
+	lock, ok := m.locks[key]
+	if !ok {
+		lock = &keyedLock{}
+		m.locks[key] = lock
+	}
+	lock.waiters++
+	m.mu.Unlock()
+
+	lock.mu.Lock()
+	return func() {
+		lock.mu.Unlock()
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		lock.waiters--
+		// Forget the keys no longer used to not grow with the number of workspaces
+		if lock.waiters == 0 {
+			delete(m.locks, key)
+		}
+	}
+}
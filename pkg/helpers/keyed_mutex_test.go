@@ -0,0 +1,58 @@
+// Copyright Red Hat
+
+package helpers
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestKeyedMutexSerializesSameKey(t *testing.T) {
+	m := NewKeyedMutex()
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	running, maxRunning := 0, 0
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			unlock := m.Lock("root:org:ws1")
+			defer unlock()
+			mu.Lock()
+			running++
+			if running > maxRunning {
+				maxRunning = running
+			}
+			mu.Unlock()
+			time.Sleep(time.Millisecond)
+			mu.Lock()
+			running--
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	if maxRunning != 1 {
+		t.Fatalf("expected a single caller at a time, got %d", maxRunning)
+	}
+	if len(m.locks) != 0 {
+		t.Fatalf("expected the unused keys to be forgotten, got %d", len(m.locks))
+	}
+}
+
+func TestKeyedMutexDifferentKeys(t *testing.T) {
+	m := NewKeyedMutex()
+	unlock := m.Lock("root:org:ws1")
+	defer unlock()
+	locked := make(chan struct{})
+	go func() {
+		unlock := m.Lock("root:org:ws2")
+		defer unlock()
+		close(locked)
+	}()
+	select {
+	case <-locked:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("expected another key to be locked while the first one is held")
+	}
+}
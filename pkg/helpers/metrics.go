@@ -0,0 +1,59 @@
+// Copyright Red Hat
+
+package helpers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// MetricsNamespace prefixes all compute-operator metrics
+const MetricsNamespace = "compute_operator"
+
+var hubAPIErrors = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "hub_api_errors_total",
+		Help:      "Number of failed requests to the hub API servers, by hub and status code.",
+	},
+	[]string{"hub", "code"},
+)
+
+func init() {
+	metrics.Registry.MustRegister(hubAPIErrors)
+}
+
+// hubAPIErrorsRoundTripper counts the requests to a hub which fail or are rejected by the hub
+type hubAPIErrorsRoundTripper struct {
+	hub      string
+	delegate http.RoundTripper
+}
+
+func newHubAPIErrorsRoundTripper(hub string) func(rt http.RoundTripper) http.RoundTripper {
+	return func(rt http.RoundTripper) http.RoundTripper {
+		return &hubAPIErrorsRoundTripper{hub: hub, delegate: rt}
+	}
+}
+
+func (rt *hubAPIErrorsRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := rt.delegate.RoundTrip(req)
+	switch {
+	case err != nil:
+		hubAPIErrors.WithLabelValues(rt.hub, "transport").Inc()
+	case isHubAPIError(resp.StatusCode):
+		hubAPIErrors.WithLabelValues(rt.hub, strconv.Itoa(resp.StatusCode)).Inc()
+	}
+	return resp, err
+}
+
+// isHubAPIError returns true for the status codes which are not part of the normal reconcile flow,
+// NotFound and Conflict responses are expected.
+func isHubAPIError(code int) bool {
+	return code >= http.StatusInternalServerError ||
+		code == http.StatusTooManyRequests ||
+		code == http.StatusUnauthorized ||
+		code == http.StatusForbidden
+}
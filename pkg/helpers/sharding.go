@@ -0,0 +1,365 @@
+// Copyright Red Hat
+
+package helpers
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	giterrors "github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const (
+	// ShardLeasePrefix prefixes the names of the leases of the shards
+	ShardLeasePrefix = "compute-operator-shard-"
+	// ShardMemberLeasePrefix prefixes the names of the leases announcing the replicas
+	ShardMemberLeasePrefix = "compute-operator-member-"
+	// ShardMemberLabel labels the leases announcing the replicas
+	ShardMemberLabel = "compute-operator.open-cluster-management.io/shard-member"
+)
+
+var ownedShards = prometheus.NewGauge(
+	prometheus.GaugeOpts{
+		Namespace: MetricsNamespace,
+		Name:      "owned_shards",
+		Help:      "Number of shards of RegisteredClusters reconciled by this replica.",
+	},
+)
+
+func init() {
+	metrics.Registry.MustRegister(ownedShards)
+}
+
+// ShardOf returns the shard of a logical cluster, all the RegisteredClusters of a workspace are in the same shard
+func ShardOf(clusterName string, shards int) int {
+	if shards <= 1 {
+		return 0
+	}
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(clusterName))
+	return int(h.Sum32() % uint32(shards))
+}
+
+// ShardManager spreads the shards of RegisteredClusters across the manager replicas.
+// Each shard is owned by a single replica holding its lease, the replicas announce themselves with
+// a member lease so each one takes its part of the shards and releases the extra ones.
+type ShardManager struct {
+	kubeClient    kubernetes.Interface
+	namespace     string
+	identity      string
+	shards        int
+	leaseDuration time.Duration
+	renewPeriod   time.Duration
+	log           logr.Logger
+
+	mu sync.RWMutex
+	// renewTimes is the last renew time of the owned shards
+	renewTimes map[int]time.Time
+	// onAcquired is called with each newly acquired shard
+	onAcquired []func(shard int)
+	// onReleased is called with each released or lost shard
+	onReleased []func(shard int)
+	now        func() time.Time
+}
+
+// NewShardManager returns a ShardManager for the given number of shards, the leases are created in namespace.
+func NewShardManager(kubeClient kubernetes.Interface,
+	namespace, identity string,
+	shards int,
+	leaseDuration time.Duration,
+	log logr.Logger) *ShardManager {
+	return &ShardManager{
+		kubeClient:    kubeClient,
+		namespace:     namespace,
+		identity:      identity,
+		shards:        shards,
+		leaseDuration: leaseDuration,
+		renewPeriod:   leaseDuration / 3,
+		log:           log.WithName("ShardManager"),
+		renewTimes:    make(map[int]time.Time),
+		now:           time.Now,
+	}
+}
+
+// OnAcquired registers a function called each time the replica acquires a shard
+func (s *ShardManager) OnAcquired(f func(shard int)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onAcquired = append(s.onAcquired, f)
+}
+
+// OnReleased registers a function called each time the replica releases or loses a shard
+func (s *ShardManager) OnReleased(f func(shard int)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onReleased = append(s.onReleased, f)
+}
+
+// Owns returns true if the logical cluster is in a shard owned by the replica
+func (s *ShardManager) Owns(clusterName string) bool {
+	return s.OwnsShard(ShardOf(clusterName, s.shards))
+}
+
+// OwnsShard returns true if the replica holds a valid lease on the shard
+func (s *ShardManager) OwnsShard(shard int) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	renewTime, ok := s.renewTimes[shard]
+	return ok && s.now().Sub(renewTime) < s.leaseDuration
+}
+
+// Shards returns the number of shards
+func (s *ShardManager) Shards() int {
+	return s.shards
+}
+
+// Start renews the leases until the context is done and then releases them
+func (s *ShardManager) Start(ctx context.Context) error {
+	s.log.Info("start", "identity", s.identity, "shards", s.shards)
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		if err := s.sync(ctx); err != nil {
+			s.log.Error(err, "failed to sync the shard leases")
+		}
+	}, s.renewPeriod)
+	s.release(context.Background())
+	return nil
+}
+
+// NeedLeaderElection returns false as all the replicas own shards
+func (s *ShardManager) NeedLeaderElection() bool {
+	return false
+}
+
+// sync renews the member lease, then acquires, renews or releases the shard leases to own the replica part of the shards
+func (s *ShardManager) sync(ctx context.Context) error {
+	replicas, err := s.syncMembers(ctx)
+	if err != nil {
+		return err
+	}
+	target := int(math.Ceil(float64(s.shards) / float64(replicas)))
+
+	acquired := make([]int, 0)
+	for shard := 0; shard < s.shards; shard++ {
+		owned := s.ownedCount()
+		ownsShard := s.OwnsShard(shard)
+		switch {
+		case ownsShard && owned > target:
+			// another replica joined, give it the extra shards
+			if err := s.releaseShard(ctx, shard); err != nil {
+				s.log.Error(err, "failed to release shard", "shard", shard)
+			}
+		case ownsShard || owned < target:
+			ok, err := s.tryAcquireOrRenew(ctx, shard)
+			if err != nil {
+				s.log.Error(err, "failed to acquire or renew shard", "shard", shard)
+				continue
+			}
+			if !ok {
+				if ownsShard {
+					// another replica took the lease
+					s.forget(shard)
+					s.notifyReleased(shard)
+				}
+				continue
+			}
+			if !ownsShard {
+				acquired = append(acquired, shard)
+			}
+		}
+	}
+	ownedShards.Set(float64(s.ownedCount()))
+
+	for _, shard := range acquired {
+		s.log.Info("shard acquired", "shard", shard, "identity", s.identity)
+		s.mu.RLock()
+		onAcquired := s.onAcquired
+		s.mu.RUnlock()
+		for _, f := range onAcquired {
+			f(shard)
+		}
+	}
+	return nil
+}
+
+// syncMembers renews the member lease of the replica and returns the number of live replicas
+func (s *ShardManager) syncMembers(ctx context.Context) (int, error) {
+	if _, err := s.tryAcquireOrRenewLease(ctx, ShardMemberLeasePrefix+s.identity, map[string]string{ShardMemberLabel: ""}); err != nil {
+		return 0, err
+	}
+	leases, err := s.kubeClient.CoordinationV1().Leases(s.namespace).List(ctx, metav1.ListOptions{LabelSelector: ShardMemberLabel})
+	if err != nil {
+		return 0, giterrors.WithStack(err)
+	}
+	replicas := 0
+	for i := range leases.Items {
+		lease := &leases.Items[i]
+		if !s.isExpired(lease) {
+			replicas++
+			continue
+		}
+		// The replica is gone
+		if err := s.kubeClient.CoordinationV1().Leases(s.namespace).Delete(ctx, lease.Name, metav1.DeleteOptions{}); err != nil &&
+			!k8serrors.IsNotFound(err) {
+			return 0, giterrors.WithStack(err)
+		}
+	}
+	if replicas == 0 {
+		replicas = 1
+	}
+	return replicas, nil
+}
+
+func (s *ShardManager) tryAcquireOrRenew(ctx context.Context, shard int) (bool, error) {
+	now := s.now()
+	ok, err := s.tryAcquireOrRenewLease(ctx, shardLeaseName(shard), nil)
+	if err != nil || !ok {
+		return ok, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.renewTimes[shard] = now
+	return true, nil
+}
+
+// tryAcquireOrRenewLease takes the lease if it is free, expired or already held by the replica,
+// false is returned if the lease is held by another replica.
+func (s *ShardManager) tryAcquireOrRenewLease(ctx context.Context, name string, labels map[string]string) (bool, error) {
+	now := metav1.NewMicroTime(s.now())
+	lease, err := s.kubeClient.CoordinationV1().Leases(s.namespace).Get(ctx, name, metav1.GetOptions{})
+	switch {
+	case k8serrors.IsNotFound(err):
+		lease = &coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: s.namespace,
+				Labels:    labels,
+			},
+			Spec: coordinationv1.LeaseSpec{
+				HolderIdentity:       pointer.String(s.identity),
+				LeaseDurationSeconds: pointer.Int32(int32(s.leaseDuration.Seconds())),
+				AcquireTime:          &now,
+				RenewTime:            &now,
+			},
+		}
+		_, err := s.kubeClient.CoordinationV1().Leases(s.namespace).Create(ctx, lease, metav1.CreateOptions{})
+		if k8serrors.IsAlreadyExists(err) {
+			return false, nil
+		}
+		return err == nil, giterrors.WithStack(err)
+	case err != nil:
+		return false, giterrors.WithStack(err)
+	}
+
+	if holder(lease) != s.identity {
+		if len(holder(lease)) != 0 && !s.isExpired(lease) {
+			return false, nil
+		}
+		lease.Spec.HolderIdentity = pointer.String(s.identity)
+		lease.Spec.AcquireTime = &now
+	}
+	lease.Spec.LeaseDurationSeconds = pointer.Int32(int32(s.leaseDuration.Seconds()))
+	lease.Spec.RenewTime = &now
+	// The update fails on conflict if another replica took the lease in the meantime
+	_, err = s.kubeClient.CoordinationV1().Leases(s.namespace).Update(ctx, lease, metav1.UpdateOptions{})
+	if k8serrors.IsConflict(err) {
+		return false, nil
+	}
+	return err == nil, giterrors.WithStack(err)
+}
+
+func (s *ShardManager) releaseShard(ctx context.Context, shard int) error {
+	s.forget(shard)
+	s.notifyReleased(shard)
+	lease, err := s.kubeClient.CoordinationV1().Leases(s.namespace).Get(ctx, shardLeaseName(shard), metav1.GetOptions{})
+	switch {
+	case k8serrors.IsNotFound(err):
+		return nil
+	case err != nil:
+		return giterrors.WithStack(err)
+	}
+	if holder(lease) != s.identity {
+		return nil
+	}
+	lease.Spec.HolderIdentity = nil
+	if _, err := s.kubeClient.CoordinationV1().Leases(s.namespace).Update(ctx, lease, metav1.UpdateOptions{}); err != nil &&
+		!k8serrors.IsConflict(err) {
+		return giterrors.WithStack(err)
+	}
+	s.log.Info("shard released", "shard", shard, "identity", s.identity)
+	return nil
+}
+
+// release gives back the shards and removes the member lease so the other replicas take over without waiting for the expiration
+func (s *ShardManager) release(ctx context.Context) {
+	for shard := 0; shard < s.shards; shard++ {
+		if !s.OwnsShard(shard) {
+			continue
+		}
+		if err := s.releaseShard(ctx, shard); err != nil {
+			s.log.Error(err, "failed to release shard", "shard", shard)
+		}
+	}
+	ownedShards.Set(0)
+	if err := s.kubeClient.CoordinationV1().Leases(s.namespace).Delete(ctx, ShardMemberLeasePrefix+s.identity, metav1.DeleteOptions{}); err != nil &&
+		!k8serrors.IsNotFound(err) {
+		s.log.Error(err, "failed to delete the member lease")
+	}
+}
+
+// notifyReleased calls the functions registered for the released shards
+func (s *ShardManager) notifyReleased(shard int) {
+	s.mu.RLock()
+	onReleased := s.onReleased
+	s.mu.RUnlock()
+	for _, f := range onReleased {
+		f(shard)
+	}
+}
+
+func (s *ShardManager) forget(shard int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.renewTimes, shard)
+}
+
+func (s *ShardManager) ownedCount() int {
+	count := 0
+	for shard := 0; shard < s.shards; shard++ {
+		if s.OwnsShard(shard) {
+			count++
+		}
+	}
+	return count
+}
+
+func (s *ShardManager) isExpired(lease *coordinationv1.Lease) bool {
+	if lease.Spec.RenewTime == nil || lease.Spec.LeaseDurationSeconds == nil {
+		return true
+	}
+	expiration := lease.Spec.RenewTime.Add(time.Duration(*lease.Spec.LeaseDurationSeconds) * time.Second)
+	return s.now().After(expiration)
+}
+
+func holder(lease *coordinationv1.Lease) string {
+	if lease.Spec.HolderIdentity == nil {
+		return ""
+	}
+	return *lease.Spec.HolderIdentity
+}
+
+func shardLeaseName(shard int) string {
+	return fmt.Sprintf("%s%d", ShardLeasePrefix, shard)
+}
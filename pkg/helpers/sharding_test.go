@@ -0,0 +1,101 @@
+// Copyright Red Hat
+
+package helpers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/kubernetes/fake"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+func TestShardOf(t *testing.T) {
+	if shard := ShardOf("root:org:ws1", 1); shard != 0 {
+		t.Fatalf("expected shard 0 with a single shard, got %d", shard)
+	}
+	if ShardOf("root:org:ws1", 8) != ShardOf("root:org:ws1", 8) {
+		t.Fatalf("expected the same shard for the same logical cluster")
+	}
+	for i := 0; i < 100; i++ {
+		if shard := ShardOf(RandomString(10, RandomTypeAlphaNum), 8); shard < 0 || shard >= 8 {
+			t.Fatalf("shard %d out of range", shard)
+		}
+	}
+}
+
+func TestShardManagerSpreadsShards(t *testing.T) {
+	ctx := context.TODO()
+	kubeClient := fake.NewSimpleClientset()
+	replica1 := NewShardManager(kubeClient, "compute-operator", "replica1", 4, 15*time.Second, ctrl.Log)
+	replica2 := NewShardManager(kubeClient, "compute-operator", "replica2", 4, 15*time.Second, ctrl.Log)
+
+	acquired := 0
+	replica1.OnAcquired(func(shard int) { acquired++ })
+	released := 0
+	replica1.OnReleased(func(shard int) { released++ })
+	if err := replica1.sync(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if owned := replica1.ownedCount(); owned != 4 {
+		t.Fatalf("expected the single replica to own the 4 shards, got %d", owned)
+	}
+	if acquired != 4 {
+		t.Fatalf("expected 4 acquired shards, got %d", acquired)
+	}
+
+	// replica2 joins, replica1 releases its extra shards which are then taken by replica2
+	for _, replica := range []*ShardManager{replica2, replica1, replica2} {
+		if err := replica.sync(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if owned := replica1.ownedCount(); owned != 2 {
+		t.Fatalf("expected replica1 to own 2 shards, got %d", owned)
+	}
+	if released != 2 {
+		t.Fatalf("expected 2 released shards, got %d", released)
+	}
+	if owned := replica2.ownedCount(); owned != 2 {
+		t.Fatalf("expected replica2 to own 2 shards, got %d", owned)
+	}
+	for shard := 0; shard < 4; shard++ {
+		if replica1.OwnsShard(shard) == replica2.OwnsShard(shard) {
+			t.Fatalf("expected shard %d to be owned by a single replica", shard)
+		}
+	}
+
+	// replica2 stops, replica1 takes all the shards back
+	replica2.release(ctx)
+	if err := replica1.sync(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if owned := replica1.ownedCount(); owned != 4 {
+		t.Fatalf("expected replica1 to own the 4 shards, got %d", owned)
+	}
+}
+
+func TestShardManagerExpiredLease(t *testing.T) {
+	ctx := context.TODO()
+	kubeClient := fake.NewSimpleClientset()
+	replica1 := NewShardManager(kubeClient, "compute-operator", "replica1", 2, 15*time.Second, ctrl.Log)
+	replica2 := NewShardManager(kubeClient, "compute-operator", "replica2", 2, 15*time.Second, ctrl.Log)
+	if err := replica1.sync(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// replica1 died without releasing its leases
+	later := time.Now().Add(time.Minute)
+	replica1.now = func() time.Time { return later }
+	replica2.now = func() time.Time { return later }
+	if replica1.OwnsShard(0) {
+		t.Fatalf("expected replica1 to no longer own its expired shard")
+	}
+	if err := replica2.sync(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if owned := replica2.ownedCount(); owned != 2 {
+		t.Fatalf("expected replica2 to take over the 2 expired shards, got %d", owned)
+	}
+}
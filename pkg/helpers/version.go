@@ -0,0 +1,7 @@
+// Copyright Red Hat
+
+package helpers
+
+// Version is the version of the operator, set at build time with
+// -ldflags "-X github.com/stolostron/compute-operator/pkg/helpers.Version=<version>"
+var Version = "0.0.1"
@@ -109,6 +109,27 @@ var _ = Describe("Process clusterRegistrar: ", func() {
 			admissionResponse := registeredClusterAdmissionHook.Validate(admissionRequest)
 			Expect(admissionResponse.Allowed).To(BeFalse())
 		})
+		By("Deny the deletion while RegisteredClusters exist", func() {
+			existing := regCluster.DeepCopy()
+			existing.Status.RegisteredClusterCount = 2
+			existingJson, err := json.Marshal(existing)
+			Expect(err).To(BeNil())
+			deleteRequest := &admissionv1beta1.AdmissionRequest{
+				Resource:  metav1.GroupVersionResource(helpers.GvrCR),
+				Operation: admissionv1beta1.Delete,
+				OldObject: runtime.RawExtension{
+					Raw: existingJson,
+				},
+			}
+			admissionResponse := registeredClusterAdmissionHook.Validate(deleteRequest)
+			Expect(admissionResponse.Allowed).To(BeFalse())
+
+			existing.Spec.DeletionMode = singaporev1alpha1.ClusterRegistrarDeletionModeCascade
+			deleteRequest.OldObject.Raw, err = json.Marshal(existing)
+			Expect(err).To(BeNil())
+			admissionResponse = registeredClusterAdmissionHook.Validate(deleteRequest)
+			Expect(admissionResponse.Allowed).To(BeTrue())
+		})
 		By("Deleting the existing one and validate new creation", func() {
 			err = controllerDynamicClient.Resource(helpers.GvrCR).Delete(context.TODO(), "cluster-reg", metav1.DeleteOptions{})
 			Expect(err).To(BeNil())
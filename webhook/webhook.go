@@ -5,6 +5,7 @@ package webhook
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"strings"
 	"sync"
@@ -127,14 +128,75 @@ func (a *RegisteredClusterAdmissionHook) ValidateRegisteredCluster(admissionSpec
 			return status
 		}
 
-		status.Allowed = true
+		return validateRegisteredClusterLocations(regCluster)
+	case admissionv1beta1.Update:
+		klog.V(4).Info("Validate RegisteredCluster update ")
+
+		oldRegCluster := &singaporev1alpha1.RegisteredCluster{}
+		if err := json.Unmarshal(admissionSpec.OldObject.Raw, oldRegCluster); err != nil {
+			status.Allowed = false
+			status.Result = &metav1.Status{
+				Status: metav1.StatusFailure, Code: http.StatusBadRequest, Reason: metav1.StatusReasonBadRequest,
+				Message: err.Error(),
+			}
+			return status
+		}
+
+		if oldRegCluster.Spec.ExistingManagedClusterName != regCluster.Spec.ExistingManagedClusterName ||
+			oldRegCluster.Spec.HubName != regCluster.Spec.HubName {
+			status.Allowed = false
+			status.Result = &metav1.Status{
+				Status: metav1.StatusFailure, Code: http.StatusForbidden, Reason: metav1.StatusReasonForbidden,
+				Message: "RegisteredCluster existingManagedClusterName and hubName are immutable",
+			}
+			return status
+		}
+
+		return validateRegisteredClusterLocations(regCluster)
+	}
+	status.Allowed = true
+	return status
+}
+
+// validateRegisteredClusterLocations denies the RegisteredCluster when its location overrides are not valid
+func validateRegisteredClusterLocations(regCluster *singaporev1alpha1.RegisteredCluster) *admissionv1beta1.AdmissionResponse {
+	status := &admissionv1beta1.AdmissionResponse{}
+	if err := validateLocationOverrides(regCluster); err != nil {
+		status.Allowed = false
+		status.Result = &metav1.Status{
+			Status: metav1.StatusFailure, Code: http.StatusForbidden, Reason: metav1.StatusReasonForbidden,
+			Message: err.Error(),
+		}
 		return status
 	}
 	status.Allowed = true
 	return status
 }
 
+// validateLocationOverrides checks that each location override targets a location of the RegisteredCluster
+func validateLocationOverrides(regCluster *singaporev1alpha1.RegisteredCluster) error {
+	locations := make(map[string]bool, len(regCluster.Spec.Location))
+	for _, location := range regCluster.Spec.Location {
+		locations[location] = true
+	}
+	overridden := make(map[string]bool, len(regCluster.Spec.LocationOverrides))
+	for _, override := range regCluster.Spec.LocationOverrides {
+		if !locations[override.Location] {
+			return fmt.Errorf("location override %s is not a location of the RegisteredCluster", override.Location)
+		}
+		if overridden[override.Location] {
+			return fmt.Errorf("location %s is overridden more than once", override.Location)
+		}
+		overridden[override.Location] = true
+	}
+	return nil
+}
+
 func (a *RegisteredClusterAdmissionHook) ValidateClusterRegistrar(admissionSpec *admissionv1beta1.AdmissionRequest) *admissionv1beta1.AdmissionResponse {
+	if admissionSpec.Operation == admissionv1beta1.Delete {
+		return validateClusterRegistrarDeletion(admissionSpec)
+	}
+
 	status := &admissionv1beta1.AdmissionResponse{}
 
 	clusterRegistrar := &singaporev1alpha1.ClusterRegistrar{}
@@ -173,6 +235,40 @@ func (a *RegisteredClusterAdmissionHook) ValidateClusterRegistrar(admissionSpec
 
 }
 
+// validateClusterRegistrarDeletion denies the ClusterRegistrar deletion while RegisteredClusters exist,
+// unless they are deleted first with the Cascade deletion mode.
+// The RegisteredClusters are counted by the installer every 5 minutes, so the denial is best-effort,
+// the drain of the installer finalizer, which counts them at each check, is the guard of the RegisteredClusters.
+func validateClusterRegistrarDeletion(admissionSpec *admissionv1beta1.AdmissionRequest) *admissionv1beta1.AdmissionResponse {
+	status := &admissionv1beta1.AdmissionResponse{}
+
+	clusterRegistrar := &singaporev1alpha1.ClusterRegistrar{}
+	if err := json.Unmarshal(admissionSpec.OldObject.Raw, clusterRegistrar); err != nil {
+		status.Allowed = false
+		status.Result = &metav1.Status{
+			Status: metav1.StatusFailure, Code: http.StatusBadRequest, Reason: metav1.StatusReasonBadRequest,
+			Message: err.Error(),
+		}
+		return status
+	}
+
+	klog.V(4).Infof("Validate webhook for ClusterRegistrar deletion name: %s", clusterRegistrar.Name)
+	if clusterRegistrar.Status.RegisteredClusterCount > 0 &&
+		clusterRegistrar.Spec.DeletionMode != singaporev1alpha1.ClusterRegistrarDeletionModeCascade {
+		status.Allowed = false
+		status.Result = &metav1.Status{
+			Status: metav1.StatusFailure, Code: http.StatusForbidden, Reason: metav1.StatusReasonForbidden,
+			Message: fmt.Sprintf("%d RegisteredClusters exist, delete them or set spec.deletionMode to Cascade. "+
+				"This check is best-effort as it relies on the last count of the installer, "+
+				"the installer finalizer blocks the uninstall until the RegisteredClusters are drained",
+				clusterRegistrar.Status.RegisteredClusterCount),
+		}
+		return status
+	}
+	status.Allowed = true
+	return status
+}
+
 // Initialize is called by generic-admission-server on startup to setup initialization that webhook needs.
 func (a *RegisteredClusterAdmissionHook) Initialize(kubeClientConfig *rest.Config, stopCh <-chan struct{}) error {
 	a.lock.Lock()